@@ -0,0 +1,332 @@
+// Package consumer runs Kafka consumers with retries and dead-letter topics
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
+)
+
+// Headers added to dead-lettered messages
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderConsumerGroup     = "x-consumer-group"
+	HeaderError             = "x-error"
+	HeaderAttempts          = "x-attempts"
+	HeaderFailedAt          = "x-failed-at"
+	HeaderReplayCount       = "x-replay-count"
+)
+
+// DLQSuffix is appended to the source topic to name its dead-letter topic
+const DLQSuffix = ".dlq"
+
+// Handler processes one message. Returning an error retries the message;
+// errors wrapped with Permanent go to the dead-letter topic right away.
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying cannot fix, e.g. a message that
+// does not unmarshal
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+type Config struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+	// MaxAttempts is the number of tries before a message is dead-lettered
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// Workers handle messages concurrently when above 1. Messages with the
+	// same key go to the same worker, so they keep their order.
+	Workers int
+	// QueueSize is the number of messages buffered per worker
+	QueueSize int
+}
+
+// messageReader is the part of *kafka.Reader the consumer uses
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// messageWriter is the part of *kafka.Writer the consumer uses
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Consumer reads a topic in a consumer group and commits each message only
+// after it was handled or dead-lettered, so nothing is dropped silently.
+type Consumer struct {
+	config Config
+	reader messageReader
+	dlq    messageWriter
+	logger *zap.Logger
+}
+
+func New(config Config, logger *zap.Logger) *Consumer {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 5
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = 500 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.QueueSize < 1 {
+		config.QueueSize = 16
+	}
+
+	return &Consumer{
+		config: config,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  config.Brokers,
+			Topic:    config.Topic,
+			GroupID:  config.GroupID,
+			MinBytes: 10e3,
+			MaxBytes: 10e6,
+		}),
+		dlq: &kafka.Writer{
+			Addr:                   kafka.TCP(config.Brokers...),
+			Topic:                  config.Topic + DLQSuffix,
+			Balancer:               &kafka.Hash{},
+			AllowAutoTopicCreation: true,
+		},
+		logger: logger.With(zap.String("topic", config.Topic)),
+	}
+}
+
+// Run consumes until ctx is cancelled. A message in flight at that point is
+// still handled and committed.
+func (c *Consumer) Run(ctx context.Context, handler Handler) {
+	c.run(ctx, context.WithoutCancel(ctx), handler)
+}
+
+// Hook runs the consumer in the background. On shutdown it stops fetching
+// and waits for the messages in flight to be handled and committed; past
+// the shutdown deadline they are abandoned and redelivered after restart.
+func (c *Consumer) Hook(handler Handler) lifecycle.Hook {
+	done := make(chan struct{})
+	work, abort := context.WithCancel(context.Background())
+
+	return lifecycle.Hook{
+		Name: "kafka consumer " + c.config.Topic,
+		Start: func(ctx context.Context) error {
+			c.logger.Info("Started consuming", zap.String("group", c.config.GroupID), zap.Int("workers", c.config.Workers))
+			go func() {
+				defer close(done)
+				c.run(ctx, work, handler)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			defer abort()
+			return lifecycle.Wait(ctx, done)
+		},
+	}
+}
+
+// run fetches until ctx is cancelled and handles messages with work, which
+// is only cancelled to abandon the message in flight
+func (c *Consumer) run(ctx, work context.Context, handler Handler) {
+	defer c.reader.Close()
+	defer c.dlq.Close()
+
+	if c.config.Workers > 1 {
+		c.runPool(ctx, work, handler)
+		return
+	}
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Error("Error reading message from Kafka", zap.Error(err))
+			continue
+		}
+		c.observeLag(msg)
+
+		if err := c.process(ctx, work, handler, msg); err != nil {
+			// Shutdown or abandoned; the message is redelivered after
+			// restart
+			c.logger.Warn("Message left uncommitted", zap.Int64("offset", msg.Offset), zap.Error(err))
+			return
+		}
+
+		if err := c.reader.CommitMessages(work, msg); err != nil && work.Err() == nil {
+			c.logger.Error("Error committing offset", zap.Int64("offset", msg.Offset), zap.Error(err))
+		}
+	}
+}
+
+func (c *Consumer) observeLag(msg kafka.Message) {
+	lag.WithLabelValues(msg.Topic, strconv.Itoa(msg.Partition)).Set(float64(msg.HighWaterMark - msg.Offset - 1))
+}
+
+// process handles a message with retries and dead-letters it when the
+// attempts run out. The handler runs with work, in a span that continues
+// the producer's trace. Once ctx is cancelled failures are no longer
+// retried, and process fails instead so the message is not committed.
+func (c *Consumer) process(ctx, work context.Context, handler Handler, msg kafka.Message) error {
+	work, span := telemetry.StartKafkaConsumerSpan(work, msg)
+	defer span.End()
+
+	var err error
+	attempt := 1
+	for ; attempt <= c.config.MaxAttempts; attempt++ {
+		if err = c.call(work, handler, msg); err == nil {
+			messages.WithLabelValues(c.config.Topic, "processed").Inc()
+			return nil
+		}
+		if work.Err() != nil {
+			return work.Err()
+		}
+		if IsPermanent(err) {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt), attribute.String("error", err.Error())))
+		messages.WithLabelValues(c.config.Topic, "retried").Inc()
+		c.logger.Warn("Error handling message, retrying",
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+		if attempt < c.config.MaxAttempts {
+			if sleepErr := sleep(ctx, c.delay(attempt)); sleepErr != nil {
+				return sleepErr
+			}
+		}
+	}
+	if attempt > c.config.MaxAttempts {
+		attempt = c.config.MaxAttempts
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "dead-lettered")
+
+	return c.deadLetter(work, msg, err, attempt)
+}
+
+// call runs the handler and turns a panic into a permanent error, so one
+// poison message cannot crash the service
+func (c *Consumer) call(ctx context.Context, handler Handler, msg kafka.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	return handler(ctx, msg)
+}
+
+// deadLetter writes the message to the dead-letter topic, retrying until it
+// succeeds or ctx is cancelled
+func (c *Consumer) deadLetter(ctx context.Context, msg kafka.Message, cause error, attempts int) error {
+	headers := append(withoutDLQHeaders(msg.Headers),
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderConsumerGroup, Value: []byte(c.config.GroupID)},
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+	dead := kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers}
+
+	for retry := 1; ; retry++ {
+		err := c.dlq.WriteMessages(ctx, dead)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.logger.Error("Error writing message to dead-letter topic, retrying",
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err),
+		)
+		if err := sleep(ctx, c.delay(retry)); err != nil {
+			return err
+		}
+	}
+
+	messages.WithLabelValues(c.config.Topic, "dead_lettered").Inc()
+	c.logger.Error("Message moved to dead-letter topic",
+		zap.String("dlq_topic", c.config.Topic+DLQSuffix),
+		zap.Int("partition", msg.Partition),
+		zap.Int64("offset", msg.Offset),
+		zap.Int("attempts", attempts),
+		zap.Error(cause),
+	)
+	return nil
+}
+
+// delay is the exponential backoff before the given retry (1-based)
+func (c *Consumer) delay(retry int) time.Duration {
+	d := c.config.Backoff << (retry - 1)
+	if d <= 0 || d > c.config.MaxBackoff {
+		d = c.config.MaxBackoff
+	}
+	return d
+}
+
+// withoutDLQHeaders drops the metadata of an earlier dead-lettering, so a
+// replayed message that fails again carries only its latest error
+func withoutDLQHeaders(headers []kafka.Header) []kafka.Header {
+	var kept []kafka.Header
+	for _, h := range headers {
+		switch h.Key {
+		case HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset, HeaderConsumerGroup,
+			HeaderError, HeaderAttempts, HeaderFailedAt:
+			continue
+		}
+		kept = append(kept, h)
+	}
+	return kept
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,247 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// fakeWriter records dead-lettered messages, failing the first `fail` writes
+type fakeWriter struct {
+	mu       sync.Mutex
+	fail     int
+	messages []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fail > 0 {
+		w.fail--
+		return errors.New("broker unavailable")
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func newTestConsumer(reader messageReader, dlq messageWriter) *Consumer {
+	return &Consumer{
+		config: Config{Topic: "test", GroupID: "group", MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond, Workers: 1},
+		reader: reader,
+		dlq:    dlq,
+		logger: zap.NewNop(),
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", io.EOF, false},
+		{"permanent", Permanent(io.EOF), true},
+		{"wrapped permanent", fmt.Errorf("handle: %w", Permanent(io.EOF)), true},
+	}
+	for _, tt := range tests {
+		if got := IsPermanent(tt.err); got != tt.want {
+			t.Fatalf("%s: IsPermanent = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if err := Permanent(io.EOF); !errors.Is(err, io.EOF) || err.Error() != "EOF" {
+		t.Fatalf("Permanent(io.EOF) = %v, want it to wrap io.EOF", err)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	c := &Consumer{config: Config{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		// The shift overflows
+		{64, time.Second},
+	}
+	for _, tt := range tests {
+		if got := c.delay(tt.retry); got != tt.want {
+			t.Fatalf("delay(%d) = %v, want %v", tt.retry, got, tt.want)
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	boom := errors.New("boom")
+
+	tests := []struct {
+		name         string
+		errs         []error // handler result per call, nil after the list
+		panics       bool
+		wantCalls    int
+		wantDead     bool
+		wantAttempts string
+		wantError    string
+	}{
+		{"handled", nil, false, 1, false, "", ""},
+		{"handled after retries", []error{boom, boom}, false, 3, false, "", ""},
+		{"attempts run out", []error{boom, boom, boom}, false, 3, true, "3", "boom"},
+		{"permanent error", []error{boom, Permanent(errors.New("bad json"))}, false, 2, true, "2", "bad json"},
+		{"panic", nil, true, 1, true, "1", "panic: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dlq := &fakeWriter{fail: 1}
+			c := newTestConsumer(nil, dlq)
+
+			calls := 0
+			handler := func(ctx context.Context, msg kafka.Message) error {
+				calls++
+				if tt.panics {
+					panic("boom")
+				}
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			}
+
+			msg := kafka.Message{Topic: "test", Partition: 2, Offset: 42, Key: []byte("p1"), Value: []byte("{}")}
+			ctx := context.Background()
+			if err := c.process(ctx, ctx, handler, msg); err != nil {
+				t.Fatalf("process: %v", err)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("handler called %d times, want %d", calls, tt.wantCalls)
+			}
+			if !tt.wantDead {
+				if len(dlq.messages) != 0 {
+					t.Fatalf("dead-lettered %d messages", len(dlq.messages))
+				}
+				return
+			}
+
+			// The failed DLQ write is retried
+			if len(dlq.messages) != 1 {
+				t.Fatalf("dead-lettered %d messages, want 1", len(dlq.messages))
+			}
+			dead := dlq.messages[0]
+			want := map[string]string{
+				HeaderOriginalTopic:     "test",
+				HeaderOriginalPartition: "2",
+				HeaderOriginalOffset:    "42",
+				HeaderConsumerGroup:     "group",
+				HeaderAttempts:          tt.wantAttempts,
+				HeaderError:             tt.wantError,
+			}
+			for key, value := range want {
+				if got := header(dead.Headers, key); got != value {
+					t.Fatalf("header %s = %q, want %q", key, got, value)
+				}
+			}
+			if string(dead.Key) != "p1" || string(dead.Value) != "{}" {
+				t.Fatalf("dead-lettered %s=%s, want the original message", dead.Key, dead.Value)
+			}
+		})
+	}
+}
+
+func TestProcessStopsRetryingOnShutdown(t *testing.T) {
+	dlq := &fakeWriter{}
+	c := newTestConsumer(nil, dlq)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	handler := func(context.Context, kafka.Message) error {
+		calls++
+		cancel()
+		return errors.New("database unavailable")
+	}
+
+	// The message is left uncommitted rather than dead-lettered
+	err := c.process(ctx, context.Background(), handler, kafka.Message{Topic: "test"})
+	if !errors.Is(err, context.Canceled) || calls != 1 || len(dlq.messages) != 0 {
+		t.Fatalf("process = %v after %d calls with %d dead-lettered", err, calls, len(dlq.messages))
+	}
+}
+
+func TestDeadLetterReplacesEarlierMetadata(t *testing.T) {
+	dlq := &fakeWriter{}
+	c := newTestConsumer(nil, dlq)
+
+	// A replayed message that fails again
+	msg := kafka.Message{Topic: "test", Offset: 7, Headers: []kafka.Header{
+		{Key: "traceparent", Value: []byte("00-abc-def-01")},
+		{Key: HeaderError, Value: []byte("old error")},
+		{Key: HeaderOriginalOffset, Value: []byte("3")},
+		{Key: HeaderReplayCount, Value: []byte("1")},
+	}}
+	if err := c.deadLetter(context.Background(), msg, errors.New("new error"), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+	for _, h := range dlq.messages[0].Headers {
+		counts[h.Key]++
+	}
+	for _, key := range []string{HeaderError, HeaderOriginalOffset, "traceparent", HeaderReplayCount} {
+		if counts[key] != 1 {
+			t.Fatalf("header %s appears %d times, want once", key, counts[key])
+		}
+	}
+	headers := dlq.messages[0].Headers
+	if header(headers, HeaderError) != "new error" || header(headers, HeaderOriginalOffset) != "7" {
+		t.Fatalf("headers = %v, want the latest failure", headers)
+	}
+}
+
+func TestRunCommitsHandledAndDeadLetteredMessages(t *testing.T) {
+	reader := &fakeReader{messages: []kafka.Message{
+		{Topic: "test", Offset: 0, Value: []byte("ok")},
+		{Topic: "test", Offset: 1, Value: []byte("poison")},
+		{Topic: "test", Offset: 2, Value: []byte("ok")},
+	}}
+	dlq := &fakeWriter{}
+	c := newTestConsumer(reader, dlq)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		if string(msg.Value) == "poison" {
+			return Permanent(errors.New("bad message"))
+		}
+		if msg.Offset == 2 {
+			cancel()
+		}
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx, handler)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+
+	if len(reader.commits) != 3 {
+		t.Fatalf("committed %d messages, want 3", len(reader.commits))
+	}
+	if len(dlq.messages) != 1 || string(dlq.messages[0].Value) != "poison" {
+		t.Fatalf("dead-lettered %v, want the poison message", dlq.messages)
+	}
+}
@@ -0,0 +1,242 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// DeadLetter is a dead-lettered message as printed by the dlq command
+type DeadLetter struct {
+	Partition int               `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Key       string            `json:"key"`
+	Headers   map[string]string `json:"headers"`
+	Value     string            `json:"value"`
+}
+
+// ReadDeadLetters returns the messages currently in a dead-letter topic,
+// without committing anything, at most limit per partition
+func ReadDeadLetters(ctx context.Context, brokers []string, topic string, limit int) ([]DeadLetter, error) {
+	messages, err := readTopic(ctx, brokers, topic, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]DeadLetter, 0, len(messages))
+	for _, msg := range messages {
+		list = append(list, toDeadLetter(msg))
+	}
+	return list, nil
+}
+
+func readTopic(ctx context.Context, brokers []string, topic string, limit int) ([]kafka.Message, error) {
+	conn, err := kafka.DialContext(ctx, "tcp", brokers[0])
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var all []kafka.Message
+	for _, p := range partitions {
+		messages, err := readPartition(ctx, brokers, topic, p.ID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("partition %d: %w", p.ID, err)
+		}
+		all = append(all, messages...)
+	}
+	return all, nil
+}
+
+func readPartition(ctx context.Context, brokers []string, topic string, partition, limit int) ([]kafka.Message, error) {
+	leader, err := kafka.DialLeader(ctx, "tcp", brokers[0], topic, partition)
+	if err != nil {
+		return nil, err
+	}
+	first, last, err := leader.ReadOffsets()
+	leader.Close()
+	if err != nil || first >= last {
+		return nil, err
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     topic,
+		Partition: partition,
+		MinBytes:  1,
+		MaxBytes:  10e6,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(first); err != nil {
+		return nil, err
+	}
+
+	var messages []kafka.Message
+	for offset := first; offset < last && (limit <= 0 || len(messages) < limit); {
+		readCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		msg, err := reader.ReadMessage(readCtx)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+		offset = msg.Offset + 1
+	}
+	return messages, nil
+}
+
+// Replay publishes a dead-lettered message back to its original topic. The
+// DLQ metadata is dropped and the replay count incremented.
+func Replay(ctx context.Context, writer *kafka.Writer, msg kafka.Message) error {
+	replay, err := replayMessage(msg)
+	if err != nil {
+		return err
+	}
+	return writer.WriteMessages(ctx, replay)
+}
+
+func replayMessage(msg kafka.Message) (kafka.Message, error) {
+	original := header(msg.Headers, HeaderOriginalTopic)
+	if original == "" {
+		return kafka.Message{}, errors.New("message has no " + HeaderOriginalTopic + " header")
+	}
+
+	replays, _ := strconv.Atoi(header(msg.Headers, HeaderReplayCount))
+	var headers []kafka.Header
+	for _, h := range withoutDLQHeaders(msg.Headers) {
+		if h.Key != HeaderReplayCount {
+			headers = append(headers, h)
+		}
+	}
+	headers = append(headers, kafka.Header{Key: HeaderReplayCount, Value: []byte(strconv.Itoa(replays + 1))})
+
+	return kafka.Message{
+		Topic:   original,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}, nil
+}
+
+// RunCommand implements the "dlq" subcommand shared by the services:
+//
+//	dlq list   -topic payment.created.dlq [-limit 100]
+//	dlq replay -topic payment.created.dlq [-partition 0 -offset 42]
+//
+// replay without -offset re-publishes every message in the topic; handlers
+// are idempotent, so replaying a message twice is harmless.
+func RunCommand(ctx context.Context, args []string, brokers []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: dlq list|replay -topic <topic>.dlq [flags]")
+	}
+
+	fs := flag.NewFlagSet("dlq "+args[0], flag.ContinueOnError)
+	topic := fs.String("topic", "", "dead-letter topic")
+	limit := fs.Int("limit", 100, "max messages per partition (list)")
+	partition := fs.Int("partition", 0, "partition of the message to replay")
+	offset := fs.Int64("offset", -1, "offset of the message to replay, all messages when unset")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(*topic, DLQSuffix) {
+		return fmt.Errorf("-topic must be a dead-letter topic ending in %s", DLQSuffix)
+	}
+
+	switch args[0] {
+	case "list":
+		list, err := ReadDeadLetters(ctx, brokers, *topic, *limit)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(out)
+		for _, dl := range list {
+			if err := enc.Encode(dl); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "replay":
+		messages, err := replayCandidates(ctx, brokers, *topic, *partition, *offset)
+		if err != nil {
+			return err
+		}
+		writer := &kafka.Writer{Addr: kafka.TCP(brokers...), Balancer: &kafka.Hash{}}
+		defer writer.Close()
+
+		for _, msg := range messages {
+			if err := Replay(ctx, writer, msg); err != nil {
+				return fmt.Errorf("partition %d offset %d: %w", msg.Partition, msg.Offset, err)
+			}
+			fmt.Fprintf(out, "replayed partition %d offset %d to %s\n",
+				msg.Partition, msg.Offset, header(msg.Headers, HeaderOriginalTopic))
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown dlq command %q", args[0])
+	}
+}
+
+func replayCandidates(ctx context.Context, brokers []string, topic string, partition int, offset int64) ([]kafka.Message, error) {
+	if offset < 0 {
+		return readTopic(ctx, brokers, topic, 0)
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     topic,
+		Partition: partition,
+		MinBytes:  1,
+		MaxBytes:  10e6,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(offset); err != nil {
+		return nil, err
+	}
+	readCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	msg, err := reader.ReadMessage(readCtx)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Offset != offset {
+		return nil, fmt.Errorf("offset %d not found in partition %d", offset, partition)
+	}
+	return []kafka.Message{msg}, nil
+}
+
+func toDeadLetter(msg kafka.Message) DeadLetter {
+	dl := DeadLetter{
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       string(msg.Key),
+		Headers:   make(map[string]string, len(msg.Headers)),
+		Value:     string(msg.Value),
+	}
+	for _, h := range msg.Headers {
+		dl.Headers[h.Key] = string(h.Value)
+	}
+	return dl
+}
+
+func header(headers []kafka.Header, key string) string {
+	for _, h := range headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package consumer
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestReplayMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     []kafka.Header
+		wantHeaders []kafka.Header
+	}{
+		{
+			"first replay",
+			[]kafka.Header{
+				{Key: "traceparent", Value: []byte("00-abc-def-01")},
+				{Key: HeaderOriginalTopic, Value: []byte("payment.created")},
+				{Key: HeaderError, Value: []byte("boom")},
+				{Key: HeaderAttempts, Value: []byte("5")},
+			},
+			[]kafka.Header{
+				{Key: "traceparent", Value: []byte("00-abc-def-01")},
+				{Key: HeaderReplayCount, Value: []byte("1")},
+			},
+		},
+		{
+			"replayed before",
+			[]kafka.Header{
+				{Key: HeaderReplayCount, Value: []byte("2")},
+				{Key: HeaderOriginalTopic, Value: []byte("payment.created")},
+				{Key: HeaderFailedAt, Value: []byte("2024-03-01T12:00:00Z")},
+			},
+			[]kafka.Header{
+				{Key: HeaderReplayCount, Value: []byte("3")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := kafka.Message{Topic: "payment.created.dlq", Key: []byte("p1"), Value: []byte("{}"), Headers: tt.headers}
+			got, err := replayMessage(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Topic != "payment.created" || string(got.Key) != "p1" || string(got.Value) != "{}" {
+				t.Fatalf("replay = %s %s=%s", got.Topic, got.Key, got.Value)
+			}
+			if !reflect.DeepEqual(got.Headers, tt.wantHeaders) {
+				t.Fatalf("replay headers = %v, want %v", got.Headers, tt.wantHeaders)
+			}
+		})
+	}
+
+	if _, err := replayMessage(kafka.Message{Topic: "payment.created.dlq"}); err == nil {
+		t.Fatal("replayed a message without its original topic")
+	}
+}
+
+func TestToDeadLetter(t *testing.T) {
+	dl := toDeadLetter(kafka.Message{
+		Partition: 1,
+		Offset:    9,
+		Key:       []byte("p1"),
+		Value:     []byte(`{"id": "p1"}`),
+		Headers:   []kafka.Header{{Key: HeaderError, Value: []byte("boom")}},
+	})
+	want := DeadLetter{Partition: 1, Offset: 9, Key: "p1", Value: `{"id": "p1"}`, Headers: map[string]string{HeaderError: "boom"}}
+	if !reflect.DeepEqual(dl, want) {
+		t.Fatalf("toDeadLetter = %+v, want %+v", dl, want)
+	}
+}
+
+func TestRunCommandRejectsInvalidArgs(t *testing.T) {
+	// Rejected before connecting to the brokers
+	for _, args := range [][]string{
+		nil,
+		{"list"},
+		{"list", "-topic", "payment.created"},
+		{"replay", "-topic", "payment.created", "-offset", "3"},
+		{"replay", "-topic", "payment.created.dlq", "-offset", "three"},
+		{"purge", "-topic", "payment.created.dlq"},
+	} {
+		if err := RunCommand(context.Background(), args, nil, io.Discard); err == nil {
+			t.Fatalf("RunCommand(%v) succeeded", args)
+		}
+	}
+}
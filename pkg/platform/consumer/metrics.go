@@ -0,0 +1,43 @@
+package consumer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	messages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_messages_total",
+		Help: "Consumed Kafka messages by topic and result (processed, retried, dead_lettered)",
+	}, []string{"topic", "result"})
+
+	// lag is updated from the high water mark returned with each fetched
+	// message, so it only moves when the consumer fetches
+	lag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Messages behind the end of the partition, by topic and partition",
+	}, []string{"topic", "partition"})
+
+	poolWorkers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_pool_workers",
+		Help: "Size of the consumer worker pool, by topic",
+	}, []string{"topic"})
+
+	poolBusy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_pool_busy_workers",
+		Help: "Workers handling a message, by topic",
+	}, []string{"topic"})
+
+	poolQueued = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_pool_queued_messages",
+		Help: "Fetched messages waiting for a worker, by topic",
+	}, []string{"topic"})
+
+	// poolDispatchWait grows when the worker of a key is saturated and
+	// fetching is blocked
+	poolDispatchWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_pool_dispatch_wait_seconds",
+		Help:    "Time a fetched message waited to be queued for its worker, by topic",
+		Buckets: []float64{0.0001, 0.001, 0.01, 0.1, 0.5, 1, 5, 10},
+	}, []string{"topic"})
+)
@@ -0,0 +1,58 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestProcessCountsResults(t *testing.T) {
+	const topic = "metrics-test"
+	c := newTestConsumer(nil, &fakeWriter{})
+	c.config.Topic = topic
+
+	results := []string{"processed", "retried", "dead_lettered"}
+	before := make(map[string]float64)
+	for _, result := range results {
+		before[result] = testutil.ToFloat64(messages.WithLabelValues(topic, result))
+	}
+
+	ctx := context.Background()
+	ok := func(context.Context, kafka.Message) error { return nil }
+	failing := func(context.Context, kafka.Message) error { return errors.New("boom") }
+	for _, handler := range []Handler{ok, ok, failing} {
+		if err := c.process(ctx, ctx, handler, kafka.Message{Topic: topic}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The failing message is retried until its three attempts run out
+	want := map[string]float64{"processed": 2, "retried": 3, "dead_lettered": 1}
+	for _, result := range results {
+		if got := testutil.ToFloat64(messages.WithLabelValues(topic, result)) - before[result]; got != want[result] {
+			t.Fatalf("%s grew by %v, want %v", result, got, want[result])
+		}
+	}
+}
+
+func TestObserveLag(t *testing.T) {
+	c := newTestConsumer(nil, nil)
+	tests := []struct {
+		offset        int64
+		highWaterMark int64
+		want          float64
+	}{
+		{9, 10, 0},
+		{5, 10, 4},
+		{0, 1000, 999},
+	}
+	for _, tt := range tests {
+		c.observeLag(kafka.Message{Topic: "lag-test", Partition: 3, Offset: tt.offset, HighWaterMark: tt.highWaterMark})
+		if got := testutil.ToFloat64(lag.WithLabelValues("lag-test", "3")); got != tt.want {
+			t.Fatalf("lag at offset %d of %d = %v, want %v", tt.offset, tt.highWaterMark, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package consumer
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// runPool fetches messages and hands them to Workers goroutines, chosen by
+// a hash of the message key. Offsets are committed per partition only up to
+// the last message before the oldest one still pending, so a crash never
+// skips a message that was not handled.
+func (c *Consumer) runPool(ctx, work context.Context, handler Handler) {
+	tracker := newOffsetTracker()
+	commits := make(chan kafka.Message, c.config.Workers*c.config.QueueSize)
+
+	var committer sync.WaitGroup
+	committer.Add(1)
+	go func() {
+		defer committer.Done()
+		c.commitLoop(work, commits)
+	}()
+
+	queues := make([]chan kafka.Message, c.config.Workers)
+	var workers sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan kafka.Message, c.config.QueueSize)
+		workers.Add(1)
+		go func(queue <-chan kafka.Message) {
+			defer workers.Done()
+			c.work(ctx, work, handler, queue, tracker, commits)
+		}(queues[i])
+	}
+	poolWorkers.WithLabelValues(c.config.Topic).Set(float64(c.config.Workers))
+
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+		workers.Wait()
+		close(commits)
+		committer.Wait()
+	}()
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Error("Error reading message from Kafka", zap.Error(err))
+			continue
+		}
+		c.observeLag(msg)
+		tracker.add(msg)
+
+		queue := queues[workerFor(msg.Key, len(queues))]
+		poolQueued.WithLabelValues(c.config.Topic).Inc()
+		start := time.Now()
+		select {
+		case queue <- msg:
+		case <-ctx.Done():
+			poolQueued.WithLabelValues(c.config.Topic).Dec()
+			return
+		}
+		poolDispatchWait.WithLabelValues(c.config.Topic).Observe(time.Since(start).Seconds())
+	}
+}
+
+// work handles the messages of one worker in order. Once shutdown begins,
+// queued messages that have not started are left for redelivery.
+func (c *Consumer) work(ctx, work context.Context, handler Handler, queue <-chan kafka.Message,
+	tracker *offsetTracker, commits chan<- kafka.Message) {
+	for msg := range queue {
+		poolQueued.WithLabelValues(c.config.Topic).Dec()
+		if ctx.Err() != nil {
+			continue
+		}
+
+		poolBusy.WithLabelValues(c.config.Topic).Inc()
+		err := c.process(ctx, work, handler, msg)
+		poolBusy.WithLabelValues(c.config.Topic).Dec()
+		if err != nil {
+			c.logger.Warn("Message left uncommitted", zap.Int64("offset", msg.Offset), zap.Error(err))
+			continue
+		}
+
+		if commit, ok := tracker.complete(msg); ok {
+			commits <- commit
+		}
+	}
+}
+
+// commitLoop commits offsets in the order they become committable. A
+// commit lower than one already made for the partition is skipped, since
+// workers may hand them over out of order.
+func (c *Consumer) commitLoop(work context.Context, commits <-chan kafka.Message) {
+	committed := make(map[int]int64)
+	for msg := range commits {
+		if last, ok := committed[msg.Partition]; ok && msg.Offset <= last {
+			continue
+		}
+		if err := c.reader.CommitMessages(work, msg); err != nil {
+			if work.Err() == nil {
+				c.logger.Error("Error committing offset", zap.Int("partition", msg.Partition), zap.Int64("offset", msg.Offset), zap.Error(err))
+			}
+			continue
+		}
+		committed[msg.Partition] = msg.Offset
+	}
+}
+
+func workerFor(key []byte, workers int) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(workers))
+}
+
+// offsetTracker remembers the fetched messages of each partition until
+// every message before them is handled too
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	// pending holds fetched messages in offset order, handled or not
+	pending []kafka.Message
+	done    map[int64]bool
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[int]*partitionOffsets)}
+}
+
+func (t *offsetTracker) add(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.partitions[msg.Partition]
+	// After a rebalance the partition may be fetched again from its last
+	// commit; older pending messages will be delivered again
+	if p == nil || (len(p.pending) > 0 && msg.Offset <= p.pending[len(p.pending)-1].Offset) {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[msg.Partition] = p
+	}
+	p.pending = append(p.pending, msg)
+}
+
+// complete marks msg handled and returns the message to commit, if the
+// oldest pending messages are now all handled
+func (t *offsetTracker) complete(msg kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.partitions[msg.Partition]
+	if p == nil {
+		return kafka.Message{}, false
+	}
+	p.done[msg.Offset] = true
+
+	var commit kafka.Message
+	found := false
+	for len(p.pending) > 0 && p.done[p.pending[0].Offset] {
+		commit = p.pending[0]
+		found = true
+		delete(p.done, commit.Offset)
+		p.pending = p.pending[1:]
+	}
+	return commit, found
+}
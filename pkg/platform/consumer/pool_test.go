@@ -0,0 +1,279 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	// Spans go to the no-op global provider
+	telemetry.Tracer = otel.Tracer("consumer-test")
+	os.Exit(m.Run())
+}
+
+func message(partition int, offset int64) kafka.Message {
+	return kafka.Message{Topic: "test", Partition: partition, Offset: offset}
+}
+
+func TestOffsetTrackerCommitsContiguousOffsets(t *testing.T) {
+	tracker := newOffsetTracker()
+	for offset := int64(10); offset < 15; offset++ {
+		tracker.add(message(0, offset))
+	}
+
+	steps := []struct {
+		complete int64
+		commit   int64 // -1 means nothing is committable
+	}{
+		// 10 is still running, so nothing after it may be committed
+		{complete: 12, commit: -1},
+		{complete: 11, commit: -1},
+		{complete: 14, commit: -1},
+		// 10 unblocks 11 and 12; 13 is the lowest unfinished offset now
+		{complete: 10, commit: 12},
+		{complete: 13, commit: 14},
+	}
+	for _, step := range steps {
+		commit, ok := tracker.complete(message(0, step.complete))
+		switch {
+		case step.commit < 0 && ok:
+			t.Fatalf("complete(%d) committed %d while a lower offset is unfinished", step.complete, commit.Offset)
+		case step.commit >= 0 && !ok:
+			t.Fatalf("complete(%d) committed nothing, want %d", step.complete, step.commit)
+		case step.commit >= 0 && commit.Offset != step.commit:
+			t.Fatalf("complete(%d) committed %d, want %d", step.complete, commit.Offset, step.commit)
+		}
+	}
+}
+
+func TestOffsetTrackerPartitionsAreIndependent(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.add(message(0, 0))
+	tracker.add(message(0, 1))
+	tracker.add(message(1, 0))
+	tracker.add(message(1, 1))
+
+	if _, ok := tracker.complete(message(0, 1)); ok {
+		t.Fatal("partition 0 committed past its unfinished offset 0")
+	}
+	commit, ok := tracker.complete(message(1, 0))
+	if !ok || commit.Partition != 1 || commit.Offset != 0 {
+		t.Fatalf("partition 1 commit = %d/%d, %v; want 1/0", commit.Partition, commit.Offset, ok)
+	}
+}
+
+func TestOffsetTrackerRefetchAfterRebalance(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.add(message(0, 5))
+	tracker.add(message(0, 6))
+
+	// The partition is fetched again from its last commit
+	tracker.add(message(0, 5))
+	commit, ok := tracker.complete(message(0, 5))
+	if !ok || commit.Offset != 5 {
+		t.Fatalf("commit after refetch = %d, %v; want 5", commit.Offset, ok)
+	}
+	if _, ok := tracker.complete(message(0, 6)); ok {
+		t.Fatal("stale offset 6 committed after the refetch")
+	}
+}
+
+// fakeReader serves a fixed list of messages, then blocks until ctx is done
+type fakeReader struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	commits  []kafka.Message
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commits = append(r.commits, msgs...)
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+func (r *fakeReader) lastCommit() (kafka.Message, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.commits) == 0 {
+		return kafka.Message{}, false
+	}
+	return r.commits[len(r.commits)-1], true
+}
+
+func TestPoolKeepsPerKeyOrder(t *testing.T) {
+	const keys, perKey = 8, 25
+
+	reader := &fakeReader{}
+	var offset int64
+	for i := 0; i < perKey; i++ {
+		for k := 0; k < keys; k++ {
+			reader.messages = append(reader.messages, kafka.Message{
+				Topic:     "test",
+				Partition: 0,
+				Offset:    offset,
+				Key:       []byte(fmt.Sprintf("payment-%d", k)),
+				Value:     []byte(fmt.Sprint(i)),
+			})
+			offset++
+		}
+	}
+	total := offset
+
+	c := &Consumer{
+		config: Config{Topic: "test", MaxAttempts: 1, Workers: 4, QueueSize: 4},
+		reader: reader,
+		logger: zap.NewNop(),
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string][]string)
+	var handled int64
+	allHandled := make(chan struct{})
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		// Uneven handling times let workers overtake each other
+		time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		key := string(msg.Key)
+		seen[key] = append(seen[key], string(msg.Value))
+		if handled++; handled == total {
+			close(allHandled)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.runPool(ctx, context.Background(), handler)
+	}()
+
+	select {
+	case <-allHandled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("messages not handled within 5s")
+	}
+	cancel()
+	<-done
+
+	for key, values := range seen {
+		if len(values) != perKey {
+			t.Fatalf("%s handled %d times, want %d", key, len(values), perKey)
+		}
+		for i, v := range values {
+			if v != fmt.Sprint(i) {
+				t.Fatalf("%s handled out of order: %v", key, values)
+			}
+		}
+	}
+
+	commit, ok := reader.lastCommit()
+	if !ok || commit.Offset != total-1 {
+		t.Fatalf("last commit = %d, %v; want %d", commit.Offset, ok, total-1)
+	}
+}
+
+func TestPoolDoesNotCommitPastUnfinishedMessage(t *testing.T) {
+	const workers = 2
+	// Offset 1 gets a worker of its own, so the others are not queued
+	// behind it
+	blocked := []byte("payment-blocked")
+	var keys [][]byte
+	for i := 0; len(keys) < 4; i++ {
+		key := []byte(fmt.Sprintf("payment-%d", i))
+		if workerFor(key, workers) != workerFor(blocked, workers) {
+			keys = append(keys, key)
+		}
+	}
+
+	reader := &fakeReader{}
+	for offset := int64(0); offset < 5; offset++ {
+		key := blocked
+		if offset != 1 {
+			key, keys = keys[0], keys[1:]
+		}
+		reader.messages = append(reader.messages, kafka.Message{
+			Topic:     "test",
+			Partition: 0,
+			Offset:    offset,
+			Key:       key,
+		})
+	}
+
+	c := &Consumer{
+		config: Config{Topic: "test", MaxAttempts: 1, Workers: workers, QueueSize: 4},
+		reader: reader,
+		logger: zap.NewNop(),
+	}
+
+	release := make(chan struct{})
+	var handled sync.WaitGroup
+	handled.Add(4)
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		if msg.Offset == 1 {
+			<-release
+			return nil
+		}
+		handled.Done()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.runPool(ctx, context.Background(), handler)
+	}()
+
+	// Every message but offset 1 is handled; only offset 0 may be committed
+	handled.Wait()
+	time.Sleep(20 * time.Millisecond)
+	if commit, ok := reader.lastCommit(); ok && commit.Offset > 0 {
+		t.Fatalf("committed offset %d while offset 1 is still running", commit.Offset)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if commit, ok := reader.lastCommit(); ok && commit.Offset == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			commit, _ := reader.lastCommit()
+			t.Fatalf("last commit = %d after offset 1 finished, want 4", commit.Offset)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
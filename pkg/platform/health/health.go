@@ -0,0 +1,150 @@
+// Package health serves the liveness and readiness probes of a service.
+// Liveness only reports that the process serves HTTP; readiness pings every
+// dependency and turns false once shutdown begins.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+)
+
+// Check pings a dependency. It must return once ctx is done.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Result is the outcome of one dependency check
+type Result struct {
+	Status    string  `json:"status"` // up, down
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the /readyz response
+type Report struct {
+	Status  string            `json:"status"` // ready, not_ready, shutting_down
+	Service string            `json:"service"`
+	Checks  map[string]Result `json:"checks"`
+}
+
+type Checker struct {
+	service  string
+	timeout  time.Duration
+	checks   []namedCheck
+	stopping atomic.Bool
+}
+
+// New returns a checker for service. Each dependency check gets timeout.
+func New(service string, timeout time.Duration) *Checker {
+	return &Checker{service: service, timeout: timeout}
+}
+
+// Add registers a dependency checked by /readyz. It must be called before
+// the HTTP server starts.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Register adds /livez and /readyz to r. /health stays as an alias of
+// /livez for existing scripts.
+func (c *Checker) Register(r gin.IRoutes) {
+	r.GET("/livez", c.live)
+	r.GET("/health", c.live)
+	r.GET("/readyz", c.ready)
+}
+
+// Hook marks the service not ready when shutdown begins, then keeps serving
+// for drainDelay so load balancers see the failing probe and stop routing
+// before the listener closes. Append it after the HTTP server, so it stops
+// first. The delay counts against the shutdown deadline.
+func (c *Checker) Hook(drainDelay time.Duration) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: "readiness",
+		Stop: func(ctx context.Context) error {
+			c.stopping.Store(true)
+			if drainDelay <= 0 {
+				return nil
+			}
+
+			timer := time.NewTimer(drainDelay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("drain delay cut short by the shutdown deadline: %w", ctx.Err())
+			}
+		},
+	}
+}
+
+// Check runs every dependency check in parallel
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Status: "ready", Service: c.service, Checks: make(map[string]Result, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+			result := c.run(ctx, nc.check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[nc.name] = result
+			if result.Status != "up" {
+				report.Status = "not_ready"
+			}
+		}(nc)
+	}
+	wg.Wait()
+
+	// Dependencies are still reported while shutting down, to tell a
+	// drain from an outage
+	if c.stopping.Load() {
+		report.Status = "shutting_down"
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := Result{Status: "up", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		result.Status = "down"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (c *Checker) live(gc *gin.Context) {
+	gc.JSON(http.StatusOK, gin.H{"status": "ok", "service": c.service})
+}
+
+func (c *Checker) ready(gc *gin.Context) {
+	report := c.Check(gc.Request.Context())
+	status := http.StatusOK
+	if report.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	gc.JSON(status, report)
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+)
+
+func TestHookDrainsBeforeServerStops(t *testing.T) {
+	const drainDelay = 100 * time.Millisecond
+	checker := New("test", time.Second)
+
+	var flipped, serverStopped time.Time
+	var readyAtServerStop string
+	done := make(chan struct{})
+	go func() {
+		// Watch the probe the way a load balancer would
+		defer close(done)
+		for flipped.IsZero() {
+			if checker.Check(context.Background()).Status == "shutting_down" {
+				flipped = time.Now()
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	app := lifecycle.New(zap.NewNop(), time.Second)
+	app.Append(
+		lifecycle.Hook{
+			Name: "http",
+			Stop: func(context.Context) error {
+				serverStopped = time.Now()
+				readyAtServerStop = checker.Check(context.Background()).Status
+				return nil
+			},
+		},
+		checker.Hook(drainDelay),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := app.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if readyAtServerStop != "shutting_down" {
+		t.Fatalf("readiness when the server stopped = %q, want shutting_down", readyAtServerStop)
+	}
+	if waited := serverStopped.Sub(flipped); waited < drainDelay-5*time.Millisecond {
+		t.Fatalf("server stopped %v after readiness flipped, want at least %v", waited, drainDelay)
+	}
+}
+
+func TestHookDrainDelayBoundedByDeadline(t *testing.T) {
+	checker := New("test", time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := checker.Hook(time.Minute).Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop past the deadline: got %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Stop took %v, want it to end at the deadline", elapsed)
+	}
+	if status := checker.Check(context.Background()).Status; status != "shutting_down" {
+		t.Fatalf("status = %q, want shutting_down", status)
+	}
+}
+
+func TestHookWithoutDrainDelay(t *testing.T) {
+	checker := New("test", time.Second)
+	if status := checker.Check(context.Background()).Status; status != "ready" {
+		t.Fatalf("status before shutdown = %q, want ready", status)
+	}
+	if err := checker.Hook(0).Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if status := checker.Check(context.Background()).Status; status != "shutting_down" {
+		t.Fatalf("status after shutdown = %q, want shutting_down", status)
+	}
+}
@@ -0,0 +1,58 @@
+// Package httpserver builds the gin router and HTTP server shared by the
+// services
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
+)
+
+// NewRouter returns a gin engine with recovery, tracing, /metrics and the
+// probes of checker already set up
+func NewRouter(checker *health.Checker) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(telemetry.TracingMiddleware())
+
+	// Prometheus metrics endpoint
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	checker.Register(r)
+
+	return r
+}
+
+// Hook serves handler on port. The listener is opened in Start, so a port
+// that is already taken fails startup instead of crashing later.
+func Hook(port string, handler http.Handler) lifecycle.Hook {
+	srv := &http.Server{Addr: ":" + port, Handler: handler}
+
+	return lifecycle.Hook{
+		Name: "http server",
+		Start: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			telemetry.Logger.Info("HTTP server starting", zap.String("service", telemetry.ServiceName), zap.String("port", port))
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					telemetry.Logger.Fatal("HTTP server failed", zap.Error(err))
+				}
+			}()
+			return nil
+		},
+		Stop: srv.Shutdown,
+	}
+}
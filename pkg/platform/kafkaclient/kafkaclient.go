@@ -0,0 +1,79 @@
+// Package kafkaclient creates Kafka writers
+package kafkaclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+)
+
+// Brokers splits a comma separated KAFKA_BROKERS value
+func Brokers(s string) []string {
+	var brokers []string
+	for _, b := range strings.Split(s, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	return brokers
+}
+
+// NewWriter returns a writer for topic
+func NewWriter(brokers []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+	}
+}
+
+// CloseHook flushes and closes the writer on shutdown. Messages still
+// pending at the shutdown deadline are lost.
+func CloseHook(writer *kafka.Writer) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: "kafka writer " + writer.Topic,
+		Stop: func(ctx context.Context) error {
+			done := make(chan struct{})
+			var err error
+			go func() {
+				defer close(done)
+				err = writer.Close()
+			}()
+			if waitErr := lifecycle.Wait(ctx, done); waitErr != nil {
+				return waitErr
+			}
+			return err
+		},
+	}
+}
+
+// Check reports ready when at least one broker accepts a connection and
+// returns the cluster metadata
+func Check(brokers []string) health.Check {
+	return func(ctx context.Context) error {
+		if len(brokers) == 0 {
+			return errors.New("no brokers configured")
+		}
+		var errs []error
+		for _, broker := range brokers {
+			conn, err := kafka.DialContext(ctx, "tcp", broker)
+			if err == nil {
+				if deadline, ok := ctx.Deadline(); ok {
+					conn.SetDeadline(deadline)
+				}
+				_, err = conn.Brokers()
+				conn.Close()
+			}
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+}
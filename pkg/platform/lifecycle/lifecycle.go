@@ -0,0 +1,127 @@
+// Package lifecycle runs a service: it starts components in order, waits
+// for SIGINT/SIGTERM and stops them in reverse order within a deadline.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Hook is a component of the service. Start must not block: long running
+// work goes into a goroutine using the context passed to Start, which is
+// cancelled when shutdown begins. Either function may be nil.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+type Runner struct {
+	logger          *zap.Logger
+	shutdownTimeout time.Duration
+	hooks           []Hook
+}
+
+func New(logger *zap.Logger, shutdownTimeout time.Duration) *Runner {
+	return &Runner{logger: logger, shutdownTimeout: shutdownTimeout}
+}
+
+// Append adds hooks. They start in the order added and stop in reverse, so
+// clients should be added before the components that use them.
+func (r *Runner) Append(hooks ...Hook) {
+	r.hooks = append(r.hooks, hooks...)
+}
+
+// OnStop adds a hook that only has cleanup, e.g. closing a client
+func (r *Runner) OnStop(name string, stop func(ctx context.Context) error) {
+	r.Append(Hook{Name: name, Stop: stop})
+}
+
+// Go returns a hook that runs fn in a goroutine until shutdown cancels its
+// context, for background loops such as consumers and refreshers. Stop
+// waits for fn to return, so the clients it uses are closed after it.
+func Go(name string, fn func(ctx context.Context)) Hook {
+	done := make(chan struct{})
+	return Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			go func() {
+				defer close(done)
+				fn(ctx)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error { return Wait(ctx, done) },
+	}
+}
+
+// Wait blocks until done is closed or ctx expires
+func Wait(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("not finished before the shutdown deadline: %w", ctx.Err())
+	}
+}
+
+// Run starts every hook and blocks until a signal arrives or ctx is done,
+// then stops the started hooks. A failing start stops the hooks started
+// before it and returns the error.
+func (r *Runner) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	started := 0
+	for _, h := range r.hooks {
+		if h.Start != nil {
+			if err := h.Start(runCtx); err != nil {
+				cancel()
+				stopErr := r.stop(started)
+				return errors.Join(fmt.Errorf("start %s: %w", h.Name, err), stopErr)
+			}
+		}
+		started++
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case sig := <-quit:
+		r.logger.Info("Shutting down", zap.String("signal", sig.String()))
+	case <-ctx.Done():
+		r.logger.Info("Shutting down", zap.Error(ctx.Err()))
+	}
+
+	cancel()
+	return r.stop(started)
+}
+
+// stop runs the Stop functions of the first n hooks in reverse order, all
+// sharing the shutdown deadline
+func (r *Runner) stop(n int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := n - 1; i >= 0; i-- {
+		h := r.hooks[i]
+		if h.Stop == nil {
+			continue
+		}
+		if err := h.Stop(ctx); err != nil {
+			r.logger.Error("Error stopping component", zap.String("component", h.Name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("stop %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
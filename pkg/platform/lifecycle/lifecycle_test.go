@@ -0,0 +1,147 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recorder builds hooks that log their calls in order
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) hook(name string, startErr, stopErr error) Hook {
+	return Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			r.calls = append(r.calls, "start "+name)
+			return startErr
+		},
+		Stop: func(context.Context) error {
+			r.calls = append(r.calls, "stop "+name)
+			return stopErr
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	failed := errors.New("failed")
+
+	tests := []struct {
+		name      string
+		hooks     func(r *recorder) []Hook
+		wantCalls []string
+		wantErr   []string
+	}{
+		{
+			"reverse stop order",
+			func(r *recorder) []Hook {
+				return []Hook{r.hook("db", nil, nil), {Name: "no-op"}, r.hook("consumer", nil, nil)}
+			},
+			[]string{"start db", "start consumer", "stop consumer", "stop db"},
+			nil,
+		},
+		{
+			"start failure stops the started hooks",
+			func(r *recorder) []Hook {
+				return []Hook{r.hook("db", nil, nil), r.hook("consumer", failed, nil), r.hook("server", nil, nil)}
+			},
+			[]string{"start db", "start consumer", "stop db"},
+			[]string{"start consumer: failed"},
+		},
+		{
+			"stop errors are joined",
+			func(r *recorder) []Hook {
+				return []Hook{r.hook("db", nil, failed), r.hook("consumer", nil, nil), r.hook("server", nil, failed)}
+			},
+			[]string{"start db", "start consumer", "start server", "stop server", "stop consumer", "stop db"},
+			[]string{"stop server: failed", "stop db: failed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			runner := New(zap.NewNop(), time.Second)
+			runner.Append(tt.hooks(rec)...)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := runner.Run(ctx)
+
+			if !reflect.DeepEqual(rec.calls, tt.wantCalls) {
+				t.Fatalf("calls = %v, want %v", rec.calls, tt.wantCalls)
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Run error = %v", err)
+				}
+				return
+			}
+			if err == nil || !errors.Is(err, failed) || err.Error() != strings.Join(tt.wantErr, "\n") {
+				t.Fatalf("Run error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGoStopsWithTheRunner(t *testing.T) {
+	var order []string
+	runner := New(zap.NewNop(), time.Second)
+	runner.OnStop("client", func(context.Context) error {
+		order = append(order, "client closed")
+		return nil
+	})
+	runner.Append(Go("refresher", func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		order = append(order, "refresher returned")
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := runner.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client is closed only after the loop using it returned
+	if want := []string{"refresher returned", "client closed"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
+
+func TestStopDeadline(t *testing.T) {
+	runner := New(zap.NewNop(), 20*time.Millisecond)
+	runner.Append(Go("stuck", func(context.Context) { select {} }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := runner.Run(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run error = %v, want the shutdown deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run took %v, want about the 20ms shutdown timeout", elapsed)
+	}
+}
+
+func TestWait(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+	if err := Wait(context.Background(), done); err != nil {
+		t.Fatalf("Wait on a closed channel = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Wait(ctx, make(chan struct{})); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait after cancel = %v, want context.Canceled", err)
+	}
+}
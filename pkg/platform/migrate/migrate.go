@@ -0,0 +1,335 @@
+// Package migrate applies the versioned SQL migrations embedded in a service
+// binary and records them in the schema_migrations table.
+//
+// Migrations are pairs of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, applied in file name order. Each one runs in its
+// own transaction together with its schema_migrations row.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// lockID is the advisory lock held while migrating, so replicas starting
+// at the same time apply each migration once. Advisory locks are scoped to
+// the database, so services sharing a server do not block each other.
+const lockID int64 = 7_245_690_214
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+type Migration struct {
+	// Version is the file name without the suffix, e.g.
+	// 001_ledger_service_schema
+	Version string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of Up, used to detect migrations edited after
+	// they were applied
+	Checksum string
+}
+
+// Status describes a migration as seen by the database
+type Status struct {
+	Version   string     `json:"version"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Drift reports that the file no longer matches what was applied
+	Drift bool `json:"drift,omitempty"`
+	// Unknown reports an applied version without a file, e.g. after
+	// rolling back to an older binary
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Load reads the migrations in the root of fsys. Every up file needs a
+// matching down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[string]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var version string
+		switch {
+		case strings.HasSuffix(name, upSuffix):
+			version = strings.TrimSuffix(name, upSuffix)
+		case strings.HasSuffix(name, downSuffix):
+			version = strings.TrimSuffix(name, downSuffix)
+		default:
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version}
+			byVersion[version] = m
+		}
+		if strings.HasSuffix(name, upSuffix) {
+			m.Up = string(data)
+			sum := sha256.Sum256(data)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s has no %s file", m.Version, upSuffix)
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("migration %s has no %s file", m.Version, downSuffix)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+func New(db *sql.DB, fsys fs.FS, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+// applied is a schema_migrations row. Checksum is empty for versions
+// recorded by the old init scripts, which are applied again.
+type applied struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// Up applies every pending migration. It refuses to run when an applied
+// migration was changed since, because the database would no longer match
+// the files.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkDrift(done); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if row, ok := done[migration.Version]; ok && row.checksum != "" {
+				continue
+			}
+			start := time.Now()
+			if err := apply(ctx, conn, migration.Up, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					INSERT INTO schema_migrations (version, checksum, applied_at)
+					VALUES ($1, $2, NOW())
+					ON CONFLICT (version) DO UPDATE SET checksum = EXCLUDED.checksum, applied_at = EXCLUDED.applied_at
+				`, migration.Version, migration.Checksum)
+				return err
+			}); err != nil {
+				return fmt.Errorf("apply %s: %w", migration.Version, err)
+			}
+			m.logger.Info("Applied migration",
+				zap.String("version", migration.Version),
+				zap.Duration("duration", time.Since(start)),
+			)
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkDrift(done); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := apply(ctx, conn, migration.Down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("roll back %s: %w", migration.Version, err)
+			}
+			m.logger.Info("Rolled back migration", zap.String("version", migration.Version))
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration followed by applied versions that
+// have no file
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		statuses = m.statuses(done)
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) statuses(done map[string]applied) []Status {
+	var statuses []Status
+	known := make(map[string]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		status := Status{Version: migration.Version}
+		if row, ok := done[migration.Version]; ok {
+			appliedAt := row.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Drift = row.checksum != "" && row.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	var unknown []string
+	for version := range done {
+		if !known[version] {
+			unknown = append(unknown, version)
+		}
+	}
+	sort.Strings(unknown)
+	for _, version := range unknown {
+		appliedAt := done[version].appliedAt
+		statuses = append(statuses, Status{Version: version, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+	}
+	return statuses
+}
+
+func (m *Migrator) checkDrift(done map[string]applied) error {
+	known := make(map[string]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		row, ok := done[migration.Version]
+		if ok && row.checksum != "" && row.checksum != migration.Checksum {
+			return fmt.Errorf("migration %s was modified after it was applied (checksum %s, file %s)",
+				migration.Version, row.checksum, migration.Checksum)
+		}
+	}
+	for version := range done {
+		if !known[version] {
+			m.logger.Warn("Database has a migration this binary does not know", zap.String("version", version))
+		}
+	}
+	return nil
+}
+
+// locked runs fn on a single connection holding the migration lock. The
+// lock is a session lock, so it is released even if the process dies.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// ensureTable creates schema_migrations, adding the checksum column to
+// tables created by the old init scripts
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(50) PRIMARY KEY,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum VARCHAR(64)`,
+	}
+	for _, statement := range statements {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("create schema_migrations: %w", err)
+		}
+	}
+	return nil
+}
+
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[string]applied, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT version, COALESCE(checksum, ''), COALESCE(applied_at, NOW())
+		FROM schema_migrations
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[string]applied)
+	for rows.Next() {
+		var version string
+		var row applied
+		if err := rows.Scan(&version, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = row
+	}
+	return done, rows.Err()
+}
+
+// apply runs script and record in one transaction
+func apply(ctx context.Context, conn *sql.Conn, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Without arguments lib/pq uses the simple query protocol, which
+	// accepts a whole script
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
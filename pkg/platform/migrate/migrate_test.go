@@ -0,0 +1,144 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_add_index.up.sql":       file("CREATE INDEX i ON t (c);"),
+		"002_add_index.down.sql":     file("DROP INDEX i;"),
+		"001_schema.up.sql":          file("CREATE TABLE t (c INT);"),
+		"001_schema.down.sql":        file("DROP TABLE t;"),
+		"README.md":                  file("not a migration"),
+		"010_later_feature.up.sql":   file("ALTER TABLE t ADD d INT;"),
+		"010_later_feature.down.sql": file("ALTER TABLE t DROP d;"),
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Migration{
+		{Version: "001_schema", Up: "CREATE TABLE t (c INT);", Down: "DROP TABLE t;", Checksum: checksum("CREATE TABLE t (c INT);")},
+		{Version: "002_add_index", Up: "CREATE INDEX i ON t (c);", Down: "DROP INDEX i;", Checksum: checksum("CREATE INDEX i ON t (c);")},
+		{Version: "010_later_feature", Up: "ALTER TABLE t ADD d INT;", Down: "ALTER TABLE t DROP d;", Checksum: checksum("ALTER TABLE t ADD d INT;")},
+	}
+	if !reflect.DeepEqual(migrations, want) {
+		t.Fatalf("Load = %+v, want %+v", migrations, want)
+	}
+}
+
+func TestLoadRejectsIncompleteMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{"missing down", fstest.MapFS{"001_schema.up.sql": file("CREATE TABLE t (c INT);")}, "001_schema has no .down.sql file"},
+		{"missing up", fstest.MapFS{"001_schema.down.sql": file("DROP TABLE t;")}, "001_schema has no .up.sql file"},
+		{"empty up", fstest.MapFS{"001_schema.up.sql": file(""), "001_schema.down.sql": file("DROP TABLE t;")}, "has no .up.sql file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func newTestMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	m, err := New(nil, fstest.MapFS{
+		"001_schema.up.sql":      file("CREATE TABLE t (c INT);"),
+		"001_schema.down.sql":    file("DROP TABLE t;"),
+		"002_add_index.up.sql":   file("CREATE INDEX i ON t (c);"),
+		"002_add_index.down.sql": file("DROP INDEX i;"),
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestCheckDrift(t *testing.T) {
+	m := newTestMigrator(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		done    map[string]applied
+		wantErr bool
+	}{
+		{"nothing applied", nil, false},
+		{"unchanged", map[string]applied{"001_schema": {checksum("CREATE TABLE t (c INT);"), now}}, false},
+		// Rows recorded by the old init scripts have no checksum
+		{"recorded without checksum", map[string]applied{"001_schema": {"", now}}, false},
+		{"unknown version", map[string]applied{"003_newer_binary": {"abc", now}}, false},
+		{"edited after it was applied", map[string]applied{"002_add_index": {checksum("CREATE INDEX i ON t (d);"), now}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.checkDrift(tt.done); (err != nil) != tt.wantErr {
+				t.Fatalf("checkDrift error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatuses(t *testing.T) {
+	m := newTestMigrator(t)
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	got := m.statuses(map[string]applied{
+		"001_schema":     {"stale", at},
+		"004_from_newer": {"abc", at},
+		"003_from_newer": {"", at},
+	})
+	want := []Status{
+		{Version: "001_schema", Applied: true, AppliedAt: &at, Drift: true},
+		{Version: "002_add_index"},
+		// Versions without a file follow in order
+		{Version: "003_from_newer", Applied: true, AppliedAt: &at, Unknown: true},
+		{Version: "004_from_newer", Applied: true, AppliedAt: &at, Unknown: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("statuses = %+v, want %+v", got, want)
+	}
+}
+
+func TestRunCommandRejectsInvalidArgs(t *testing.T) {
+	m := newTestMigrator(t)
+
+	// Rejected before touching the database
+	for _, args := range [][]string{
+		nil,
+		{"redo"},
+		{"down", "-steps", "0"},
+		{"down", "-steps", "all"},
+	} {
+		if err := m.RunCommand(context.Background(), args, io.Discard); err == nil {
+			t.Fatalf("RunCommand(%v) succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Decimal is an amount in major units as it appears in JSON. It accepts a
+// number (100.10) or a string ("100.10") and is written back as a number
+// with the digits it was given, so it never passes through float64.
+type Decimal string
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = ""
+		return nil
+	}
+
+	s := string(data)
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		s = strings.TrimSpace(s)
+	}
+	var n json.Number
+	if err := json.Unmarshal([]byte(s), &n); err != nil || strings.ContainsAny(s, "eE") {
+		return fmt.Errorf("%w %q: want a decimal number", ErrInvalidAmount, s)
+	}
+	*d = Decimal(s)
+	return nil
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if d == "" {
+		return []byte("null"), nil
+	}
+	return []byte(d), nil
+}
+
+// Money converts the amount to minor units of currency, see Parse
+func (d Decimal) Money(currency string) (Money, error) {
+	return Parse(string(d), currency)
+}
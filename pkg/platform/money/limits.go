@@ -0,0 +1,98 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Limits holds one amount per currency, e.g. a cap of 10000.00 USD and
+// 1500000 JPY. A currency without an entry has no limit; amounts are never
+// compared across currencies.
+type Limits map[string]Money
+
+// ParseLimits parses a comma separated list of "<currency>:<amount>"
+// entries in major units, e.g. "USD:10000,JPY:1500000"
+func ParseLimits(spec string) (Limits, error) {
+	limits := Limits{}
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		currency, amount, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w %q, expected <currency>:<amount>", ErrInvalidAmount, entry)
+		}
+		m, err := ParseLimit(currency, amount)
+		if err != nil {
+			return nil, err
+		}
+		limits[m.Currency] = m
+	}
+	return limits, nil
+}
+
+// ParseLimit parses a single limit in major units of currency. Limits
+// cannot be negative.
+func ParseLimit(currency, amount string) (Money, error) {
+	m, err := Parse(strings.TrimSpace(amount), strings.ToUpper(strings.TrimSpace(currency)))
+	if err != nil {
+		return Money{}, err
+	}
+	return m, checkLimit(m)
+}
+
+func checkLimit(m Money) error {
+	if m.Minor < 0 {
+		return fmt.Errorf("%w %s %s: limits cannot be negative", ErrInvalidAmount, m, m.Currency)
+	}
+	return nil
+}
+
+// Get returns the limit of a currency
+func (l Limits) Get(currency string) (Money, bool) {
+	m, ok := l[currency]
+	return m, ok
+}
+
+// String formats the limits the way ParseLimits reads them, sorted by currency
+func (l Limits) String() string {
+	entries := make([]string, 0, len(l))
+	for currency, m := range l {
+		entries = append(entries, currency+":"+m.String())
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// UnmarshalJSON reads an object of currency to amount in major units, e.g.
+// {"USD": 10000, "JPY": "1500000"}, with the same checks as ParseLimit
+func (l *Limits) UnmarshalJSON(data []byte) error {
+	var raw map[string]Decimal
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	limits := make(Limits, len(raw))
+	for currency, amount := range raw {
+		currency = strings.ToUpper(currency)
+		m, err := amount.Money(currency)
+		if err != nil {
+			return err
+		}
+		if err := checkLimit(m); err != nil {
+			return err
+		}
+		limits[currency] = m
+	}
+	*l = limits
+	return nil
+}
+
+func (l Limits) MarshalJSON() ([]byte, error) {
+	raw := make(map[string]Decimal, len(l))
+	for currency, m := range l {
+		raw[currency] = m.Decimal()
+	}
+	return json.Marshal(raw)
+}
@@ -0,0 +1,187 @@
+// Package money represents amounts as an integer number of minor units of an
+// ISO 4217 currency, so an amount crosses every service unchanged instead of
+// drifting through float64.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrInvalidAmount   = errors.New("invalid amount")
+	// ErrPrecision is returned for amounts finer than the currency's minor
+	// unit, e.g. 1.005 USD or 10.5 JPY
+	ErrPrecision = errors.New("amount is more precise than the currency's minor unit")
+)
+
+// exponents maps ISO 4217 codes to the number of digits of their minor unit
+var exponents = map[string]int{
+	// No minor unit
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+
+	// Three digit minor unit
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+
+	// Two digit minor unit
+	"AED": 2, "AMD": 2, "ARS": 2, "AUD": 2, "AZN": 2, "BGN": 2, "BRL": 2,
+	"BYN": 2, "CAD": 2, "CHF": 2, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2,
+	"EGP": 2, "EUR": 2, "GBP": 2, "GEL": 2, "HKD": 2, "HUF": 2, "IDR": 2,
+	"ILS": 2, "INR": 2, "KGS": 2, "KZT": 2, "MDL": 2, "MXN": 2, "MYR": 2,
+	"NGN": 2, "NOK": 2, "NZD": 2, "PHP": 2, "PKR": 2, "PLN": 2, "QAR": 2,
+	"RON": 2, "RSD": 2, "RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2,
+	"TJS": 2, "TMT": 2, "TRY": 2, "TWD": 2, "UAH": 2, "USD": 2, "UZS": 2,
+	"ZAR": 2,
+}
+
+// Exponent returns the number of minor unit digits of currency
+func Exponent(currency string) (int, error) {
+	exp, ok := exponents[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+	return exp, nil
+}
+
+// Money is an amount in minor units, e.g. 10010 USD is 100.10 USD and 500
+// JPY is 500 JPY. Embedded in a JSON message it adds the amount_minor and
+// currency fields.
+type Money struct {
+	Minor    int64  `json:"amount_minor"`
+	Currency string `json:"currency"`
+}
+
+// New returns minor units of currency
+func New(minor int64, currency string) (Money, error) {
+	if _, err := Exponent(currency); err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// Parse converts a decimal amount in major units, e.g. "100.10", to minor
+// units of currency. Trailing zeros beyond the minor unit are accepted;
+// other digits there fail with ErrPrecision.
+func Parse(amount, currency string) (Money, error) {
+	exp, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	s := amount
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || !digits(whole) || !digits(frac) || (strings.Contains(s, ".") && frac == "") {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		if strings.Trim(frac[exp:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %s has %d decimal places", ErrPrecision, currency, exp)
+		}
+		frac = frac[:exp]
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) exponent() int {
+	return exponents[m.Currency]
+}
+
+// String formats the amount in major units with every minor unit digit,
+// e.g. "100.10", "500" for JPY or "1.250" for KWD
+func (m Money) String() string {
+	exp := m.exponent()
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	s := strconv.FormatInt(minor, 10)
+	if exp == 0 {
+		return sign + s
+	}
+	if len(s) <= exp {
+		s = strings.Repeat("0", exp-len(s)+1) + s
+	}
+	return sign + s[:len(s)-exp] + "." + s[len(s)-exp:]
+}
+
+// Decimal returns the amount in major units for JSON
+func (m Money) Decimal() Decimal {
+	return Decimal(m.String())
+}
+
+// Float64 returns the amount in major units. It is only meant for scores,
+// thresholds and metrics, never for arithmetic on the amount.
+func (m Money) Float64() float64 {
+	return float64(m.Minor) / math.Pow10(m.exponent())
+}
+
+// IsPositive reports whether the amount is above zero
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+// Percent returns the given basis points (hundredths of a percent) of the
+// amount, rounded half away from zero to a minor unit, e.g. Percent(200)
+// is 2%. The product is computed in 128 bits; a result beyond int64
+// saturates at math.MaxInt64 or math.MinInt64.
+func (m Money) Percent(basis int64) Money {
+	negative := (m.Minor < 0) != (basis < 0)
+	hi, lo := bits.Mul64(absUint(m.Minor), absUint(basis))
+
+	// The quotient of hi:lo by 10000 fits 64 bits only if hi < 10000
+	q, r := uint64(math.MaxUint64), uint64(0)
+	if hi < 10000 {
+		q, r = bits.Div64(hi, lo, 10000)
+		if r >= 5000 {
+			q++
+		}
+	}
+
+	switch {
+	case !negative && q > math.MaxInt64:
+		return Money{Minor: math.MaxInt64, Currency: m.Currency}
+	case negative && q > 1<<63:
+		return Money{Minor: math.MinInt64, Currency: m.Currency}
+	case negative:
+		return Money{Minor: -int64(q), Currency: m.Currency}
+	}
+	return Money{Minor: int64(q), Currency: m.Currency}
+}
+
+func absUint(n int64) uint64 {
+	if n < 0 {
+		return uint64(-n)
+	}
+	return uint64(n)
+}
@@ -0,0 +1,181 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		minor    int64
+	}{
+		// Exponent 0
+		{"500", "JPY", 500},
+		{"500.0", "JPY", 500},
+		// Exponent 2
+		{"100.10", "USD", 10010},
+		{"100.1", "USD", 10010},
+		{"100", "USD", 10000},
+		{"0.01", "USD", 1},
+		{"1.500", "USD", 150},
+		{"-2.50", "EUR", -250},
+		// Exponent 3
+		{"1.250", "KWD", 1250},
+		{"1.25", "KWD", 1250},
+		{"0.001", "KWD", 1},
+	}
+	for _, tt := range tests {
+		m, err := Parse(tt.amount, tt.currency)
+		if err != nil {
+			t.Fatalf("Parse(%q, %s): %v", tt.amount, tt.currency, err)
+		}
+		if m.Minor != tt.minor || m.Currency != tt.currency {
+			t.Fatalf("Parse(%q, %s) = %d %s, want %d %s", tt.amount, tt.currency, m.Minor, m.Currency, tt.minor, tt.currency)
+		}
+	}
+}
+
+func TestParseRejectsExcessPrecision(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+	}{
+		{"10.5", "JPY"},
+		{"1.005", "USD"},
+		{"0.0001", "KWD"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.amount, tt.currency); !errors.Is(err, ErrPrecision) {
+			t.Fatalf("Parse(%q, %s) error = %v, want ErrPrecision", tt.amount, tt.currency, err)
+		}
+	}
+}
+
+func TestParseRejectsInvalidInput(t *testing.T) {
+	for _, amount := range []string{"", ".5", "1.", "1e3", "abc", "1,00", "--1"} {
+		if _, err := Parse(amount, "USD"); !errors.Is(err, ErrInvalidAmount) {
+			t.Fatalf("Parse(%q) error = %v, want ErrInvalidAmount", amount, err)
+		}
+	}
+	if _, err := Parse("1", "XXX"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Fatalf("Parse with unknown currency error = %v, want ErrUnknownCurrency", err)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{Minor: 500, Currency: "JPY"}, "500"},
+		{Money{Minor: -7, Currency: "JPY"}, "-7"},
+		{Money{Minor: 10010, Currency: "USD"}, "100.10"},
+		{Money{Minor: 5, Currency: "USD"}, "0.05"},
+		{Money{Minor: -250, Currency: "EUR"}, "-2.50"},
+		{Money{Minor: 1250, Currency: "KWD"}, "1.250"},
+		{Money{Minor: 1, Currency: "KWD"}, "0.001"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Fatalf("%d %s formats as %q, want %q", tt.money.Minor, tt.money.Currency, got, tt.want)
+		}
+		back, err := Parse(tt.want, tt.money.Currency)
+		if err != nil || back != tt.money {
+			t.Fatalf("Parse(%q) = %v, %v; want %v", tt.want, back, err, tt.money)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		money Money
+		basis int64
+		want  int64
+	}{
+		// 2% of 1000 JPY
+		{Money{Minor: 1000, Currency: "JPY"}, 200, 20},
+		// 1.5% of 333 JPY is 4.995, rounded to 5
+		{Money{Minor: 333, Currency: "JPY"}, 150, 5},
+		// 2% of 100.10 USD
+		{Money{Minor: 10010, Currency: "USD"}, 200, 200},
+		// 2.5% of 0.30 USD is 0.0075, rounded half away from zero
+		{Money{Minor: 30, Currency: "USD"}, 250, 1},
+		{Money{Minor: -30, Currency: "USD"}, 250, -1},
+		// 1.5% of 0.033 KWD is 0.000495, rounded down
+		{Money{Minor: 33, Currency: "KWD"}, 150, 0},
+		{Money{Minor: 12345, Currency: "KWD"}, 10000, 12345},
+		// Products beyond int64 are exact, results beyond it saturate
+		{Money{Minor: math.MaxInt64, Currency: "JPY"}, 10000, math.MaxInt64},
+		{Money{Minor: math.MaxInt64, Currency: "JPY"}, 220, 202914184810805068},
+		{Money{Minor: math.MinInt64, Currency: "JPY"}, 10000, math.MinInt64},
+		{Money{Minor: math.MaxInt64, Currency: "JPY"}, 20000, math.MaxInt64},
+		{Money{Minor: math.MinInt64, Currency: "JPY"}, 20000, math.MinInt64},
+		{Money{Minor: math.MinInt64, Currency: "JPY"}, math.MinInt64, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		got := tt.money.Percent(tt.basis)
+		if got.Minor != tt.want || got.Currency != tt.money.Currency {
+			t.Fatalf("%v.Percent(%d) = %d %s, want %d %s", tt.money, tt.basis, got.Minor, got.Currency, tt.want, tt.money.Currency)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("usd:10000, JPY:1500000,KWD:3000.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Limits{
+		"USD": {Minor: 1000000, Currency: "USD"},
+		"JPY": {Minor: 1500000, Currency: "JPY"},
+		"KWD": {Minor: 3000500, Currency: "KWD"},
+	}
+	if len(limits) != len(want) {
+		t.Fatalf("ParseLimits = %v, want %v", limits, want)
+	}
+	for currency, m := range want {
+		if got, ok := limits.Get(currency); !ok || got != m {
+			t.Fatalf("limit of %s = %v, %v; want %v", currency, got, ok, m)
+		}
+	}
+	if _, ok := limits.Get("EUR"); ok {
+		t.Fatal("EUR has a limit although none was configured")
+	}
+	if got := limits.String(); got != "JPY:1500000,KWD:3000.500,USD:10000.00" {
+		t.Fatalf("String() = %q", got)
+	}
+
+	for _, spec := range []string{"USD", "USD:1.001", "XXX:1", "USD:-1", "USD=1"} {
+		if _, err := ParseLimits(spec); err == nil {
+			t.Fatalf("ParseLimits(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestLimitsJSON(t *testing.T) {
+	var limits Limits
+	if err := json.Unmarshal([]byte(`{"usd": 0.10, "JPY": "15"}`), &limits); err != nil {
+		t.Fatal(err)
+	}
+	if m := limits["USD"]; m.Minor != 10 {
+		t.Fatalf("USD = %v, want 10 minor units", m)
+	}
+	if m := limits["JPY"]; m.Minor != 15 {
+		t.Fatalf("JPY = %v, want 15 minor units", m)
+	}
+	if err := json.Unmarshal([]byte(`{"JPY": 0.5}`), &limits); !errors.Is(err, ErrPrecision) {
+		t.Fatalf("unmarshal of 0.5 JPY error = %v, want ErrPrecision", err)
+	}
+	for _, data := range []string{`{"USD": -1}`, `{"JPY": "-15"}`, `{"XXX": 1}`} {
+		if err := json.Unmarshal([]byte(data), &limits); err == nil {
+			t.Fatalf("unmarshal of %s succeeded", data)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"USD": -1}`), &limits); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("unmarshal of a negative limit error = %v, want ErrInvalidAmount", err)
+	}
+}
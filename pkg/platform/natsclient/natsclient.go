@@ -0,0 +1,235 @@
+// Package natsclient connects to NATS and carries trace context through
+// NATS message headers
+package natsclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
+)
+
+// Connect opens a connection and its JetStream context
+func Connect(url string) (*nats.Conn, nats.JetStreamContext, error) {
+	nc, err := nats.Connect(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, nil, err
+	}
+	return nc, js, nil
+}
+
+// EnsureStreams creates the JetStream streams that do not exist yet.
+// Existing streams are left as they are.
+func EnsureStreams(js nats.JetStreamContext, streams ...*nats.StreamConfig) error {
+	for _, cfg := range streams {
+		_, err := js.StreamInfo(cfg.Name)
+		if err == nats.ErrStreamNotFound {
+			_, err = js.AddStream(cfg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueueConsumer is a durable JetStream push consumer whose messages the
+// replicas of a service share through a queue group
+type QueueConsumer struct {
+	Stream  string
+	Durable string
+	Subject string
+	Queue   string
+	AckWait time.Duration
+}
+
+func (c QueueConsumer) config() *nats.ConsumerConfig {
+	return &nats.ConsumerConfig{
+		Durable:        c.Durable,
+		DeliverSubject: "_deliver." + c.Stream + "." + c.Durable,
+		DeliverGroup:   c.Queue,
+		FilterSubject:  c.Subject,
+		AckPolicy:      nats.AckExplicitPolicy,
+		AckWait:        c.AckWait,
+	}
+}
+
+// EnsureQueueConsumer creates the consumer if it does not exist yet. Every
+// replica asks for the same config, so concurrent starts agree on it.
+func EnsureQueueConsumer(js nats.JetStreamContext, c QueueConsumer) error {
+	_, err := js.ConsumerInfo(c.Stream, c.Durable)
+	if err == nats.ErrConsumerNotFound {
+		if _, err = js.AddConsumer(c.Stream, c.config()); err != nil {
+			// Another replica may have created it in the meantime
+			if _, infoErr := js.ConsumerInfo(c.Stream, c.Durable); infoErr == nil {
+				err = nil
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to ensure consumer %s on %s: %w", c.Durable, c.Stream, err)
+	}
+	return nil
+}
+
+// QueueSubscribe binds handler to the durable consumer with manual acks.
+// The consumer is created by EnsureQueueConsumer rather than by the
+// subscription, so draining the subscription on shutdown leaves it on the
+// server for the other replicas; a consumer nats.go creates itself is
+// deleted when its subscription is drained.
+func QueueSubscribe(js nats.JetStreamContext, c QueueConsumer, handler nats.MsgHandler) (*nats.Subscription, error) {
+	if err := EnsureQueueConsumer(js, c); err != nil {
+		return nil, err
+	}
+	return js.QueueSubscribe(c.Subject, c.Queue, handler, nats.Bind(c.Stream, c.Durable), nats.ManualAck())
+}
+
+// CloseHook drains the connection on shutdown, so in-flight handlers finish
+// and pending publishes are flushed before it closes. The connection is
+// closed outright at the shutdown deadline.
+func CloseHook(nc *nats.Conn) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: "nats",
+		Stop: func(ctx context.Context) error {
+			if err := nc.Drain(); err != nil {
+				nc.Close()
+				return err
+			}
+			if err := waitFor(ctx, nc.IsClosed); err != nil {
+				nc.Close()
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// Check reports ready when the connection is up and the server answers a
+// round trip
+func Check(nc *nats.Conn) health.Check {
+	return func(ctx context.Context) error {
+		if status := nc.Status(); status != nats.CONNECTED {
+			return fmt.Errorf("connection is %s", status)
+		}
+		return nc.FlushWithContext(ctx)
+	}
+}
+
+// SubscribeHook subscribes in Start. Stop drains the subscription: no new
+// messages are delivered and it waits until the pending ones are handled,
+// so the clients the handler uses are closed after it.
+func SubscribeHook(name string, subscribe func() (*nats.Subscription, error)) lifecycle.Hook {
+	var sub *nats.Subscription
+	return lifecycle.Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			var err error
+			sub, err = subscribe()
+			return err
+		},
+		Stop: func(ctx context.Context) error {
+			if err := sub.Drain(); err != nil {
+				return err
+			}
+			return waitFor(ctx, func() bool { return !sub.IsValid() })
+		},
+	}
+}
+
+// waitFor polls done until it is true or ctx expires
+func waitFor(ctx context.Context, done func() bool) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !done() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not drained before the shutdown deadline: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// NewMsg builds a message for subject that carries the trace context of ctx
+// in its headers
+func NewMsg(ctx context.Context, subject string, data []byte) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	InjectHeaders(ctx, msg)
+	return msg
+}
+
+// InjectHeaders writes the trace context of ctx into the message headers
+func InjectHeaders(ctx context.Context, msg *nats.Msg) {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(http.Header(msg.Header)))
+}
+
+// StartClientSpan starts a span for a request or publish on subject
+func StartClientSpan(ctx context.Context, subject string) (context.Context, trace.Span) {
+	return telemetry.Tracer.Start(ctx, subject+" send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", subject),
+		),
+	)
+}
+
+// StartHandlerSpan continues the trace carried in the message headers with a
+// process span
+func StartHandlerSpan(ctx context.Context, msg *nats.Msg) (context.Context, trace.Span) {
+	if msg.Header != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(http.Header(msg.Header)))
+	}
+	return telemetry.Tracer.Start(ctx, msg.Subject+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", msg.Subject),
+		),
+	)
+}
+
+// ErrorHeader carries the reason a responder rejected a request, so the
+// requester fails at once instead of waiting for its timeout
+const ErrorHeader = "Nats-Service-Error"
+
+// ErrRejected is returned by ReplyError for replies sent by RespondError
+var ErrRejected = errors.New("request rejected")
+
+// RespondError answers a request with an empty body and the reason in
+// ErrorHeader
+func RespondError(msg *nats.Msg, reason error) error {
+	reply := nats.NewMsg(msg.Reply)
+	reply.Header.Set(ErrorHeader, reason.Error())
+	return msg.RespondMsg(reply)
+}
+
+// ReplyError returns an error wrapping ErrRejected when the reply was sent
+// by RespondError
+func ReplyError(reply *nats.Msg) error {
+	if reason := reply.Header.Get(ErrorHeader); reason != "" {
+		return fmt.Errorf("%w: %s", ErrRejected, reason)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package natsclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func runServer(t *testing.T) *server.Server {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func connect(t *testing.T, s *server.Server) (*nats.Conn, nats.JetStreamContext) {
+	t.Helper()
+	nc, js, err := Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc, js
+}
+
+func TestDrainedQueueSubscriptionKeepsConsumer(t *testing.T) {
+	s := runServer(t)
+	_, js := connect(t, s)
+
+	err := EnsureStreams(js, &nats.StreamConfig{
+		Name:      "JOBS",
+		Subjects:  []string{"jobs"},
+		Retention: nats.WorkQueuePolicy,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumer := QueueConsumer{
+		Stream:  "JOBS",
+		Durable: "workers",
+		Subject: "jobs",
+		Queue:   "workers",
+		AckWait: time.Second,
+	}
+
+	// Two replicas share the consumer; the first one shuts down
+	_, jsA := connect(t, s)
+	first := SubscribeHook("jobs", func() (*nats.Subscription, error) {
+		return QueueSubscribe(jsA, consumer, func(msg *nats.Msg) { msg.Ack() })
+	})
+	if err := first.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, jsB := connect(t, s)
+	received := make(chan string, 1)
+	second := SubscribeHook("jobs", func() (*nats.Subscription, error) {
+		return QueueSubscribe(jsB, consumer, func(msg *nats.Msg) {
+			msg.Ack()
+			received <- string(msg.Data)
+		})
+	})
+	if err := second.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := first.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := js.ConsumerInfo("JOBS", "workers"); err != nil {
+		t.Fatalf("consumer gone after one replica drained: %v", err)
+	}
+
+	if _, err := js.Publish("jobs", []byte("job-1")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-received:
+		if data != "job-1" {
+			t.Fatalf("received %q, want job-1", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("remaining replica received nothing after the other drained")
+	}
+
+	if err := second.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.ConsumerInfo("JOBS", "workers"); err != nil {
+		t.Fatalf("consumer gone after every replica drained: %v", err)
+	}
+}
+
+func TestEnsureQueueConsumerIsIdempotent(t *testing.T) {
+	s := runServer(t)
+	_, js := connect(t, s)
+
+	if err := EnsureStreams(js, &nats.StreamConfig{Name: "RESULTS", Subjects: []string{"results"}}); err != nil {
+		t.Fatal(err)
+	}
+	consumer := QueueConsumer{Stream: "RESULTS", Durable: "readers", Subject: "results", Queue: "readers", AckWait: time.Second}
+	for i := 0; i < 2; i++ {
+		if err := EnsureQueueConsumer(js, consumer); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+
+	info, err := js.ConsumerInfo("RESULTS", "readers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Config.DeliverGroup != "readers" || info.Config.AckPolicy != nats.AckExplicitPolicy {
+		t.Fatalf("consumer config = %+v", info.Config)
+	}
+}
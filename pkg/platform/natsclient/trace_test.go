@@ -0,0 +1,122 @@
+package natsclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
+)
+
+func useTestTracer() {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	telemetry.Tracer = sdktrace.NewTracerProvider().Tracer("natsclient-test")
+}
+
+func TestHandlerSpanContinuesTheTrace(t *testing.T) {
+	useTestTracer()
+	s := runServer(t)
+	nc, _ := connect(t, s)
+
+	parents := make(chan sdktrace.ReadOnlySpan, 1)
+	_, err := nc.Subscribe("fraud.check", func(msg *nats.Msg) {
+		_, span := StartHandlerSpan(context.Background(), msg)
+		span.End()
+		parents <- span.(sdktrace.ReadOnlySpan)
+		msg.Respond(nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, client := StartClientSpan(context.Background(), "fraud.check")
+	defer client.End()
+	if _, err := nc.RequestMsg(NewMsg(ctx, "fraud.check", []byte("{}")), time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := <-parents
+	if got := handler.Parent(); got.TraceID() != client.SpanContext().TraceID() || got.SpanID() != client.SpanContext().SpanID() {
+		t.Fatalf("handler span parent = %s/%s, want the client span %s/%s",
+			got.TraceID(), got.SpanID(), client.SpanContext().TraceID(), client.SpanContext().SpanID())
+	}
+	if handler.Name() != "fraud.check process" {
+		t.Fatalf("handler span name = %q", handler.Name())
+	}
+
+	// Messages from publishers without tracing start a new trace
+	_, orphan := StartHandlerSpan(context.Background(), &nats.Msg{Subject: "fraud.check"})
+	orphan.End()
+	if orphan.(sdktrace.ReadOnlySpan).Parent().IsValid() {
+		t.Fatal("span of a message without headers has a parent")
+	}
+}
+
+// traceparent reads the header the way the propagator wrote it, in its
+// canonical form
+func traceparent(msg *nats.Msg) string {
+	return http.Header(msg.Header).Get("traceparent")
+}
+
+func TestNewMsg(t *testing.T) {
+	useTestTracer()
+
+	// Without a span there is no trace context to carry
+	msg := NewMsg(context.Background(), "fraud.check", []byte("{}"))
+	if msg.Subject != "fraud.check" || string(msg.Data) != "{}" || traceparent(msg) != "" {
+		t.Fatalf("NewMsg = %+v", msg)
+	}
+
+	ctx, span := StartClientSpan(context.Background(), "fraud.check")
+	defer span.End()
+	if traceparent(NewMsg(ctx, "fraud.check", nil)) == "" {
+		t.Fatal("NewMsg did not carry the trace context")
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	s := runServer(t)
+	nc, _ := connect(t, s)
+
+	_, err := nc.Subscribe("fraud.check", func(msg *nats.Msg) {
+		if string(msg.Data) == "invalid" {
+			RespondError(msg, errors.New("missing payment_id"))
+			return
+		}
+		msg.Respond([]byte("ok"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		data    string
+		wantErr string
+	}{
+		{"valid", ""},
+		{"invalid", "request rejected: missing payment_id"},
+	}
+	for _, tt := range tests {
+		reply, err := nc.Request("fraud.check", []byte(tt.data), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ReplyError(reply)
+		if tt.wantErr == "" {
+			if err != nil || string(reply.Data) != "ok" {
+				t.Fatalf("%s: reply %q, error %v", tt.data, reply.Data, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrRejected) || err.Error() != tt.wantErr {
+			t.Fatalf("%s: ReplyError = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package postgres opens the PostgreSQL connection pool of a service
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/lib/pq"
+
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+)
+
+// Open returns a connection pool for url. Like sql.Open it does not
+// connect yet.
+func Open(url string) (*sql.DB, error) {
+	return sql.Open("postgres", url)
+}
+
+// CloseHook closes the pool on shutdown
+func CloseHook(db *sql.DB) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: "postgres",
+		Stop: func(context.Context) error { return db.Close() },
+	}
+}
+
+// Check pings the database for readiness
+func Check(db *sql.DB) health.Check {
+	return db.PingContext
+}
@@ -0,0 +1,30 @@
+// Package redisclient creates the Redis client of a service
+package redisclient
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+)
+
+func New(addr string) *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: addr})
+}
+
+// CloseHook closes the client on shutdown
+func CloseHook(client *redis.Client) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: "redis",
+		Stop: func(context.Context) error { return client.Close() },
+	}
+}
+
+// Check pings Redis for readiness
+func Check(client *redis.Client) health.Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
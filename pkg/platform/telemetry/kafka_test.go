@@ -0,0 +1,64 @@
+package telemetry
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestKafkaHeaderCarrier(t *testing.T) {
+	headers := []kafka.Header{{Key: "x-original-topic", Value: []byte("payment.created")}}
+	carrier := KafkaHeaderCarrier{Headers: &headers}
+
+	carrier.Set("traceparent", "00-first-01")
+	// Setting a key again replaces it instead of adding a second header
+	carrier.Set("traceparent", "00-second-01")
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"traceparent", "00-second-01"},
+		{"x-original-topic", "payment.created"},
+		{"tracestate", ""},
+	}
+	for _, tt := range tests {
+		if got := carrier.Get(tt.key); got != tt.want {
+			t.Fatalf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+	if keys := carrier.Keys(); !reflect.DeepEqual(keys, []string{"x-original-topic", "traceparent"}) {
+		t.Fatalf("Keys = %v", keys)
+	}
+}
+
+func TestKafkaSpansContinueTheTrace(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	Tracer = sdktrace.NewTracerProvider().Tracer("telemetry-test")
+
+	msg := kafka.Message{Key: []byte("p1")}
+	_, producer := StartKafkaProducerSpan(context.Background(), "payment.created", &msg)
+	producer.End()
+
+	msg.Topic = "payment.created"
+	_, consumer := StartKafkaConsumerSpan(context.Background(), msg)
+	consumer.End()
+
+	parent := consumer.(sdktrace.ReadOnlySpan).Parent()
+	if parent.TraceID() != producer.SpanContext().TraceID() || parent.SpanID() != producer.SpanContext().SpanID() {
+		t.Fatalf("consumer span parent = %s/%s, want the producer span %s/%s",
+			parent.TraceID(), parent.SpanID(), producer.SpanContext().TraceID(), producer.SpanContext().SpanID())
+	}
+
+	// A message without trace headers starts a new trace
+	_, orphan := StartKafkaConsumerSpan(context.Background(), kafka.Message{Topic: "payment.created"})
+	orphan.End()
+	if orphan.(sdktrace.ReadOnlySpan).Parent().IsValid() {
+		t.Fatal("span of a message without trace headers has a parent")
+	}
+}
@@ -1,3 +1,4 @@
+// Package telemetry sets up tracing and structured logging for a service
 package telemetry
 
 import (
@@ -2,23 +2,25 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
-	_ "github.com/lib/pq"
-	"github.com/redis/go-redis/v9"
-	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 
 	"github.com/akylbek/payment-system/api-gateway/internal/api"
 	"github.com/akylbek/payment-system/api-gateway/internal/config"
 	"github.com/akylbek/payment-system/api-gateway/internal/repository"
-	"github.com/akylbek/payment-system/api-gateway/internal/telemetry"
+	"github.com/akylbek/payment-system/api-gateway/internal/validation"
+	"github.com/akylbek/payment-system/api-gateway/migrations"
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/httpserver"
+	"github.com/akylbek/payment-system/pkg/platform/kafkaclient"
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+	"github.com/akylbek/payment-system/pkg/platform/migrate"
+	"github.com/akylbek/payment-system/pkg/platform/postgres"
+	"github.com/akylbek/payment-system/pkg/platform/redisclient"
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
 )
 
 func main() {
@@ -34,59 +36,69 @@ func main() {
 	telemetry.Logger.Info("Starting API Gateway")
 
 	// Connect to PostgreSQL
-	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	db, err := postgres.Open(cfg.DatabaseURL)
 	if err != nil {
 		telemetry.Logger.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer db.Close()
 
-	// Initialize repository
-	paymentRepo := repository.NewPaymentRepository(db)
-	if err := paymentRepo.InitDB(); err != nil {
-		telemetry.Logger.Fatal("Failed to initialize database", zap.Error(err))
+	migrator, err := migrate.New(db, migrations.FS, telemetry.Logger)
+	if err != nil {
+		telemetry.Logger.Fatal("Failed to load migrations", zap.Error(err))
 	}
 
-	// Connect to Redis
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: cfg.RedisURL,
-	})
+	// Inspect, apply or roll back migrations instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.RunCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			telemetry.Logger.Fatal("migrate command failed", zap.Error(err))
+		}
+		return
+	}
 
-	// Connect to Kafka
-	kafkaWriter := &kafka.Writer{
-		Addr:     kafka.TCP(cfg.KafkaBrokers),
-		Topic:    "payment.created",
-		Balancer: &kafka.LeastBytes{},
+	if err := migrator.Up(context.Background()); err != nil {
+		telemetry.Logger.Fatal("Failed to migrate database", zap.Error(err))
 	}
-	defer kafkaWriter.Close()
 
-	// Setup router with all routes
-	router := api.NewRouter(paymentRepo, redisClient, kafkaWriter)
+	// Initialize repositories
+	paymentRepo := repository.NewPaymentRepository(db)
+	merchantRepo := repository.NewMerchantRepository(db)
 
-	// Setup HTTP server
-	srv := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: router,
+	currencies, err := validation.ParseCurrencies(cfg.SupportedCurrencies, cfg.CurrencyLimits)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid SUPPORTED_CURRENCIES or CURRENCY_LIMITS", zap.Error(err))
 	}
+	validator := validation.New(currencies, merchantRepo)
 
-	// Start server in goroutine
-	go func() {
-		telemetry.Logger.Info("API Gateway starting", zap.String("port", cfg.Port))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			telemetry.Logger.Fatal("Failed to start server", zap.Error(err))
-		}
-	}()
+	redisClient := redisclient.New(cfg.RedisURL)
+	brokers := kafkaclient.Brokers(cfg.KafkaBrokers)
+	kafkaWriter := kafkaclient.NewWriter(brokers, "payment.created")
+
+	checker := health.New("api-gateway", 2*time.Second)
+	checker.Add("postgres", postgres.Check(db))
+	checker.Add("redis", redisclient.Check(redisClient))
+	checker.Add("kafka", kafkaclient.Check(brokers))
 
-	// Wait for interrupt signal for graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// Setup router with all routes
+	router := api.NewRouter(checker, paymentRepo, validator, redisClient, kafkaWriter)
 
-	telemetry.Logger.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	shutdownTimeout, err := time.ParseDuration(cfg.ShutdownTimeout)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid SHUTDOWN_TIMEOUT", zap.Error(err))
+	}
+	drainDelay, err := time.ParseDuration(cfg.DrainDelay)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid DRAIN_DELAY", zap.Error(err))
+	}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		telemetry.Logger.Error("Server forced to shutdown", zap.Error(err))
+	app := lifecycle.New(telemetry.Logger, shutdownTimeout)
+	app.Append(
+		postgres.CloseHook(db),
+		redisclient.CloseHook(redisClient),
+		kafkaclient.CloseHook(kafkaWriter),
+		httpserver.Hook(cfg.Port, router),
+		checker.Hook(drainDelay),
+	)
+	if err := app.Run(context.Background()); err != nil {
+		telemetry.Logger.Fatal("API Gateway stopped with errors", zap.Error(err))
 	}
 
 	telemetry.Logger.Info("Server exited")
@@ -1,35 +1,23 @@
 package api
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"github.com/segmentio/kafka-go"
 
 	"github.com/akylbek/payment-system/api-gateway/internal/handlers"
 	"github.com/akylbek/payment-system/api-gateway/internal/interfaces"
 	"github.com/akylbek/payment-system/api-gateway/internal/middleware"
-	"github.com/akylbek/payment-system/api-gateway/internal/telemetry"
+	"github.com/akylbek/payment-system/api-gateway/internal/validation"
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/httpserver"
 )
 
-func NewRouter(paymentRepo interfaces.PaymentRepository, redisClient *redis.Client, kafkaWriter *kafka.Writer) *gin.Engine {
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(telemetry.TracingMiddleware())
-
-	// Prometheus metrics
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
-
-	// Health check
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "api-gateway"})
-	})
+func NewRouter(checker *health.Checker, paymentRepo interfaces.PaymentRepository, validator *validation.Validator, redisClient *redis.Client, kafkaWriter *kafka.Writer) *gin.Engine {
+	r := httpserver.NewRouter(checker)
 
 	// Payment routes
-	paymentHandler := handlers.NewPaymentHandler(paymentRepo, redisClient, kafkaWriter)
+	paymentHandler := handlers.NewPaymentHandler(paymentRepo, validator, redisClient, kafkaWriter)
 	payments := r.Group("/payments")
 	{
 		payments.POST("", middleware.IdempotencyMiddleware(redisClient, paymentRepo), paymentHandler.CreatePayment)
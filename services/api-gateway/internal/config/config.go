@@ -8,6 +8,17 @@ type Config struct {
 	KafkaBrokers   string
 	JaegerEndpoint string
 	Port           string
+
+	// ShutdownTimeout bounds draining in-flight work on SIGTERM
+	ShutdownTimeout string
+	// DrainDelay keeps serving after readiness turns false on SIGTERM, so
+	// load balancers stop routing before the listener closes
+	DrainDelay string
+
+	// SupportedCurrencies lists the ISO 4217 codes payments may use, and
+	// CurrencyLimits their amount bounds, see validation.ParseCurrencies
+	SupportedCurrencies string
+	CurrencyLimits      string
 }
 
 func Load() *Config {
@@ -22,5 +33,18 @@ func Load() *Config {
 		KafkaBrokers:   os.Getenv("KAFKA_BROKERS"),
 		JaegerEndpoint: os.Getenv("JAEGER_ENDPOINT"),
 		Port:           port,
+
+		ShutdownTimeout: getEnv("SHUTDOWN_TIMEOUT", "10s"),
+		DrainDelay:      getEnv("DRAIN_DELAY", "0s"),
+
+		SupportedCurrencies: getEnv("SUPPORTED_CURRENCIES", "USD,EUR,GBP,KZT,KGS,RUB,JPY,KWD"),
+		CurrencyLimits:      os.Getenv("CURRENCY_LIMITS"),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
 }
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	paymentsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payments_created_total",
+		Help: "Payments accepted by the gateway, by currency and merchant",
+	}, []string{"currency", "merchant_id"})
+
+	paymentAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payments_created_amount_total",
+		Help: "Sum of accepted payment amounts in major units, by currency",
+	}, []string{"currency"})
+
+	paymentsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payments_rejected_total",
+		Help: "Payment requests rejected by validation, by field and error code",
+	}, []string{"field", "code"})
+)
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/akylbek/payment-system/api-gateway/internal/validation"
+)
+
+func TestRejectPaymentCountsEveryField(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	invalid := &validation.Error{Fields: []validation.FieldError{
+		{Field: "amount", Code: validation.CodeAboveMaximum, Message: "amount must be at most 10000.00 USD"},
+		{Field: "customer_id", Code: validation.CodeRequired, Message: "customer_id is required"},
+	}}
+
+	counters := map[string]float64{}
+	for _, f := range invalid.Fields {
+		counters[f.Field] = testutil.ToFloat64(paymentsRejected.WithLabelValues(f.Field, f.Code))
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	rejectPayment(c, invalid)
+
+	for _, f := range invalid.Fields {
+		if got := testutil.ToFloat64(paymentsRejected.WithLabelValues(f.Field, f.Code)) - counters[f.Field]; got != 1 {
+			t.Fatalf("payments_rejected_total{field=%q, code=%q} grew by %v, want 1", f.Field, f.Code, got)
+		}
+	}
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	var body struct {
+		Fields []validation.FieldError `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Fields) != 2 {
+		t.Fatalf("body = %s, want both field errors", w.Body)
+	}
+}
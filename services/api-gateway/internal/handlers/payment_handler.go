@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -16,18 +17,21 @@ import (
 
 	"github.com/akylbek/payment-system/api-gateway/internal/interfaces"
 	"github.com/akylbek/payment-system/api-gateway/internal/models"
-	"github.com/akylbek/payment-system/api-gateway/internal/telemetry"
+	"github.com/akylbek/payment-system/api-gateway/internal/validation"
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
 )
 
 type PaymentHandler struct {
 	repo        interfaces.PaymentRepository
+	validator   *validation.Validator
 	redisClient *redis.Client
 	kafkaWriter *kafka.Writer
 }
 
-func NewPaymentHandler(repo interfaces.PaymentRepository, redisClient *redis.Client, kafkaWriter *kafka.Writer) *PaymentHandler {
+func NewPaymentHandler(repo interfaces.PaymentRepository, validator *validation.Validator, redisClient *redis.Client, kafkaWriter *kafka.Writer) *PaymentHandler {
 	return &PaymentHandler{
 		repo:        repo,
+		validator:   validator,
 		redisClient: redisClient,
 		kafkaWriter: kafkaWriter,
 	}
@@ -40,27 +44,50 @@ func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 	var req models.CreatePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		telemetry.Logger.Warn("Invalid payment request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		rejectPayment(c, validation.FromBindError(err))
+		return
+	}
+
+	amount, err := h.validator.ValidatePayment(ctx, &req)
+	var invalid *validation.Error
+	if errors.As(err, &invalid) {
+		telemetry.Logger.Warn("Invalid payment request",
+			zap.String("merchant_id", req.MerchantID),
+			zap.Error(err),
+		)
+		rejectPayment(c, invalid)
+		return
+	}
+	if err != nil {
+		telemetry.Logger.Error("Failed to validate payment request", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate payment"})
 		return
 	}
 
 	idempotencyKey := c.GetString("idempotency_key")
 
 	payment := models.Payment{
-		ID:             uuid.New().String(),
-		Amount:         req.Amount,
-		Currency:       req.Currency,
-		CustomerID:     req.CustomerID,
-		MerchantID:     req.MerchantID,
-		Status:         "NEW",
-		IdempotencyKey: idempotencyKey,
-		CreatedAt:      time.Now(),
+		ID:              uuid.New().String(),
+		Amount:          amount.Decimal(),
+		Money:           amount,
+		CustomerID:      req.CustomerID,
+		MerchantID:      req.MerchantID,
+		CustomerEmail:   req.CustomerEmail,
+		CustomerCountry: req.CustomerCountry,
+		PaymentMethod:   req.PaymentMethod,
+		CardBIN:         req.CardBIN,
+		IPAddress:       c.ClientIP(),
+		DeviceID:        req.DeviceID,
+		Status:          "NEW",
+		IdempotencyKey:  idempotencyKey,
+		CreatedAt:       time.Now(),
 	}
 
 	telemetry.Logger.Info("Creating payment",
 		zap.String("payment_id", payment.ID),
 		zap.String("customer_id", payment.CustomerID),
-		zap.Float64("amount", payment.Amount),
+		zap.Stringer("amount", payment.Money),
+		zap.String("currency", payment.Currency),
 		zap.String("trace_id", span.SpanContext().TraceID().String()),
 	)
 
@@ -74,31 +101,42 @@ func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 		return
 	}
 
+	paymentsCreated.WithLabelValues(payment.Currency, payment.MerchantID).Inc()
+	paymentAmount.WithLabelValues(payment.Currency).Add(payment.Float64())
+
 	// Cache in Redis
 	paymentJSON, _ := json.Marshal(payment)
 	h.redisClient.Set(ctx, fmt.Sprintf("idempotency:%s", idempotencyKey), paymentJSON, 24*time.Hour)
 
 	// Publish to Kafka
 	event := map[string]interface{}{
-		"payment_id":  payment.ID,
-		"amount":      payment.Amount,
-		"currency":    payment.Currency,
-		"customer_id": payment.CustomerID,
-		"merchant_id": payment.MerchantID,
-		"status":      payment.Status,
-		"created_at":  payment.CreatedAt,
+		"payment_id":       payment.ID,
+		"amount":           payment.Amount,
+		"amount_minor":     payment.Minor,
+		"currency":         payment.Currency,
+		"customer_id":      payment.CustomerID,
+		"merchant_id":      payment.MerchantID,
+		"customer_email":   payment.CustomerEmail,
+		"customer_country": payment.CustomerCountry,
+		"payment_method":   payment.PaymentMethod,
+		"card_bin":         payment.CardBIN,
+		"ip_address":       payment.IPAddress,
+		"device_id":        payment.DeviceID,
+		"status":           payment.Status,
+		"created_at":       payment.CreatedAt,
 	}
 	eventJSON, _ := json.Marshal(event)
 
-	if err := h.kafkaWriter.WriteMessages(ctx, kafka.Message{
-		Key:   []byte(payment.ID),
-		Value: eventJSON,
-	}); err != nil {
+	msg := kafka.Message{Key: []byte(payment.ID), Value: eventJSON}
+	publishCtx, publishSpan := telemetry.StartKafkaProducerSpan(ctx, h.kafkaWriter.Topic, &msg)
+	if err := h.kafkaWriter.WriteMessages(publishCtx, msg); err != nil {
+		publishSpan.RecordError(err)
 		telemetry.Logger.Error("Failed to publish payment event to Kafka",
 			zap.String("payment_id", payment.ID),
 			zap.Error(err),
 		)
 	}
+	publishSpan.End()
 
 	telemetry.Logger.Info("Payment created successfully",
 		zap.String("payment_id", payment.ID),
@@ -107,6 +145,14 @@ func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 	c.JSON(http.StatusCreated, payment)
 }
 
+// rejectPayment answers with the field errors of an invalid request
+func rejectPayment(c *gin.Context, invalid *validation.Error) {
+	for _, field := range invalid.Fields {
+		paymentsRejected.WithLabelValues(field.Field, field.Code).Inc()
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment request", "fields": invalid.Fields})
+}
+
 func (h *PaymentHandler) GetPayment(c *gin.Context) {
 	id := c.Param("id")
 
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/akylbek/payment-system/api-gateway/internal/models"
+)
+
+// MerchantRepository defines the contract for merchant data access
+type MerchantRepository interface {
+	GetByID(ctx context.Context, id string) (*models.Merchant, error)
+	// GetAmountLimit returns the merchant's bounds for currency, or nil if
+	// the merchant has none
+	GetAmountLimit(ctx context.Context, merchantID, currency string) (*models.AmountLimit, error)
+}
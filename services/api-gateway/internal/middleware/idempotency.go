@@ -28,6 +28,10 @@ func IdempotencyMiddleware(redisClient *redis.Client, paymentRepo interfaces.Pay
 		if err == nil {
 			var payment models.Payment
 			if err := json.Unmarshal([]byte(cached), &payment); err == nil {
+				// Entries cached before amount_minor was added
+				if payment.Minor == 0 && payment.Amount != "" {
+					payment.Money, _ = payment.Amount.Money(payment.Currency)
+				}
 				c.JSON(http.StatusOK, payment)
 				c.Abort()
 				return
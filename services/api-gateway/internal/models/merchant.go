@@ -0,0 +1,17 @@
+package models
+
+// MerchantStatusActive is the only merchant status that accepts payments
+const MerchantStatusActive = "active"
+
+type Merchant struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// AmountLimit bounds payment amounts in minor units of one currency. A nil
+// bound is not checked.
+type AmountLimit struct {
+	Min *int64
+	Max *int64
+}
@@ -1,21 +1,43 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
 
 type Payment struct {
-	ID             string    `json:"id"`
-	Amount         float64   `json:"amount"`
-	Currency       string    `json:"currency"`
-	CustomerID     string    `json:"customer_id"`
-	MerchantID     string    `json:"merchant_id"`
-	Status         string    `json:"status"`
-	IdempotencyKey string    `json:"idempotency_key"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID string `json:"id"`
+	// Amount repeats Money in major units for clients reading "amount";
+	// Money adds amount_minor and currency
+	Amount money.Decimal `json:"amount"`
+	money.Money
+	CustomerID      string    `json:"customer_id"`
+	MerchantID      string    `json:"merchant_id"`
+	CustomerEmail   string    `json:"customer_email,omitempty"`
+	CustomerCountry string    `json:"customer_country,omitempty"`
+	PaymentMethod   string    `json:"payment_method,omitempty"`
+	CardBIN         string    `json:"card_bin,omitempty"`
+	IPAddress       string    `json:"ip_address,omitempty"`
+	DeviceID        string    `json:"device_id,omitempty"`
+	Status          string    `json:"status"`
+	IdempotencyKey  string    `json:"idempotency_key"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
+// CreatePaymentRequest carries optional customer and instrument attributes
+// that the fraud service checks against its blocklists and allowlists.
+// Required fields and formats are checked by the validation package.
 type CreatePaymentRequest struct {
-	Amount     float64 `json:"amount" binding:"required"`
-	Currency   string  `json:"currency" binding:"required"`
-	CustomerID string  `json:"customer_id" binding:"required"`
-	MerchantID string  `json:"merchant_id" binding:"required"`
+	// Amount is in major units, as a JSON number or string, e.g. 100.10
+	// or "100.10"
+	Amount          money.Decimal `json:"amount"`
+	Currency        string        `json:"currency"`
+	CustomerID      string        `json:"customer_id"`
+	MerchantID      string        `json:"merchant_id"`
+	CustomerEmail   string        `json:"customer_email"`
+	CustomerCountry string        `json:"customer_country"`
+	PaymentMethod   string        `json:"payment_method"`
+	CardBIN         string        `json:"card_bin"`
+	DeviceID        string        `json:"device_id"`
 }
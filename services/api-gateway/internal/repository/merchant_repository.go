@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/akylbek/payment-system/api-gateway/internal/models"
+)
+
+type MerchantRepository struct {
+	db *sql.DB
+}
+
+func NewMerchantRepository(db *sql.DB) *MerchantRepository {
+	return &MerchantRepository{db: db}
+}
+
+func (r *MerchantRepository) GetByID(ctx context.Context, id string) (*models.Merchant, error) {
+	var merchant models.Merchant
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, COALESCE(status, '')
+		FROM merchants WHERE id = $1
+	`, id).Scan(&merchant.ID, &merchant.Name, &merchant.Status)
+	if err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+func (r *MerchantRepository) GetAmountLimit(ctx context.Context, merchantID, currency string) (*models.AmountLimit, error) {
+	var minAmount, maxAmount sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT min_amount_minor, max_amount_minor
+		FROM merchant_amount_limits WHERE merchant_id = $1 AND currency = $2
+	`, merchantID, currency).Scan(&minAmount, &maxAmount)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var limit models.AmountLimit
+	if minAmount.Valid {
+		limit.Min = &minAmount.Int64
+	}
+	if maxAmount.Valid {
+		limit.Max = &maxAmount.Int64
+	}
+	return &limit, nil
+}
@@ -15,64 +15,51 @@ func NewPaymentRepository(db *sql.DB) *PaymentRepository {
 	return &PaymentRepository{db: db}
 }
 
-func (r *PaymentRepository) InitDB() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS payments (
-			id VARCHAR(255) PRIMARY KEY,
-			amount DECIMAL(15,2) NOT NULL,
-			currency VARCHAR(3) NOT NULL,
-			customer_id VARCHAR(255) NOT NULL,
-			merchant_id VARCHAR(255) NOT NULL,
-			status VARCHAR(50) NOT NULL,
-			idempotency_key VARCHAR(255) UNIQUE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_payments_customer_id ON payments(customer_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_payments_idempotency_key ON payments(idempotency_key)`,
-	}
-
-	for _, query := range queries {
-		if _, err := r.db.Exec(query); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 func (r *PaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO payments (id, amount, currency, customer_id, merchant_id, status, idempotency_key)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, payment.ID, payment.Amount, payment.Currency, payment.CustomerID,
-		payment.MerchantID, payment.Status, payment.IdempotencyKey)
+		INSERT INTO payments (id, amount, amount_minor, currency, customer_id, merchant_id, status, idempotency_key,
+			customer_email, customer_country, payment_method, card_bin, ip_address, device_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''))
+	`, payment.ID, payment.Money.String(), payment.Minor, payment.Currency, payment.CustomerID,
+		payment.MerchantID, payment.Status, payment.IdempotencyKey,
+		payment.CustomerEmail, payment.CustomerCountry, payment.PaymentMethod,
+		payment.CardBIN, payment.IPAddress, payment.DeviceID)
 	return err
 }
 
 func (r *PaymentRepository) GetByID(ctx context.Context, id string) (*models.Payment, error) {
 	var payment models.Payment
 	err := r.db.QueryRowContext(ctx, `
-		SELECT id, amount, currency, customer_id, merchant_id, status, idempotency_key, created_at
+		SELECT id, amount_minor, currency, customer_id, merchant_id, status, idempotency_key, created_at,
+			COALESCE(customer_email, ''), COALESCE(customer_country, ''), COALESCE(payment_method, ''),
+			COALESCE(card_bin, ''), COALESCE(ip_address, ''), COALESCE(device_id, '')
 		FROM payments WHERE id = $1
-	`, id).Scan(&payment.ID, &payment.Amount, &payment.Currency, &payment.CustomerID,
-		&payment.MerchantID, &payment.Status, &payment.IdempotencyKey, &payment.CreatedAt)
+	`, id).Scan(&payment.ID, &payment.Minor, &payment.Currency, &payment.CustomerID,
+		&payment.MerchantID, &payment.Status, &payment.IdempotencyKey, &payment.CreatedAt,
+		&payment.CustomerEmail, &payment.CustomerCountry, &payment.PaymentMethod,
+		&payment.CardBIN, &payment.IPAddress, &payment.DeviceID)
 	if err != nil {
 		return nil, err
 	}
+	payment.Amount = payment.Money.Decimal()
 	return &payment, nil
 }
 
 func (r *PaymentRepository) GetByIdempotencyKey(ctx context.Context, key string) (*models.Payment, error) {
 	var payment models.Payment
 	err := r.db.QueryRowContext(ctx, `
-		SELECT id, amount, currency, customer_id, merchant_id, status, idempotency_key, created_at
+		SELECT id, amount_minor, currency, customer_id, merchant_id, status, idempotency_key, created_at,
+			COALESCE(customer_email, ''), COALESCE(customer_country, ''), COALESCE(payment_method, ''),
+			COALESCE(card_bin, ''), COALESCE(ip_address, ''), COALESCE(device_id, '')
 		FROM payments WHERE idempotency_key = $1
-	`, key).Scan(&payment.ID, &payment.Amount, &payment.Currency, &payment.CustomerID,
-		&payment.MerchantID, &payment.Status, &payment.IdempotencyKey, &payment.CreatedAt)
+	`, key).Scan(&payment.ID, &payment.Minor, &payment.Currency, &payment.CustomerID,
+		&payment.MerchantID, &payment.Status, &payment.IdempotencyKey, &payment.CreatedAt,
+		&payment.CustomerEmail, &payment.CustomerCountry, &payment.PaymentMethod,
+		&payment.CardBIN, &payment.IPAddress, &payment.DeviceID)
 	if err != nil {
 		return nil, err
 	}
+	payment.Amount = payment.Money.Decimal()
 	return &payment, nil
 }
 
@@ -0,0 +1,68 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/akylbek/payment-system/api-gateway/internal/models"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// Currencies maps the currencies the gateway accepts to their amount
+// bounds in minor units
+type Currencies map[string]models.AmountLimit
+
+// ParseCurrencies parses a comma separated list of ISO 4217 codes, e.g.
+// "USD,EUR,JPY", and a comma separated list of "<currency>:<min>:<max>"
+// bounds in major units, e.g. "USD:0.50:100000,JPY:50:". An empty min or
+// max leaves that side unbounded.
+func ParseCurrencies(supported, limits string) (Currencies, error) {
+	currencies := make(Currencies)
+	for _, code := range splitList(supported) {
+		if _, err := money.Exponent(code); err != nil {
+			return nil, err
+		}
+		currencies[code] = models.AmountLimit{}
+	}
+	if len(currencies) == 0 {
+		return nil, fmt.Errorf("no supported currencies")
+	}
+
+	for _, part := range splitList(limits) {
+		fields := strings.Split(part, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid currency limit %q", part)
+		}
+		code := fields[0]
+		if _, ok := currencies[code]; !ok {
+			return nil, fmt.Errorf("currency limit %q for unsupported currency", part)
+		}
+
+		var limit models.AmountLimit
+		for i, bound := range []**int64{&limit.Min, &limit.Max} {
+			if fields[i+1] == "" {
+				continue
+			}
+			amount, err := money.Parse(fields[i+1], code)
+			if err != nil {
+				return nil, fmt.Errorf("invalid currency limit %q: %w", part, err)
+			}
+			*bound = &amount.Minor
+		}
+		if limit.Min != nil && limit.Max != nil && *limit.Min > *limit.Max {
+			return nil, fmt.Errorf("invalid currency limit %q: min above max", part)
+		}
+		currencies[code] = limit
+	}
+	return currencies, nil
+}
+
+func splitList(spec string) []string {
+	var parts []string
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
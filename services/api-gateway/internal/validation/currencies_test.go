@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/akylbek/payment-system/api-gateway/internal/models"
+)
+
+func TestParseCurrencies(t *testing.T) {
+	got, err := ParseCurrencies(" USD, JPY ,KWD,", "USD:0.50:100000, JPY:50:,KWD::1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Currencies{
+		"USD": {Min: bound(50), Max: bound(10000000)},
+		"JPY": {Min: bound(50)},
+		"KWD": {Max: bound(1500)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseCurrencies = %v, want %v", got, want)
+	}
+
+	// Currencies without limits are accepted at any positive amount
+	got, err = ParseCurrencies("EUR", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Currencies{"EUR": models.AmountLimit{}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseCurrencies = %v, want %v", got, want)
+	}
+}
+
+func TestParseCurrenciesRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		supported string
+		limits    string
+	}{
+		{"no currencies", " , ", ""},
+		{"unknown currency", "USD,XYZ", ""},
+		{"limit without bounds", "USD", "USD:0.50"},
+		{"limit for unsupported currency", "USD", "EUR:1:100"},
+		{"invalid bound", "USD", "USD:one:100"},
+		{"too precise bound", "JPY", "JPY:0.5:"},
+		{"min above max", "USD", "USD:100:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCurrencies(tt.supported, tt.limits); err == nil {
+				t.Fatalf("ParseCurrencies(%q, %q) succeeded", tt.supported, tt.limits)
+			}
+		})
+	}
+}
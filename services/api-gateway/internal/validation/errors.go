@@ -0,0 +1,68 @@
+// Package validation checks payment requests before the gateway accepts
+// them and reports every problem found as a field error
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// Error codes returned to clients in FieldError.Code
+const (
+	CodeRequired            = "required"
+	CodeInvalid             = "invalid"
+	CodeMalformed           = "malformed"
+	CodeInvalidPrecision    = "invalid_precision"
+	CodeNotPositive         = "not_positive"
+	CodeBelowMinimum        = "below_minimum"
+	CodeAboveMaximum        = "above_maximum"
+	CodeUnknownCurrency     = "unknown_currency"
+	CodeUnsupportedCurrency = "unsupported_currency"
+	CodeNotFound            = "not_found"
+	CodeInactive            = "inactive"
+)
+
+// FieldError describes one invalid field of the request body. Field is
+// the JSON name, or "body" when the body could not be read at all.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error lists every invalid field of a request
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
+
+func (e *Error) add(field, code, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+// FromBindError turns a request body that failed to decode into field
+// errors
+func FromBindError(err error) *Error {
+	var typeErr *json.UnmarshalTypeError
+	var result Error
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		result.add(typeErr.Field, CodeInvalid, fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type))
+	case errors.Is(err, money.ErrInvalidAmount):
+		result.add("amount", CodeInvalid, `amount must be a decimal number, e.g. 100.10 or "100.10"`)
+	default:
+		result.add("body", CodeMalformed, "request body must be a JSON object")
+	}
+	return &result
+}
@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/akylbek/payment-system/api-gateway/internal/models"
+)
+
+func TestFromBindError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want FieldError
+	}{
+		{"wrong field type", `{"customer_id": 42}`, FieldError{"customer_id", CodeInvalid, "customer_id must be a string"}},
+		{"invalid amount", `{"amount": "ten"}`, FieldError{"amount", CodeInvalid, `amount must be a decimal number, e.g. 100.10 or "100.10"`}},
+		{"not an object", `[1, 2]`, FieldError{"body", CodeMalformed, "request body must be a JSON object"}},
+		{"truncated", `{"amount": 10`, FieldError{"body", CodeMalformed, "request body must be a JSON object"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.CreatePaymentRequest
+			err := json.Unmarshal([]byte(tt.body), &req)
+			if err == nil {
+				t.Fatalf("decoding %s succeeded", tt.body)
+			}
+			if got := FromBindError(err).Fields; !reflect.DeepEqual(got, []FieldError{tt.want}) {
+				t.Fatalf("FromBindError = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := FromBindError(errors.New("EOF")).Fields[0].Field; got != "body" {
+		t.Fatalf("FromBindError of an empty body reports %q, want body", got)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	var err Error
+	err.add("currency", CodeRequired, "currency is required")
+	err.add("amount", CodeNotPositive, "amount must be greater than zero")
+
+	want := "invalid request: currency: currency is required; amount: amount must be greater than zero"
+	if err.Error() != want {
+		t.Fatalf("Error = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,154 @@
+package validation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/akylbek/payment-system/api-gateway/internal/interfaces"
+	"github.com/akylbek/payment-system/api-gateway/internal/models"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+var (
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	countryCode  = regexp.MustCompile(`^[A-Z]{2}$`)
+	cardBIN      = regexp.MustCompile(`^[0-9]{6,8}$`)
+)
+
+type Validator struct {
+	currencies Currencies
+	merchants  interfaces.MerchantRepository
+}
+
+func New(currencies Currencies, merchants interfaces.MerchantRepository) *Validator {
+	return &Validator{currencies: currencies, merchants: merchants}
+}
+
+// ValidatePayment checks a payment request and returns its amount. Invalid
+// requests fail with an *Error listing every invalid field; other errors
+// mean the merchant could not be looked up.
+func (v *Validator) ValidatePayment(ctx context.Context, req *models.CreatePaymentRequest) (money.Money, error) {
+	var result Error
+
+	amount, amountOK := v.checkAmount(req, &result)
+
+	if req.CustomerID == "" {
+		result.add("customer_id", CodeRequired, "customer_id is required")
+	}
+	if req.CustomerCountry != "" && !countryCode.MatchString(req.CustomerCountry) {
+		result.add("customer_country", CodeInvalid, "customer_country must be an uppercase ISO 3166-1 alpha-2 code, e.g. KZ")
+	}
+	if req.CardBIN != "" && !cardBIN.MatchString(req.CardBIN) {
+		result.add("card_bin", CodeInvalid, "card_bin must be 6 to 8 digits")
+	}
+
+	if err := v.checkMerchant(ctx, req.MerchantID, amount, amountOK, &result); err != nil {
+		return money.Money{}, err
+	}
+
+	if len(result.Fields) > 0 {
+		return money.Money{}, &result
+	}
+	return amount, nil
+}
+
+// checkAmount validates currency and amount together, since the currency
+// decides how many decimals the amount may have. It reports whether the
+// amount is usable for the merchant checks.
+func (v *Validator) checkAmount(req *models.CreatePaymentRequest, result *Error) (money.Money, bool) {
+	currencyOK := false
+	switch {
+	case req.Currency == "":
+		result.add("currency", CodeRequired, "currency is required")
+	case !currencyCode.MatchString(req.Currency):
+		result.add("currency", CodeInvalid, "currency must be an uppercase ISO 4217 code, e.g. USD")
+	default:
+		if _, err := money.Exponent(req.Currency); err != nil {
+			result.add("currency", CodeUnknownCurrency, fmt.Sprintf("%s is not a known ISO 4217 currency", req.Currency))
+		} else if _, ok := v.currencies[req.Currency]; !ok {
+			result.add("currency", CodeUnsupportedCurrency, fmt.Sprintf("%s is not supported", req.Currency))
+		} else {
+			currencyOK = true
+		}
+	}
+
+	if req.Amount == "" {
+		result.add("amount", CodeRequired, "amount is required")
+		return money.Money{}, false
+	}
+	if !currencyOK {
+		return money.Money{}, false
+	}
+
+	amount, err := req.Amount.Money(req.Currency)
+	switch {
+	case errors.Is(err, money.ErrPrecision):
+		message := fmt.Sprintf("%s amounts must be whole numbers", req.Currency)
+		if exp, _ := money.Exponent(req.Currency); exp > 0 {
+			message = fmt.Sprintf("%s amounts have at most %d decimal places", req.Currency, exp)
+		}
+		result.add("amount", CodeInvalidPrecision, message)
+		return money.Money{}, false
+	case err != nil:
+		result.add("amount", CodeInvalid, `amount must be a decimal number, e.g. 100.10 or "100.10"`)
+		return money.Money{}, false
+	case !amount.IsPositive():
+		result.add("amount", CodeNotPositive, "amount must be greater than zero")
+		return money.Money{}, false
+	}
+
+	return amount, checkLimit(amount, v.currencies[req.Currency], "", result)
+}
+
+// checkMerchant requires an existing active merchant and applies its amount
+// bounds when the amount itself is valid
+func (v *Validator) checkMerchant(ctx context.Context, merchantID string, amount money.Money, amountOK bool, result *Error) error {
+	if merchantID == "" {
+		result.add("merchant_id", CodeRequired, "merchant_id is required")
+		return nil
+	}
+
+	merchant, err := v.merchants.GetByID(ctx, merchantID)
+	if err == sql.ErrNoRows {
+		result.add("merchant_id", CodeNotFound, fmt.Sprintf("merchant %s does not exist", merchantID))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load merchant %s: %w", merchantID, err)
+	}
+	if merchant.Status != models.MerchantStatusActive {
+		result.add("merchant_id", CodeInactive, fmt.Sprintf("merchant %s is %s", merchantID, merchant.Status))
+		return nil
+	}
+
+	if !amountOK {
+		return nil
+	}
+	limit, err := v.merchants.GetAmountLimit(ctx, merchantID, amount.Currency)
+	if err != nil {
+		return fmt.Errorf("load amount limit of merchant %s: %w", merchantID, err)
+	}
+	if limit != nil {
+		checkLimit(amount, *limit, " for this merchant", result)
+	}
+	return nil
+}
+
+// checkLimit reports whether amount is within limit. scope is appended to
+// the message to tell merchant bounds from currency bounds.
+func checkLimit(amount money.Money, limit models.AmountLimit, scope string, result *Error) bool {
+	if limit.Min != nil && amount.Minor < *limit.Min {
+		minimum := money.Money{Minor: *limit.Min, Currency: amount.Currency}
+		result.add("amount", CodeBelowMinimum, fmt.Sprintf("amount must be at least %s %s%s", minimum, amount.Currency, scope))
+		return false
+	}
+	if limit.Max != nil && amount.Minor > *limit.Max {
+		maximum := money.Money{Minor: *limit.Max, Currency: amount.Currency}
+		result.add("amount", CodeAboveMaximum, fmt.Sprintf("amount must be at most %s %s%s", maximum, amount.Currency, scope))
+		return false
+	}
+	return true
+}
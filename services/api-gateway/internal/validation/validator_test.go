@@ -0,0 +1,183 @@
+package validation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/akylbek/payment-system/api-gateway/internal/models"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// fakeMerchants serves merchants and their amount limits from memory
+type fakeMerchants struct {
+	merchants map[string]*models.Merchant
+	limits    map[string]*models.AmountLimit
+	err       error
+}
+
+func (f *fakeMerchants) GetByID(_ context.Context, id string) (*models.Merchant, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	merchant, ok := f.merchants[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return merchant, nil
+}
+
+func (f *fakeMerchants) GetAmountLimit(_ context.Context, merchantID, currency string) (*models.AmountLimit, error) {
+	return f.limits[merchantID+":"+currency], nil
+}
+
+func bound(minor int64) *int64 {
+	return &minor
+}
+
+func newTestValidator(t *testing.T) *Validator {
+	t.Helper()
+	currencies, err := ParseCurrencies("USD,JPY,KWD", "USD:0.50:10000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(currencies, &fakeMerchants{
+		merchants: map[string]*models.Merchant{
+			"m-active":    {ID: "m-active", Status: models.MerchantStatusActive},
+			"m-suspended": {ID: "m-suspended", Status: "suspended"},
+		},
+		limits: map[string]*models.AmountLimit{
+			"m-active:USD": {Max: bound(50000)},
+		},
+	})
+}
+
+func validRequest() *models.CreatePaymentRequest {
+	return &models.CreatePaymentRequest{
+		Amount:     "100.10",
+		Currency:   "USD",
+		CustomerID: "cust-1",
+		MerchantID: "m-active",
+	}
+}
+
+func TestValidatePayment(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		name       string
+		modify     func(req *models.CreatePaymentRequest)
+		wantAmount money.Money
+		wantFields []string
+	}{
+		{"valid", func(*models.CreatePaymentRequest) {}, money.Money{Minor: 10010, Currency: "USD"}, nil},
+		{"zero decimal currency", func(r *models.CreatePaymentRequest) { r.Amount, r.Currency = "1500", "JPY" }, money.Money{Minor: 1500, Currency: "JPY"}, nil},
+		{"three decimal currency", func(r *models.CreatePaymentRequest) { r.Amount, r.Currency = "1.234", "KWD" }, money.Money{Minor: 1234, Currency: "KWD"}, nil},
+		{"optional fields", func(r *models.CreatePaymentRequest) { r.CustomerCountry, r.CardBIN = "KZ", "4242424" }, money.Money{Minor: 10010, Currency: "USD"}, nil},
+		{"missing currency", func(r *models.CreatePaymentRequest) { r.Currency = "" }, money.Money{}, []string{"currency:" + CodeRequired}},
+		{"lowercase currency", func(r *models.CreatePaymentRequest) { r.Currency = "usd" }, money.Money{}, []string{"currency:" + CodeInvalid}},
+		{"unknown currency", func(r *models.CreatePaymentRequest) { r.Currency = "XYZ" }, money.Money{}, []string{"currency:" + CodeUnknownCurrency}},
+		{"unsupported currency", func(r *models.CreatePaymentRequest) { r.Currency = "EUR" }, money.Money{}, []string{"currency:" + CodeUnsupportedCurrency}},
+		{"missing amount", func(r *models.CreatePaymentRequest) { r.Amount = "" }, money.Money{}, []string{"amount:" + CodeRequired}},
+		{"fractional yen", func(r *models.CreatePaymentRequest) { r.Amount, r.Currency = "10.5", "JPY" }, money.Money{}, []string{"amount:" + CodeInvalidPrecision}},
+		{"too many decimals", func(r *models.CreatePaymentRequest) { r.Amount = "1.001" }, money.Money{}, []string{"amount:" + CodeInvalidPrecision}},
+		{"not a number", func(r *models.CreatePaymentRequest) { r.Amount = "ten" }, money.Money{}, []string{"amount:" + CodeInvalid}},
+		{"zero amount", func(r *models.CreatePaymentRequest) { r.Amount = "0" }, money.Money{}, []string{"amount:" + CodeNotPositive}},
+		{"below currency minimum", func(r *models.CreatePaymentRequest) { r.Amount = "0.49" }, money.Money{}, []string{"amount:" + CodeBelowMinimum}},
+		{"above currency maximum", func(r *models.CreatePaymentRequest) { r.Amount = "10000.01" }, money.Money{}, []string{"amount:" + CodeAboveMaximum}},
+		{"above merchant maximum", func(r *models.CreatePaymentRequest) { r.Amount = "500.01" }, money.Money{}, []string{"amount:" + CodeAboveMaximum}},
+		{"missing customer", func(r *models.CreatePaymentRequest) { r.CustomerID = "" }, money.Money{}, []string{"customer_id:" + CodeRequired}},
+		{"invalid country", func(r *models.CreatePaymentRequest) { r.CustomerCountry = "KAZ" }, money.Money{}, []string{"customer_country:" + CodeInvalid}},
+		{"invalid card BIN", func(r *models.CreatePaymentRequest) { r.CardBIN = "4242" }, money.Money{}, []string{"card_bin:" + CodeInvalid}},
+		{"missing merchant", func(r *models.CreatePaymentRequest) { r.MerchantID = "" }, money.Money{}, []string{"merchant_id:" + CodeRequired}},
+		{"unknown merchant", func(r *models.CreatePaymentRequest) { r.MerchantID = "m-unknown" }, money.Money{}, []string{"merchant_id:" + CodeNotFound}},
+		{"inactive merchant", func(r *models.CreatePaymentRequest) { r.MerchantID = "m-suspended" }, money.Money{}, []string{"merchant_id:" + CodeInactive}},
+		{
+			"every invalid field is reported",
+			func(r *models.CreatePaymentRequest) {
+				*r = models.CreatePaymentRequest{Amount: "-1", Currency: "USD", CustomerCountry: "kz", CardBIN: "42", MerchantID: "m-unknown"}
+			},
+			money.Money{},
+			[]string{
+				"amount:" + CodeNotPositive,
+				"customer_id:" + CodeRequired,
+				"customer_country:" + CodeInvalid,
+				"card_bin:" + CodeInvalid,
+				"merchant_id:" + CodeNotFound,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validRequest()
+			tt.modify(req)
+
+			amount, err := v.ValidatePayment(context.Background(), req)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("ValidatePayment error = %v", err)
+				}
+				if amount != tt.wantAmount {
+					t.Fatalf("amount = %+v, want %+v", amount, tt.wantAmount)
+				}
+				return
+			}
+
+			var invalid *Error
+			if !errors.As(err, &invalid) {
+				t.Fatalf("ValidatePayment error = %v, want an *Error", err)
+			}
+			var fields []string
+			for _, f := range invalid.Fields {
+				fields = append(fields, f.Field+":"+f.Code)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Fatalf("fields = %v, want %v", fields, tt.wantFields)
+			}
+			if amount != (money.Money{}) {
+				t.Fatalf("amount of an invalid request = %+v", amount)
+			}
+		})
+	}
+}
+
+func TestValidatePaymentLimitMessages(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		amount string
+		want   string
+	}{
+		{"0.10", "amount must be at least 0.50 USD"},
+		{"20000", "amount must be at most 10000.00 USD"},
+		{"600", "amount must be at most 500.00 USD for this merchant"},
+	}
+	for _, tt := range tests {
+		req := validRequest()
+		req.Amount = money.Decimal(tt.amount)
+
+		_, err := v.ValidatePayment(context.Background(), req)
+		var invalid *Error
+		if !errors.As(err, &invalid) || len(invalid.Fields) != 1 || invalid.Fields[0].Message != tt.want {
+			t.Fatalf("ValidatePayment(%s) error = %v, want %q", tt.amount, err, tt.want)
+		}
+	}
+}
+
+func TestValidatePaymentMerchantLookupFails(t *testing.T) {
+	currencies, err := ParseCurrencies("USD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := errors.New("connection refused")
+	v := New(currencies, &fakeMerchants{err: down})
+
+	// A failed lookup is not the client's fault and is not a field error
+	_, err = v.ValidatePayment(context.Background(), validRequest())
+	var invalid *Error
+	if !errors.Is(err, down) || errors.As(err, &invalid) {
+		t.Fatalf("ValidatePayment error = %v, want the lookup error", err)
+	}
+}
@@ -0,0 +1,6 @@
+-- Reverts 001_api_gateway_schema
+
+DROP TABLE IF EXISTS merchants;
+DROP TABLE IF EXISTS customers;
+DROP TABLE IF EXISTS payments;
+DROP FUNCTION IF EXISTS update_updated_at_column();
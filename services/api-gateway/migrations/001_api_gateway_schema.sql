@@ -1,109 +0,0 @@
--- API Gateway Database Schema
--- Version: 001
--- Description: Schema for API Gateway service
-
--- =====================================================
--- API GATEWAY TABLES
--- =====================================================
-
--- Payments table (main payment records)
-CREATE TABLE IF NOT EXISTS payments (
-    id VARCHAR(255) PRIMARY KEY,
-    amount DECIMAL(15,2) NOT NULL CHECK (amount >= 0),
-    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
-    customer_id VARCHAR(255) NOT NULL,
-    merchant_id VARCHAR(255) NOT NULL,
-    status VARCHAR(50) NOT NULL,
-    payment_method VARCHAR(50),
-    description TEXT,
-    idempotency_key VARCHAR(255) UNIQUE,
-    metadata JSONB,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- Core indexes for payments
-CREATE INDEX IF NOT EXISTS idx_payments_customer_id ON payments(customer_id);
-CREATE INDEX IF NOT EXISTS idx_payments_idempotency_key ON payments(idempotency_key);
-
--- Composite indexes for common queries
-CREATE INDEX IF NOT EXISTS idx_payments_merchant_status ON payments(merchant_id, status) 
-    WHERE status IN ('NEW', 'SUCCEEDED', 'FAILED', 'PENDING');
-CREATE INDEX IF NOT EXISTS idx_payments_customer_created ON payments(customer_id, created_at DESC);
-CREATE INDEX IF NOT EXISTS idx_payments_merchant_created ON payments(merchant_id, created_at DESC);
-
--- Customers table
-CREATE TABLE IF NOT EXISTS customers (
-    id VARCHAR(255) PRIMARY KEY,
-    email VARCHAR(255) UNIQUE NOT NULL,
-    name VARCHAR(255) NOT NULL,
-    phone VARCHAR(50),
-    country VARCHAR(2),
-    metadata JSONB,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- Merchants table
-CREATE TABLE IF NOT EXISTS merchants (
-    id VARCHAR(255) PRIMARY KEY,
-    name VARCHAR(255) NOT NULL,
-    business_type VARCHAR(100),
-    email VARCHAR(255) UNIQUE NOT NULL,
-    website VARCHAR(255),
-    status VARCHAR(50) DEFAULT 'active',
-    api_key VARCHAR(255) UNIQUE,
-    metadata JSONB,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- =====================================================
--- FUNCTIONS AND TRIGGERS
--- =====================================================
-
--- Function to update updated_at timestamp
-CREATE OR REPLACE FUNCTION update_updated_at_column()
-RETURNS TRIGGER AS $$
-BEGIN
-    NEW.updated_at = CURRENT_TIMESTAMP;
-    RETURN NEW;
-END;
-$$ language 'plpgsql';
-
--- Triggers for updated_at
-CREATE TRIGGER update_payments_updated_at BEFORE UPDATE ON payments
-    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
-
-CREATE TRIGGER update_customers_updated_at BEFORE UPDATE ON customers
-    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
-
-CREATE TRIGGER update_merchants_updated_at BEFORE UPDATE ON merchants
-    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
-
--- =====================================================
--- INITIAL DATA
--- =====================================================
-
--- Insert sample merchant for testing
--- NOTE: This is a test/demo merchant. In production, remove this or use proper API key management.
-INSERT INTO merchants (id, name, business_type, email, status, api_key)
-VALUES 
-    ('merchant-001', 'Test Merchant Inc', 'e-commerce', 'merchant@test.com', 'active', 'test-key-DO-NOT-USE-IN-PRODUCTION')
-ON CONFLICT (id) DO NOTHING;
-
--- =====================================================
--- COMMENTS
--- =====================================================
-
-COMMENT ON TABLE payments IS 'Main payments table storing all payment transactions';
-COMMENT ON TABLE customers IS 'Customer information';
-COMMENT ON TABLE merchants IS 'Merchant/business accounts';
-
--- Schema version tracking
-CREATE TABLE IF NOT EXISTS schema_migrations (
-    version VARCHAR(50) PRIMARY KEY,
-    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-INSERT INTO schema_migrations (version) VALUES ('001_api_gateway_schema') ON CONFLICT DO NOTHING;
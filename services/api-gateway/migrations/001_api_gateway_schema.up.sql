@@ -15,6 +15,11 @@ CREATE TABLE IF NOT EXISTS payments (
     merchant_id VARCHAR(255) NOT NULL,
     status VARCHAR(50) NOT NULL,
     payment_method VARCHAR(50),
+    customer_email VARCHAR(255),
+    customer_country VARCHAR(2),
+    card_bin VARCHAR(8),
+    ip_address VARCHAR(45),
+    device_id VARCHAR(255),
     description TEXT,
     idempotency_key VARCHAR(255) UNIQUE,
     metadata JSONB,
@@ -22,6 +27,16 @@ CREATE TABLE IF NOT EXISTS payments (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Columns missing from tables created by the service before migrations
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS payment_method VARCHAR(50);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS customer_email VARCHAR(255);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS customer_country VARCHAR(2);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS card_bin VARCHAR(8);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS ip_address VARCHAR(45);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS device_id VARCHAR(255);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS metadata JSONB;
+
 -- Core indexes for payments
 CREATE INDEX IF NOT EXISTS idx_payments_customer_id ON payments(customer_id);
 CREATE INDEX IF NOT EXISTS idx_payments_idempotency_key ON payments(idempotency_key);
@@ -72,12 +87,15 @@ END;
 $$ language 'plpgsql';
 
 -- Triggers for updated_at
+DROP TRIGGER IF EXISTS update_payments_updated_at ON payments;
 CREATE TRIGGER update_payments_updated_at BEFORE UPDATE ON payments
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_customers_updated_at ON customers;
 CREATE TRIGGER update_customers_updated_at BEFORE UPDATE ON customers
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_merchants_updated_at ON merchants;
 CREATE TRIGGER update_merchants_updated_at BEFORE UPDATE ON merchants
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
@@ -99,11 +117,3 @@ ON CONFLICT (id) DO NOTHING;
 COMMENT ON TABLE payments IS 'Main payments table storing all payment transactions';
 COMMENT ON TABLE customers IS 'Customer information';
 COMMENT ON TABLE merchants IS 'Merchant/business accounts';
-
--- Schema version tracking
-CREATE TABLE IF NOT EXISTS schema_migrations (
-    version VARCHAR(50) PRIMARY KEY,
-    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-INSERT INTO schema_migrations (version) VALUES ('001_api_gateway_schema') ON CONFLICT DO NOTHING;
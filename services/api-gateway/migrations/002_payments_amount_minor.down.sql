@@ -0,0 +1,5 @@
+-- Reverts 002_payments_amount_minor
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_amount_minor_check;
+ALTER TABLE payments DROP COLUMN IF EXISTS amount_minor;
+ALTER TABLE payments ALTER COLUMN amount TYPE DECIMAL(15,2);
@@ -0,0 +1,21 @@
+-- Store payment amounts as integer minor units of their currency. amount
+-- stays for reporting and is widened to three decimals for currencies
+-- such as KWD.
+
+ALTER TABLE payments ALTER COLUMN amount TYPE DECIMAL(20,3);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS amount_minor BIGINT;
+
+UPDATE payments
+SET amount_minor = ROUND(amount * CASE
+        WHEN currency IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+                          'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1
+        WHEN currency IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        ELSE 100
+    END)
+WHERE amount_minor IS NULL;
+
+ALTER TABLE payments ALTER COLUMN amount_minor SET NOT NULL;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_amount_minor_check;
+ALTER TABLE payments ADD CONSTRAINT payments_amount_minor_check CHECK (amount_minor >= 0);
+
+COMMENT ON COLUMN payments.amount_minor IS 'Amount in minor units of currency (ISO 4217 exponent)';
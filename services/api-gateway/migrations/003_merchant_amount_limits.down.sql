@@ -0,0 +1,3 @@
+-- Reverts 003_merchant_amount_limits
+
+DROP TABLE IF EXISTS merchant_amount_limits;
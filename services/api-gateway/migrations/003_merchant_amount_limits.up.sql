@@ -0,0 +1,18 @@
+-- Per-merchant payment amount bounds in minor units of the currency,
+-- checked by the gateway on top of the bounds configured per currency
+CREATE TABLE IF NOT EXISTS merchant_amount_limits (
+    merchant_id VARCHAR(255) NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL,
+    min_amount_minor BIGINT CHECK (min_amount_minor > 0),
+    max_amount_minor BIGINT CHECK (max_amount_minor > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (merchant_id, currency),
+    CHECK (min_amount_minor IS NULL OR max_amount_minor IS NULL OR min_amount_minor <= max_amount_minor)
+);
+
+DROP TRIGGER IF EXISTS update_merchant_amount_limits_updated_at ON merchant_amount_limits;
+CREATE TRIGGER update_merchant_amount_limits_updated_at BEFORE UPDATE ON merchant_amount_limits
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE merchant_amount_limits IS 'Payment amount bounds per merchant and currency, in minor units';
@@ -4,51 +4,121 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/nats-io/nats.go"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
-	"github.com/akylbek/payment-system/fraud-service/internal/telemetry"
+	"github.com/akylbek/payment-system/fraud-service/internal/analytics"
+	"github.com/akylbek/payment-system/fraud-service/internal/config"
+	"github.com/akylbek/payment-system/fraud-service/internal/feedback"
+	"github.com/akylbek/payment-system/fraud-service/internal/lists"
+	"github.com/akylbek/payment-system/fraud-service/internal/profile"
+	"github.com/akylbek/payment-system/fraud-service/internal/review"
+	"github.com/akylbek/payment-system/fraud-service/internal/rules"
+	"github.com/akylbek/payment-system/fraud-service/internal/scoring"
+	"github.com/akylbek/payment-system/fraud-service/internal/velocity"
+	"github.com/akylbek/payment-system/fraud-service/migrations"
+	"github.com/akylbek/payment-system/pkg/platform/consumer"
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/httpserver"
+	"github.com/akylbek/payment-system/pkg/platform/kafkaclient"
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+	"github.com/akylbek/payment-system/pkg/platform/migrate"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+	"github.com/akylbek/payment-system/pkg/platform/natsclient"
+	"github.com/akylbek/payment-system/pkg/platform/postgres"
+	"github.com/akylbek/payment-system/pkg/platform/redisclient"
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
 )
 
 type FraudCheckRequest struct {
-	PaymentID  string  `json:"payment_id"`
-	Amount     float64 `json:"amount"`
-	CustomerID string  `json:"customer_id"`
+	PaymentID string `json:"payment_id"`
+	// Amount is the same amount in major units, the only one sent by
+	// orchestrators predating amount_minor
+	Amount money.Decimal `json:"amount"`
+	money.Money
+	CustomerID      string `json:"customer_id"`
+	MerchantID      string `json:"merchant_id,omitempty"`
+	CustomerCountry string `json:"customer_country,omitempty"` // ISO 3166-1 alpha-2
+	CustomerEmail   string `json:"customer_email,omitempty"`
+	EmailDomain     string `json:"email_domain,omitempty"`
+	PaymentMethod   string `json:"payment_method,omitempty"` // card fingerprint or payment method token
+	CardBIN         string `json:"card_bin,omitempty"`
+	IPAddress       string `json:"ip_address,omitempty"`
+	DeviceID        string `json:"device_id,omitempty"`
 }
 
-type FraudCheckResponse struct {
-	Decision string `json:"decision"` // approve, deny, manual_review
-	Reason   string `json:"reason"`
+// decodeCheckRequest reads a fraud check request, converting the decimal
+// amount of requests without amount_minor
+func decodeCheckRequest(data []byte, req *FraudCheckRequest) error {
+	if err := json.Unmarshal(data, req); err != nil {
+		return err
+	}
+	if req.PaymentID == "" {
+		return errors.New("fraud check request without payment_id")
+	}
+	if req.Minor == 0 {
+		amount, err := req.Amount.Money(req.Currency)
+		if err != nil {
+			return err
+		}
+		req.Money = amount
+	}
+	return nil
 }
 
-type FraudRule struct {
-	ID          int
-	Name        string
-	MaxAmount   float64
-	MaxPerHour  int
-	Description string
+type FraudCheckResponse struct {
+	PaymentID string         `json:"payment_id,omitempty"`
+	Decision  string         `json:"decision"` // approve, deny, manual_review
+	Reason    string         `json:"reason"`
+	RiskScore int            `json:"risk_score"`
+	Rules     []rules.Result `json:"rules"`
 }
 
+// Durable fraud checks: the orchestrator falls back to publishing requests
+// on a work-queue stream when request-reply times out, and reads decisions
+// from the results stream, so checks survive fraud-service restarts.
+const (
+	checkStream         = "FRAUD_CHECKS"
+	checkRequestSubject = "fraud.check.requests"
+	resultStream        = "FRAUD_CHECK_RESULTS"
+	resultSubject       = "fraud.check.results"
+	checkQueueGroup     = "fraud-service"
+)
+
 var (
-	db          *sql.DB
-	redisClient *redis.Client
-	nc          *nats.Conn
+	db              *sql.DB
+	redisClient     *redis.Client
+	nc              *nats.Conn
+	js              nats.JetStreamContext
+	amountLimits    money.Limits
+	velocityTracker *velocity.Tracker
+	velocityLimits  []velocity.Limit
+	velocityPolicy  string
+	reviewQueue     *review.Queue
+	listManager     *lists.Manager
+	profileService  *profile.Service
+	riskScorer      scoring.RiskScorer
+	riskUnits       scoring.Units
+	reviewScore     int
+	denyScore       int
 )
 
 func main() {
 	var err error
 
+	// Load configuration
+	cfg := config.Load()
+
 	// Initialize telemetry
 	if err := telemetry.InitTelemetry("fraud-service"); err != nil {
 		panic(fmt.Sprintf("Failed to initialize telemetry: %v", err))
@@ -57,243 +127,592 @@ func main() {
 
 	telemetry.Logger.Info("Starting Fraud Service")
 
+	// Inspect or replay dead-lettered fraud labels instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		brokers := kafkaclient.Brokers(cfg.KafkaBrokers)
+		if err := consumer.RunCommand(context.Background(), os.Args[2:], brokers, os.Stdout); err != nil {
+			telemetry.Logger.Fatal("dlq command failed", zap.Error(err))
+		}
+		return
+	}
+
 	// Connect to PostgreSQL
-	dbURL := os.Getenv("DATABASE_URL")
-	db, err = sql.Open("postgres", dbURL)
+	db, err = postgres.Open(cfg.DatabaseURL)
 	if err != nil {
 		telemetry.Logger.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer db.Close()
 
-	// Initialize database
-	if err := initDB(); err != nil {
-		telemetry.Logger.Fatal("Failed to initialize database", zap.Error(err))
+	migrator, err := migrate.New(db, migrations.FS, telemetry.Logger)
+	if err != nil {
+		telemetry.Logger.Fatal("Failed to load migrations", zap.Error(err))
 	}
 
-	// Connect to Redis
-	redisURL := os.Getenv("REDIS_URL")
-	redisClient = redis.NewClient(&redis.Options{
-		Addr: redisURL,
-	})
+	// Inspect, apply or roll back migrations instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.RunCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			telemetry.Logger.Fatal("migrate command failed", zap.Error(err))
+		}
+		return
+	}
+
+	if err := migrator.Up(context.Background()); err != nil {
+		telemetry.Logger.Fatal("Failed to migrate database", zap.Error(err))
+	}
+
+	redisClient = redisclient.New(cfg.RedisURL)
+
+	// Per-currency cap on a single payment
+	if amountLimits, err = money.ParseLimits(cfg.AmountLimits); err != nil {
+		telemetry.Logger.Fatal("Invalid AMOUNT_LIMITS", zap.Error(err))
+	}
+
+	// Setup velocity counters
+	if err := initVelocity(cfg); err != nil {
+		telemetry.Logger.Fatal("Invalid velocity configuration", zap.Error(err))
+	}
+
+	// Load blocklists and allowlists
+	listManager = lists.NewManager(lists.NewRepository(db), telemetry.Logger)
+	if err := listManager.Refresh(context.Background()); err != nil {
+		telemetry.Logger.Fatal("Failed to load fraud lists", zap.Error(err))
+	}
+
+	// Setup customer profiles
+	profileTTL, err := time.ParseDuration(cfg.ProfileCacheTTL)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid PROFILE_CACHE_TTL", zap.Error(err))
+	}
+	profileService = profile.NewService(profile.NewRepository(db), redisClient, velocityTracker, profileTTL, telemetry.Logger)
+
+	// Setup risk scoring
+	if err := initScoring(cfg); err != nil {
+		telemetry.Logger.Fatal("Invalid risk scoring configuration", zap.Error(err))
+	}
 
 	// Connect to NATS
-	natsURL := os.Getenv("NATS_URL")
-	nc, err = nats.Connect(natsURL)
+	nc, js, err = natsclient.Connect(cfg.NATSURL)
 	if err != nil {
 		telemetry.Logger.Fatal("Failed to connect to NATS", zap.Error(err))
 	}
-	defer nc.Close()
 
-	// Subscribe to fraud check requests
-	nc.Subscribe("fraud.check", handleFraudCheckRequest)
+	// Setup manual review queue
+	reviewSLA, err := time.ParseDuration(cfg.ReviewSLA)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid REVIEW_SLA", zap.Error(err))
+	}
+	if err := review.EnsureStream(js); err != nil {
+		telemetry.Logger.Fatal("Failed to create review stream", zap.Error(err))
+	}
+	reviewQueue = review.NewQueue(review.NewRepository(db), js, reviewSLA, telemetry.Logger)
 
-	telemetry.Logger.Info("Subscribed to fraud.check on NATS")
+	if err := ensureCheckStreams(); err != nil {
+		telemetry.Logger.Fatal("Failed to create fraud check streams", zap.Error(err))
+	}
 
-	// Setup Gin router
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(telemetry.TracingMiddleware())
+	labelRepo := feedback.NewRepository(db)
 
-	// Prometheus metrics endpoint
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	checker := health.New("fraud-service", 2*time.Second)
+	checker.Add("postgres", postgres.Check(db))
+	checker.Add("redis", redisclient.Check(redisClient))
+	checker.Add("nats", natsclient.Check(nc))
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "fraud-service"})
-	})
+	r := httpserver.NewRouter(checker)
+	r.GET("/fraud/decisions/:payment_id", getFraudDecision)
+	review.NewHandler(reviewQueue).Register(r)
+	analytics.NewHandler(analytics.NewRepository(db)).Register(r)
+	lists.NewHandler(listManager).Register(r)
+	profile.NewHandler(profileService).Register(r)
+	feedback.NewHandler(labelRepo).Register(r)
 
-	r.GET("/fraud/stats", getFraudStats)
+	shutdownTimeout, err := time.ParseDuration(cfg.ShutdownTimeout)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid SHUTDOWN_TIMEOUT", zap.Error(err))
+	}
+	drainDelay, err := time.ParseDuration(cfg.DrainDelay)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid DRAIN_DELAY", zap.Error(err))
+	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8083"
+	app := lifecycle.New(telemetry.Logger, shutdownTimeout)
+	app.Append(
+		postgres.CloseHook(db),
+		redisclient.CloseHook(redisClient),
+		natsclient.CloseHook(nc),
+		lifecycle.Go("velocity tracker", func(ctx context.Context) {
+			velocityTracker.Run(ctx, 5*time.Second)
+		}),
+		lifecycle.Go("fraud lists", func(ctx context.Context) {
+			listManager.Run(ctx, 30*time.Second)
+		}),
+		lifecycle.Go("review queue", func(ctx context.Context) {
+			reviewQueue.Run(ctx, 30*time.Second)
+		}),
+		natsclient.SubscribeHook(review.RequestedSubject, func() (*nats.Subscription, error) {
+			return natsclient.QueueSubscribe(js, natsclient.QueueConsumer{
+				Stream:  review.RequestStreamName,
+				Durable: "fraud-service-review-requests",
+				Subject: review.RequestedSubject,
+				Queue:   checkQueueGroup,
+				AckWait: 30 * time.Second,
+			}, reviewQueue.HandleRequest)
+		}),
+		// Replicas share the work through a queue group, so each check is answered once
+		natsclient.SubscribeHook("fraud.check", func() (*nats.Subscription, error) {
+			return nc.QueueSubscribe("fraud.check", checkQueueGroup, handleFraudCheckRequest)
+		}),
+		natsclient.SubscribeHook(checkRequestSubject, func() (*nats.Subscription, error) {
+			return natsclient.QueueSubscribe(js, natsclient.QueueConsumer{
+				Stream:  checkStream,
+				Durable: "fraud-service-checks",
+				Subject: checkRequestSubject,
+				Queue:   checkQueueGroup,
+				AckWait: 30 * time.Second,
+			}, handleFraudCheckJob)
+		}),
+	)
+
+	// Consume fraud labels (chargebacks, confirmed fraud, false positives)
+	if cfg.KafkaBrokers != "" {
+		brokers := kafkaclient.Brokers(cfg.KafkaBrokers)
+		labels := consumer.New(consumer.Config{
+			Brokers: brokers,
+			Topic:   cfg.FeedbackTopic,
+			GroupID: "fraud-service-feedback",
+		}, telemetry.Logger)
+		app.Append(labels.Hook(feedback.NewIngester(labelRepo, telemetry.Logger).Handle))
+		checker.Add("kafka", kafkaclient.Check(brokers))
+		telemetry.Logger.Info("Consuming fraud labels from Kafka", zap.String("topic", cfg.FeedbackTopic))
 	}
 
-	// Setup HTTP server
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: r,
+	app.Append(httpserver.Hook(cfg.Port, r), checker.Hook(drainDelay))
+	if err := app.Run(context.Background()); err != nil {
+		telemetry.Logger.Fatal("Fraud Service stopped with errors", zap.Error(err))
 	}
 
-	// Start server in goroutine
-	go func() {
-		telemetry.Logger.Info("Fraud Service starting", zap.String("port", port))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			telemetry.Logger.Fatal("Failed to start server", zap.Error(err))
-		}
-	}()
+	telemetry.Logger.Info("Server exited")
+}
 
-	// Wait for interrupt signal for graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+func initVelocity(cfg *config.Config) error {
+	dimensions, err := velocity.ParseDimensions(cfg.VelocityDimensions)
+	if err != nil {
+		return err
+	}
 
-	telemetry.Logger.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	
-	if err := srv.Shutdown(ctx); err != nil {
-		telemetry.Logger.Error("Server forced to shutdown", zap.Error(err))
+	windows, err := velocity.ParseWindows(cfg.VelocityWindows)
+	if err != nil {
+		return err
 	}
-	
-	telemetry.Logger.Info("Server exited")
+
+	velocityLimits, err = velocity.ParseLimits(cfg.VelocityLimits, windows)
+	if err != nil {
+		return err
+	}
+
+	switch cfg.VelocityPolicy {
+	case "open", "closed":
+		velocityPolicy = cfg.VelocityPolicy
+	default:
+		return fmt.Errorf("unknown velocity failure policy %q", cfg.VelocityPolicy)
+	}
+
+	store := velocity.NewStore(db)
+	velocityTracker = velocity.NewTracker(redisClient, store, telemetry.Logger, windows, dimensions)
+	return nil
 }
 
-func initDB() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS fraud_rules (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			max_amount DECIMAL(15,2),
-			max_per_hour INTEGER,
-			description TEXT,
-			active BOOLEAN DEFAULT true,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS fraud_decisions (
-			id SERIAL PRIMARY KEY,
-			payment_id VARCHAR(255) NOT NULL,
-			customer_id VARCHAR(255) NOT NULL,
-			amount DECIMAL(15,2) NOT NULL,
-			decision VARCHAR(50) NOT NULL,
-			reason TEXT,
-			risk_score INTEGER,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_fraud_decisions_payment_id ON fraud_decisions(payment_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_fraud_decisions_customer_id ON fraud_decisions(customer_id)`,
-	}
-
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return err
-		}
+func initScoring(cfg *config.Config) error {
+	var err error
+	riskScorer, err = scoring.New(cfg.RiskScorer, cfg.RiskWeightsFile, cfg.RiskModelFile)
+	if err != nil {
+		return err
 	}
 
-	// Insert default rules
-	db.Exec(`
-		INSERT INTO fraud_rules (name, max_amount, max_per_hour, description)
-		VALUES 
-			('High Amount Check', 10000.00, NULL, 'Deny payments over $10,000'),
-			('Velocity Check', NULL, 5, 'Max 5 payments per hour per customer')
-		ON CONFLICT DO NOTHING
-	`)
+	if riskUnits, err = scoring.ParseUnits(cfg.RiskAmountUnits); err != nil {
+		return fmt.Errorf("invalid RISK_AMOUNT_UNITS: %w", err)
+	}
+	if reviewScore, err = strconv.Atoi(cfg.RiskReviewScore); err != nil {
+		return fmt.Errorf("invalid RISK_REVIEW_THRESHOLD: %w", err)
+	}
+	if denyScore, err = strconv.Atoi(cfg.RiskDenyScore); err != nil {
+		return fmt.Errorf("invalid RISK_DENY_THRESHOLD: %w", err)
+	}
+	if reviewScore > denyScore {
+		return fmt.Errorf("review threshold %d is above deny threshold %d", reviewScore, denyScore)
+	}
 
+	telemetry.Logger.Info("Risk scorer configured",
+		zap.String("scorer", riskScorer.Name()),
+		zap.Int("review_threshold", reviewScore),
+		zap.Int("deny_threshold", denyScore),
+		zap.Stringer("amount_units", riskUnits),
+	)
 	return nil
 }
 
+// velocityEntities returns the counters a payment contributes to
+func velocityEntities(req *FraudCheckRequest) []velocity.Entity {
+	return []velocity.Entity{
+		{Dimension: velocity.DimensionCustomer, ID: req.CustomerID},
+		{Dimension: velocity.DimensionMerchant, ID: req.MerchantID},
+		{Dimension: velocity.DimensionPaymentMethod, ID: req.PaymentMethod},
+		{Dimension: velocity.DimensionIP, ID: req.IPAddress},
+		{Dimension: velocity.DimensionDevice, ID: req.DeviceID},
+	}
+}
+
+// handleFraudCheckRequest answers a request-reply fraud check. A retried
+// request gets the stored decision, so the payment is checked only once.
 func handleFraudCheckRequest(msg *nats.Msg) {
 	var req FraudCheckRequest
-	if err := json.Unmarshal(msg.Data, &req); err != nil {
+	if err := decodeCheckRequest(msg.Data, &req); err != nil {
 		telemetry.Logger.Error("Error unmarshaling fraud check request", zap.Error(err))
+		natsclient.RespondError(msg, fmt.Errorf("invalid fraud check request: %w", err))
+		return
+	}
+
+	ctx, span := natsclient.StartHandlerSpan(context.Background(), msg)
+	defer span.End()
+
+	// A decision that is not stored is never returned: the caller would act
+	// on it while a retry could decide differently. Storage errors get no
+	// reply rather than RespondError, which the caller treats as a final
+	// rejection; it times out, retries and falls back like on any outage.
+	decision, err := loadDecision(ctx, req.PaymentID)
+	if err != nil && err != sql.ErrNoRows {
+		telemetry.Logger.Error("Error loading fraud decision",
+			zap.String("payment_id", req.PaymentID),
+			zap.Error(err),
+		)
+		return
+	}
+	if err == sql.ErrNoRows {
+		if decision, err = processFraudCheck(ctx, &req); err != nil {
+			return
+		}
+	}
+
+	// Send response back via NATS
+	respJSON, _ := json.Marshal(decision)
+	msg.Respond(respJSON)
+}
+
+// handleFraudCheckJob answers a durable fraud check by publishing the
+// decision on the results stream. A request redelivered after a crash reuses
+// the stored decision instead of checking the payment twice.
+func handleFraudCheckJob(msg *nats.Msg) {
+	var req FraudCheckRequest
+	if err := decodeCheckRequest(msg.Data, &req); err != nil {
+		telemetry.Logger.Error("Error unmarshaling durable fraud check request", zap.Error(err))
+		msg.Term()
+		return
+	}
+
+	ctx, span := natsclient.StartHandlerSpan(context.Background(), msg)
+	defer span.End()
+
+	decision, err := loadDecision(ctx, req.PaymentID)
+	if err != nil && err != sql.ErrNoRows {
+		telemetry.Logger.Error("Error loading fraud decision",
+			zap.String("payment_id", req.PaymentID),
+			zap.Error(err),
+		)
+		msg.NakWithDelay(5 * time.Second)
 		return
 	}
+	if err == sql.ErrNoRows {
+		if decision, err = processFraudCheck(ctx, &req); err != nil {
+			msg.NakWithDelay(5 * time.Second)
+			return
+		}
+	}
+
+	respJSON, _ := json.Marshal(decision)
+	result := natsclient.NewMsg(ctx, resultSubject, respJSON)
+	if _, err := js.PublishMsg(result, nats.MsgId(req.PaymentID)); err != nil {
+		telemetry.Logger.Error("Error publishing fraud check result",
+			zap.String("payment_id", req.PaymentID),
+			zap.Error(err),
+		)
+		msg.NakWithDelay(5 * time.Second)
+		return
+	}
+
+	msg.Ack()
+}
 
+func ensureCheckStreams() error {
+	streams := []*nats.StreamConfig{
+		{
+			Name:      checkStream,
+			Subjects:  []string{checkRequestSubject},
+			Retention: nats.WorkQueuePolicy,
+			Storage:   nats.FileStorage,
+			MaxAge:    24 * time.Hour,
+		},
+		{
+			Name:     resultStream,
+			Subjects: []string{resultSubject},
+			Storage:  nats.FileStorage,
+			MaxAge:   24 * time.Hour,
+		},
+	}
+
+	return natsclient.EnsureStreams(js, streams...)
+}
+
+// processFraudCheck checks a payment, opens a review case if needed and
+// stores the decision. The error reports a decision that could not be saved.
+func processFraudCheck(ctx context.Context, req *FraudCheckRequest) (*FraudCheckResponse, error) {
 	telemetry.Logger.Info("Fraud check request",
 		zap.String("payment_id", req.PaymentID),
-		zap.Float64("amount", req.Amount),
+		zap.Stringer("amount", req.Money),
+		zap.String("currency", req.Currency),
 		zap.String("customer_id", req.CustomerID),
 	)
 
-	ctx := context.Background()
-	decision := checkFraud(ctx, &req)
+	start := time.Now()
+	decision := checkFraud(ctx, req)
+	decision.PaymentID = req.PaymentID
+
+	// Queue payments flagged for manual review for an analyst
+	if decision.Decision == "manual_review" {
+		err := reviewQueue.Enqueue(ctx, &review.Case{
+			PaymentID:  req.PaymentID,
+			CustomerID: req.CustomerID,
+			Money:      req.Money,
+			Reason:     decision.Reason,
+			RiskScore:  decision.RiskScore,
+		})
+		if err != nil {
+			// Without a case nobody would ever decide the payment
+			telemetry.Logger.Error("Error opening review case, declining payment",
+				zap.String("payment_id", req.PaymentID),
+				zap.Error(err),
+			)
+			decision.Rules = append(decision.Rules, rules.ReviewUnavailable())
+			decision.Decision, decision.Reason = rules.Decide(decision.Rules)
+		}
+	}
 
-	// Save decision to database
-	_, err := db.ExecContext(ctx, `
-		INSERT INTO fraud_decisions (payment_id, customer_id, amount, decision, reason, risk_score)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, req.PaymentID, req.CustomerID, req.Amount, decision.Decision, decision.Reason, calculateRiskScore(&req))
+	fraudDecisions.WithLabelValues(decision.Decision).Inc()
+	fraudCheckDuration.WithLabelValues(decision.Decision).Observe(time.Since(start).Seconds())
+
+	// Save decision to database. A concurrent check of the same payment may
+	// have saved one first; that decision wins and is returned instead.
+	ruleResults, _ := json.Marshal(decision.Rules)
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO fraud_decisions (payment_id, customer_id, merchant_id, amount, amount_minor, currency, decision, reason, risk_score, rules_triggered, rule_results)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11)
+		ON CONFLICT (payment_id) DO NOTHING
+	`, req.PaymentID, req.CustomerID, req.MerchantID, req.Money.String(), req.Minor, req.Currency, decision.Decision, decision.Reason,
+		decision.RiskScore, pq.Array(rules.Triggered(decision.Rules)), ruleResults)
 
 	if err != nil {
 		telemetry.Logger.Error("Error saving fraud decision",
 			zap.String("payment_id", req.PaymentID),
 			zap.Error(err),
 		)
+		return decision, err
+	}
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		stored, err := loadDecision(ctx, req.PaymentID)
+		if err != nil {
+			return decision, err
+		}
+		telemetry.Logger.Info("Fraud check already decided, returning stored decision",
+			zap.String("payment_id", req.PaymentID),
+			zap.String("decision", stored.Decision),
+		)
+		return stored, nil
 	}
 
-	// Send response back via NATS
-	respJSON, _ := json.Marshal(decision)
-	msg.Respond(respJSON)
+	// Only approved payments count towards velocity limits
+	if decision.Decision == "approve" {
+		if err := velocityTracker.Record(ctx, velocityEntities(req), req.PaymentID, req.Money, time.Now()); err != nil {
+			telemetry.Logger.Error("Error recording velocity counters",
+				zap.String("payment_id", req.PaymentID),
+				zap.Error(err),
+			)
+		}
+	}
 
 	telemetry.Logger.Info("Fraud check completed",
 		zap.String("payment_id", req.PaymentID),
 		zap.String("decision", decision.Decision),
 		zap.String("reason", decision.Reason),
+		zap.Int("risk_score", decision.RiskScore),
 	)
+
+	return decision, nil
+}
+
+// loadDecision returns the stored decision for a payment
+func loadDecision(ctx context.Context, paymentID string) (*FraudCheckResponse, error) {
+	decision := &FraudCheckResponse{PaymentID: paymentID}
+	var ruleResults []byte
+
+	err := db.QueryRowContext(ctx, `
+		SELECT decision, COALESCE(reason, ''), COALESCE(risk_score, 0), COALESCE(rule_results, '[]')
+		FROM fraud_decisions
+		WHERE payment_id = $1
+	`, paymentID).Scan(&decision.Decision, &decision.Reason, &decision.RiskScore, &ruleResults)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(ruleResults, &decision.Rules); err != nil {
+		return nil, err
+	}
+	return decision, nil
 }
 
 func checkFraud(ctx context.Context, req *FraudCheckRequest) *FraudCheckResponse {
-	// Rule 1: High amount check
-	if req.Amount > 10000 {
+	// Rule 0: Blocklists and allowlists short-circuit every other rule
+	subject := lists.Subject{
+		Country:     req.CustomerCountry,
+		CustomerID:  req.CustomerID,
+		Email:       req.CustomerEmail,
+		EmailDomain: req.EmailDomain,
+		IP:          req.IPAddress,
+		BIN:         req.CardBIN,
+	}
+	if entry := listManager.Matcher().Match(lists.ListBlock, subject, time.Now()); entry != nil {
+		result := rules.ListMatch(entry)
 		return &FraudCheckResponse{
-			Decision: "deny",
-			Reason:   "Amount exceeds $10,000 limit",
+			Decision:  rules.ActionDeny,
+			Reason:    result.Detail,
+			RiskScore: scoring.MaxScore,
+			Rules:     []rules.Result{result},
 		}
 	}
-
-	// Rule 2: Velocity check (max 5 payments per hour)
-	velocityKey := "fraud:velocity:" + req.CustomerID
-	count, err := redisClient.Incr(ctx, velocityKey).Result()
-	if err == nil {
-		if count == 1 {
-			redisClient.Expire(ctx, velocityKey, time.Hour)
-		}
-		if count > 5 {
-			return &FraudCheckResponse{
-				Decision: "deny",
-				Reason:   "Too many payments in the last hour (velocity check failed)",
-			}
+	if entry := listManager.Matcher().Match(lists.ListAllow, subject, time.Now()); entry != nil {
+		result := rules.ListMatch(entry)
+		return &FraudCheckResponse{
+			Decision:  rules.ActionApprove,
+			Reason:    result.Detail,
+			RiskScore: scoring.MinScore,
+			Rules:     []rules.Result{result},
 		}
 	}
 
-	// Rule 3: Random manual review (10% of transactions)
-	// In real system, this would be based on more sophisticated ML models
-	if req.Amount > 5000 {
-		return &FraudCheckResponse{
-			Decision: "manual_review",
-			Reason:   "High-value transaction requires manual review",
+	// Velocity counters over sliding windows feed both the limits and the score
+	snapshot, _, velocityErr := velocityTracker.Snapshot(ctx, velocityEntities(req), time.Now())
+	if velocityErr != nil {
+		mode := velocity.ModeFailOpen
+		if velocityPolicy == "closed" {
+			mode = velocity.ModeFailClosed
 		}
+		velocity.DegradedEvaluations.WithLabelValues(mode).Inc()
+		telemetry.Logger.Warn("Velocity counters unavailable",
+			zap.String("payment_id", req.PaymentID),
+			zap.String("mode", mode),
+			zap.Error(velocityErr),
+		)
+	}
+
+	// Customer history is cached and optional; scoring works without it
+	customerProfile, err := profileService.Get(ctx, req.CustomerID)
+	if err != nil {
+		telemetry.Logger.Warn("Customer profile unavailable",
+			zap.String("payment_id", req.PaymentID),
+			zap.String("customer_id", req.CustomerID),
+			zap.Error(err),
+		)
 	}
 
+	riskScore, features := calculateRiskScore(req, snapshot, customerProfile)
+
+	var results []rules.Result
+
+	// Rule 1: High amount check
+	results = append(results, rules.AmountLimit(req.Money, amountLimits))
+
+	// Rule 2: Velocity limits
+	results = append(results, rules.Velocity(snapshot, velocityLimits, req.Money,
+		velocityErr == nil, velocityPolicy == "closed")...)
+
+	// Rule 3: Risk score thresholds
+	results = append(results, rules.Score(riskScorer, features, riskScore, reviewScore, denyScore)...)
+
+	decision, reason := rules.Decide(results)
 	return &FraudCheckResponse{
-		Decision: "approve",
-		Reason:   "All fraud checks passed",
+		Decision:  decision,
+		Reason:    reason,
+		RiskScore: riskScore,
+		Rules:     results,
 	}
 }
 
-func calculateRiskScore(req *FraudCheckRequest) int {
-	// Simple risk scoring logic
-	score := 0
+// calculateRiskScore runs the configured scorer over the payment features.
+// Scorer failures are logged and score as 0 so the other rules still apply.
+func calculateRiskScore(req *FraudCheckRequest, snapshot velocity.Snapshot, customerProfile *profile.Profile) (int, scoring.Features) {
+	features := scoring.ExtractFeatures(scoring.Input{
+		Amount:        req.Money,
+		Units:         riskUnits,
+		CustomerID:    req.CustomerID,
+		MerchantID:    req.MerchantID,
+		PaymentMethod: req.PaymentMethod,
+		IPAddress:     req.IPAddress,
+		DeviceID:      req.DeviceID,
+		Velocity:      snapshot,
+		Profile:       customerProfile,
+		Now:           time.Now(),
+	})
 
-	if req.Amount > 1000 {
-		score += 30
-	}
-	if req.Amount > 5000 {
-		score += 50
+	score, err := riskScorer.Score(features)
+	if err != nil {
+		telemetry.Logger.Error("Error calculating risk score",
+			zap.String("payment_id", req.PaymentID),
+			zap.String("scorer", riskScorer.Name()),
+			zap.Error(err),
+		)
+		return scoring.MinScore, features
 	}
-
-	return score
+	return score, features
 }
 
-func getFraudStats(c *gin.Context) {
-	var stats struct {
-		TotalChecks    int `json:"total_checks"`
-		ApprovedCount  int `json:"approved_count"`
-		DeniedCount    int `json:"denied_count"`
-		ManualReview   int `json:"manual_review_count"`
-		AvgRiskScore   int `json:"avg_risk_score"`
-	}
-
-	db.QueryRow(`
-		SELECT 
-			COUNT(*) as total,
-			COUNT(CASE WHEN decision = 'approve' THEN 1 END) as approved,
-			COUNT(CASE WHEN decision = 'deny' THEN 1 END) as denied,
-			COUNT(CASE WHEN decision = 'manual_review' THEN 1 END) as manual_review,
-			COALESCE(AVG(risk_score), 0) as avg_risk_score
+func getFraudDecision(c *gin.Context) {
+	paymentID := c.Param("payment_id")
+
+	var decision struct {
+		PaymentID  string        `json:"payment_id"`
+		CustomerID string        `json:"customer_id"`
+		Amount     money.Decimal `json:"amount"`
+		money.Money
+		Decision       string         `json:"decision"`
+		Reason         string         `json:"reason"`
+		RiskScore      int            `json:"risk_score"`
+		RulesTriggered []string       `json:"rules_triggered"`
+		Rules          []rules.Result `json:"rules"`
+		CreatedAt      time.Time      `json:"created_at"`
+	}
+	var ruleResults []byte
+
+	err := db.QueryRowContext(c.Request.Context(), `
+		SELECT payment_id, customer_id, COALESCE(amount_minor, 0), COALESCE(currency, ''), decision, COALESCE(reason, ''), COALESCE(risk_score, 0),
+			COALESCE(rules_triggered, '{}'), COALESCE(rule_results, '[]'), created_at
 		FROM fraud_decisions
-	`).Scan(&stats.TotalChecks, &stats.ApprovedCount, &stats.DeniedCount,
-		&stats.ManualReview, &stats.AvgRiskScore)
+		WHERE payment_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, paymentID).Scan(&decision.PaymentID, &decision.CustomerID, &decision.Minor, &decision.Currency, &decision.Decision,
+		&decision.Reason, &decision.RiskScore, pq.Array(&decision.RulesTriggered), &ruleResults, &decision.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fraud decision not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fraud decision"})
+		return
+	}
+
+	if err := json.Unmarshal(ruleResults, &decision.Rules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode fraud decision"})
+		return
+	}
+	decision.Amount = decision.Money.Decimal()
 
-	c.JSON(http.StatusOK, stats)
+	c.JSON(http.StatusOK, decision)
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func TestDecodeCheckRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    money.Money
+		wantErr bool
+	}{
+		{"minor units", `{"payment_id": "p1", "amount_minor": 10010, "currency": "USD"}`, money.Money{Minor: 10010, Currency: "USD"}, false},
+		// Orchestrators predating amount_minor only send the decimal amount
+		{"decimal amount", `{"payment_id": "p1", "amount": 100.10, "currency": "USD"}`, money.Money{Minor: 10010, Currency: "USD"}, false},
+		{"decimal string", `{"payment_id": "p1", "amount": "1500", "currency": "JPY"}`, money.Money{Minor: 1500, Currency: "JPY"}, false},
+		{"minor units win", `{"payment_id": "p1", "amount": 1, "amount_minor": 250, "currency": "EUR"}`, money.Money{Minor: 250, Currency: "EUR"}, false},
+		{"malformed", `not json`, money.Money{}, true},
+		{"no payment", `{"amount_minor": 100, "currency": "USD"}`, money.Money{}, true},
+		{"too precise", `{"payment_id": "p1", "amount": 1.5, "currency": "JPY"}`, money.Money{}, true},
+		{"unknown currency", `{"payment_id": "p1", "amount": 10, "currency": "XXX"}`, money.Money{}, true},
+		{"no amount", `{"payment_id": "p1", "currency": "USD"}`, money.Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req FraudCheckRequest
+			err := decodeCheckRequest([]byte(tt.data), &req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decodeCheckRequest succeeded with %+v", req.Money)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.PaymentID != "p1" || req.Money != tt.want {
+				t.Fatalf("decodeCheckRequest = %s %+v, want p1 %+v", req.PaymentID, req.Money, tt.want)
+			}
+		})
+	}
+}
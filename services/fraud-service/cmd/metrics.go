@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	fraudDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "fraud_decisions_total",
+		Help: "Fraud check decisions by outcome (approve, deny, manual_review)",
+	}, []string{"decision"})
+
+	// fraudCheckDuration covers rule evaluation and opening a review case,
+	// but not storing the decision
+	fraudCheckDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "fraud_check_duration_seconds",
+		Help:    "Time to decide a fraud check, by outcome",
+		Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"decision"})
+)
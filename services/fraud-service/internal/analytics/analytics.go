@@ -0,0 +1,265 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+type Granularity string
+
+const (
+	Hour Granularity = "hour"
+	Day  Granularity = "day"
+	Week Granularity = "week"
+)
+
+func (g Granularity) duration() time.Duration {
+	switch g {
+	case Hour:
+		return time.Hour
+	case Week:
+		return 7 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// MaxBuckets bounds the size of a single stats response
+const MaxBuckets = 1000
+
+// Query selects decisions in [From, To). Empty filters match everything.
+type Query struct {
+	From        time.Time
+	To          time.Time
+	Granularity Granularity
+	MerchantID  string
+	Currency    string
+	TopRules    int
+}
+
+func (q *Query) Validate() error {
+	switch q.Granularity {
+	case Hour, Day, Week:
+	default:
+		return fmt.Errorf("granularity must be %q, %q or %q", Hour, Day, Week)
+	}
+	if !q.From.Before(q.To) {
+		return fmt.Errorf("from must be before to")
+	}
+	if q.To.Sub(q.From)/q.Granularity.duration() > MaxBuckets {
+		return fmt.Errorf("time range exceeds %d %s buckets", MaxBuckets, q.Granularity)
+	}
+	if q.TopRules < 0 || q.TopRules > 100 {
+		return fmt.Errorf("top must be between 0 and 100")
+	}
+	return nil
+}
+
+// Amounts sums the checked amounts of one currency by decision
+type Amounts struct {
+	Total        money.Money `json:"total"`
+	Approved     money.Money `json:"approved"`
+	Denied       money.Money `json:"denied"`
+	ManualReview money.Money `json:"manual_review"`
+}
+
+func (a *Amounts) add(o Amounts) {
+	a.Total.Minor += o.Total.Minor
+	a.Approved.Minor += o.Approved.Minor
+	a.Denied.Minor += o.Denied.Minor
+	a.ManualReview.Minor += o.ManualReview.Minor
+}
+
+// Counts aggregates the decisions of a bucket or of the whole range.
+// Amounts are kept per currency.
+type Counts struct {
+	TotalChecks   int                `json:"total_checks"`
+	ApprovedCount int                `json:"approved_count"`
+	DeniedCount   int                `json:"denied_count"`
+	ManualReview  int                `json:"manual_review_count"`
+	Amounts       map[string]Amounts `json:"amounts"`
+	AvgRiskScore  float64            `json:"avg_risk_score"`
+
+	scoreSum   int64
+	scoreCount int64
+}
+
+func (c *Counts) add(o Counts) {
+	c.TotalChecks += o.TotalChecks
+	c.ApprovedCount += o.ApprovedCount
+	c.DeniedCount += o.DeniedCount
+	c.ManualReview += o.ManualReview
+	for currency, amounts := range o.Amounts {
+		c.addAmounts(currency, amounts)
+	}
+	c.scoreSum += o.scoreSum
+	c.scoreCount += o.scoreCount
+	c.average()
+}
+
+func (c *Counts) addAmounts(currency string, o Amounts) {
+	if c.Amounts == nil {
+		c.Amounts = make(map[string]Amounts)
+	}
+	a, ok := c.Amounts[currency]
+	if !ok {
+		zero := money.Money{Currency: currency}
+		a = Amounts{Total: zero, Approved: zero, Denied: zero, ManualReview: zero}
+	}
+	a.add(o)
+	c.Amounts[currency] = a
+}
+
+func (c *Counts) average() {
+	c.AvgRiskScore = 0
+	if c.scoreCount > 0 {
+		c.AvgRiskScore = float64(c.scoreSum) / float64(c.scoreCount)
+	}
+}
+
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Counts
+}
+
+type RuleCount struct {
+	Code  string `json:"code"`
+	Count int    `json:"count"`
+}
+
+// Stats embeds the range totals so the top level keeps the fields of the
+// original all-time /fraud/stats response
+type Stats struct {
+	From        time.Time   `json:"from"`
+	To          time.Time   `json:"to"`
+	Granularity Granularity `json:"granularity"`
+	MerchantID  string      `json:"merchant_id,omitempty"`
+	Currency    string      `json:"currency,omitempty"`
+	Counts
+	Buckets  []Bucket    `json:"buckets"`
+	TopRules []RuleCount `json:"top_rules"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Stats returns one bucket per granularity step in the range, including
+// empty ones, and the most frequently triggered rules
+func (r *Repository) Stats(ctx context.Context, q Query) (*Stats, error) {
+	from, to := q.From.UTC(), q.To.UTC()
+	stats := &Stats{
+		From:        from,
+		To:          to,
+		Granularity: q.Granularity,
+		MerchantID:  q.MerchantID,
+		Currency:    q.Currency,
+		Counts:      Counts{Amounts: map[string]Amounts{}},
+		Buckets:     []Bucket{},
+		TopRules:    []RuleCount{},
+	}
+
+	// One row per bucket and currency, merged into one bucket below.
+	// Decisions recorded before amounts were kept in minor units count but
+	// have no amount.
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH buckets AS (
+			SELECT generate_series(
+				date_trunc($1, $2::timestamp),
+				date_trunc($1, $3::timestamp - interval '1 microsecond'),
+				('1 ' || $1)::interval
+			) AS start
+		)
+		SELECT
+			b.start,
+			COALESCE(d.currency, ''),
+			COUNT(d.id),
+			COUNT(*) FILTER (WHERE d.decision = 'approve'),
+			COUNT(*) FILTER (WHERE d.decision = 'deny'),
+			COUNT(*) FILTER (WHERE d.decision = 'manual_review'),
+			COALESCE(SUM(d.amount_minor), 0),
+			COALESCE(SUM(d.amount_minor) FILTER (WHERE d.decision = 'approve'), 0),
+			COALESCE(SUM(d.amount_minor) FILTER (WHERE d.decision = 'deny'), 0),
+			COALESCE(SUM(d.amount_minor) FILTER (WHERE d.decision = 'manual_review'), 0),
+			COALESCE(SUM(d.risk_score), 0),
+			COUNT(d.risk_score)
+		FROM buckets b
+		LEFT JOIN fraud_decisions d
+			ON d.created_at >= GREATEST(b.start, $2::timestamp)
+			AND d.created_at < LEAST(b.start + ('1 ' || $1)::interval, $3::timestamp)
+			AND ($4 = '' OR d.merchant_id = $4)
+			AND ($5 = '' OR d.currency = $5)
+		GROUP BY b.start, d.currency
+		ORDER BY b.start, d.currency
+	`, string(q.Granularity), from, to, q.MerchantID, q.Currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var start time.Time
+		var currency string
+		var c Counts
+		var total, approved, denied, review int64
+		if err := rows.Scan(&start, &currency, &c.TotalChecks, &c.ApprovedCount, &c.DeniedCount, &c.ManualReview,
+			&total, &approved, &denied, &review,
+			&c.scoreSum, &c.scoreCount); err != nil {
+			return nil, err
+		}
+		if currency != "" {
+			c.addAmounts(currency, Amounts{
+				Total:        money.Money{Minor: total, Currency: currency},
+				Approved:     money.Money{Minor: approved, Currency: currency},
+				Denied:       money.Money{Minor: denied, Currency: currency},
+				ManualReview: money.Money{Minor: review, Currency: currency},
+			})
+		}
+
+		if n := len(stats.Buckets); n == 0 || !stats.Buckets[n-1].Start.Equal(start) {
+			stats.Buckets = append(stats.Buckets, Bucket{Start: start, Counts: Counts{Amounts: map[string]Amounts{}}})
+		}
+		stats.Buckets[len(stats.Buckets)-1].Counts.add(c)
+		stats.Counts.add(c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if q.TopRules == 0 {
+		return stats, nil
+	}
+
+	rules, err := r.db.QueryContext(ctx, `
+		SELECT code, COUNT(*)
+		FROM fraud_decisions d, unnest(d.rules_triggered) AS code
+		WHERE d.created_at >= $1 AND d.created_at < $2
+			AND ($3 = '' OR d.merchant_id = $3)
+			AND ($4 = '' OR d.currency = $4)
+		GROUP BY code
+		ORDER BY COUNT(*) DESC, code
+		LIMIT $5
+	`, from, to, q.MerchantID, q.Currency, q.TopRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rules.Close()
+
+	for rules.Next() {
+		var rc RuleCount
+		if err := rules.Scan(&rc.Code, &rc.Count); err != nil {
+			return nil, err
+		}
+		stats.TopRules = append(stats.TopRules, rc)
+	}
+	return stats, rules.Err()
+}
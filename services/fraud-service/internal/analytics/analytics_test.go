@@ -0,0 +1,108 @@
+package analytics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func TestQueryValidate(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		q       Query
+		wantErr bool
+	}{
+		{"hourly", Query{From: from, To: from.Add(MaxBuckets * time.Hour), Granularity: Hour, TopRules: 10}, false},
+		{"too many hours", Query{From: from, To: from.Add((MaxBuckets + 1) * time.Hour), Granularity: Hour}, true},
+		{"daily", Query{From: from, To: from.AddDate(0, 0, MaxBuckets), Granularity: Day}, false},
+		{"too many days", Query{From: from, To: from.AddDate(0, 0, MaxBuckets+1), Granularity: Day}, true},
+		{"weekly over years", Query{From: from, To: from.AddDate(10, 0, 0), Granularity: Week}, false},
+		{"unknown granularity", Query{From: from, To: from.Add(time.Hour), Granularity: "month"}, true},
+		{"empty range", Query{From: from, To: from, Granularity: Day}, true},
+		{"reversed range", Query{From: from, To: from.Add(-time.Hour), Granularity: Day}, true},
+		{"no top rules", Query{From: from, To: from.Add(time.Hour), Granularity: Day, TopRules: 0}, false},
+		{"negative top", Query{From: from, To: from.Add(time.Hour), Granularity: Day, TopRules: -1}, true},
+		{"top over 100", Query{From: from, To: from.Add(time.Hour), Granularity: Day, TopRules: 101}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.q.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCountsAdd(t *testing.T) {
+	usd := func(minor int64) money.Money { return money.Money{Minor: minor, Currency: "USD"} }
+
+	var total Counts
+	total.add(Counts{
+		TotalChecks: 2, ApprovedCount: 1, DeniedCount: 1,
+		Amounts:  map[string]Amounts{"USD": {Total: usd(300), Approved: usd(100), Denied: usd(200), ManualReview: usd(0)}},
+		scoreSum: 100, scoreCount: 2,
+	})
+	// Rows of another currency in the same bucket
+	total.add(Counts{
+		TotalChecks: 1, ManualReview: 1,
+		Amounts:  map[string]Amounts{"JPY": {Total: money.Money{Minor: 5000, Currency: "JPY"}, ManualReview: money.Money{Minor: 5000, Currency: "JPY"}}},
+		scoreSum: 80, scoreCount: 1,
+	})
+	// Decisions recorded without an amount or a score
+	total.add(Counts{TotalChecks: 1, ApprovedCount: 1})
+
+	if total.TotalChecks != 4 || total.ApprovedCount != 2 || total.DeniedCount != 1 || total.ManualReview != 1 {
+		t.Fatalf("counts = %+v", total)
+	}
+	// The average covers only decisions with a score
+	if total.AvgRiskScore != 60 {
+		t.Fatalf("AvgRiskScore = %v, want 60", total.AvgRiskScore)
+	}
+
+	jpy := money.Money{Currency: "JPY"}
+	want := map[string]Amounts{
+		"USD": {Total: usd(300), Approved: usd(100), Denied: usd(200), ManualReview: usd(0)},
+		// Missing amounts are zero in the currency rather than empty
+		"JPY": {Total: money.Money{Minor: 5000, Currency: "JPY"}, Approved: jpy, Denied: jpy, ManualReview: money.Money{Minor: 5000, Currency: "JPY"}},
+	}
+	if !reflect.DeepEqual(total.Amounts, want) {
+		t.Fatalf("amounts = %+v, want %+v", total.Amounts, want)
+	}
+
+	var empty Counts
+	empty.add(Counts{})
+	if empty.AvgRiskScore != 0 {
+		t.Fatalf("AvgRiskScore without scores = %v, want 0", empty.AvgRiskScore)
+	}
+}
+
+func TestHandlerRejectsInvalidQueries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Invalid queries never reach the database
+	NewHandler(NewRepository(nil)).Register(router)
+
+	for _, query := range []string{
+		"granularity=month",
+		"from=yesterday",
+		"to=2024-03-01",
+		"from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z",
+		"from=2020-01-01T00:00:00Z&to=2024-01-01T00:00:00Z&granularity=hour",
+		"top=ten",
+		"top=101",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fraud/stats?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("GET /fraud/stats?%s: status %d, want 400", query, w.Code)
+		}
+	}
+}
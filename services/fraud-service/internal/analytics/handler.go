@@ -0,0 +1,66 @@
+package analytics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// Register mounts the analytics routes on the router
+func (h *Handler) Register(r gin.IRouter) {
+	r.GET("/fraud/stats", h.Stats)
+}
+
+// Stats accepts from/to (RFC 3339, default: the last 7 days), granularity
+// (hour, day, week), merchant_id, currency and top (number of rules)
+func (h *Handler) Stats(c *gin.Context) {
+	now := time.Now()
+	q := Query{
+		To:          now,
+		From:        now.Add(-7 * 24 * time.Hour),
+		Granularity: Granularity(c.DefaultQuery("granularity", string(Day))),
+		MerchantID:  c.Query("merchant_id"),
+		Currency:    strings.ToUpper(c.Query("currency")),
+	}
+
+	var err error
+	if v := c.Query("from"); v != "" {
+		if q.From, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if q.To, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	if q.TopRules, err = strconv.Atoi(c.DefaultQuery("top", "10")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "top must be a number"})
+		return
+	}
+	if err := q.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.repo.Stats(c.Request.Context(), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fraud stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,67 @@
+package config
+
+import "os"
+
+type Config struct {
+	DatabaseURL        string
+	RedisURL           string
+	NATSURL            string
+	JaegerEndpoint     string
+	Port               string
+	AmountLimits       string
+	VelocityDimensions string
+	VelocityWindows    string
+	VelocityLimits     string
+	VelocityPolicy     string
+	ReviewSLA          string
+	RiskScorer         string
+	RiskAmountUnits    string
+	RiskWeightsFile    string
+	RiskModelFile      string
+	RiskReviewScore    string
+	RiskDenyScore      string
+	KafkaBrokers       string
+	FeedbackTopic      string
+	ProfileCacheTTL    string
+	ShutdownTimeout    string
+	DrainDelay         string
+}
+
+func Load() *Config {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8083"
+	}
+
+	return &Config{
+		DatabaseURL:        os.Getenv("DATABASE_URL"),
+		RedisURL:           os.Getenv("REDIS_URL"),
+		NATSURL:            os.Getenv("NATS_URL"),
+		JaegerEndpoint:     os.Getenv("JAEGER_ENDPOINT"),
+		Port:               port,
+		AmountLimits:       getEnv("AMOUNT_LIMITS", "USD:10000,EUR:10000,GBP:10000,KZT:5000000,KGS:1000000,RUB:1000000,JPY:1500000,KWD:3000"),
+		VelocityDimensions: getEnv("VELOCITY_DIMENSIONS", "customer,merchant,payment_method,ip,device"),
+		VelocityWindows:    getEnv("VELOCITY_WINDOWS", "1m,1h,24h"),
+		VelocityLimits:     getEnv("VELOCITY_LIMITS", "customer:1h:count:5"),
+		VelocityPolicy:     getEnv("VELOCITY_FAILURE_POLICY", "open"),
+		ReviewSLA:          getEnv("REVIEW_SLA", "4h"),
+		RiskScorer:         getEnv("RISK_SCORER", "weighted"),
+		RiskAmountUnits:    getEnv("RISK_AMOUNT_UNITS", "USD:1,EUR:1,GBP:1,KZT:500,KGS:90,RUB:90,JPY:150,KWD:0.3"),
+		RiskWeightsFile:    os.Getenv("RISK_WEIGHTS_FILE"),
+		RiskModelFile:      os.Getenv("RISK_MODEL_FILE"),
+		RiskReviewScore:    getEnv("RISK_REVIEW_THRESHOLD", "80"),
+		RiskDenyScore:      getEnv("RISK_DENY_THRESHOLD", "95"),
+		KafkaBrokers:       os.Getenv("KAFKA_BROKERS"),
+		FeedbackTopic:      getEnv("FEEDBACK_TOPIC", "fraud.labels"),
+		ProfileCacheTTL:    getEnv("PROFILE_CACHE_TTL", "5m"),
+		ShutdownTimeout:    getEnv("SHUTDOWN_TIMEOUT", "10s"),
+		DrainDelay:         getEnv("DRAIN_DELAY", "0s"),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,172 @@
+package feedback
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type LabelType string
+
+const (
+	LabelChargeback     LabelType = "chargeback"
+	LabelConfirmedFraud LabelType = "confirmed_fraud"
+	LabelFalsePositive  LabelType = "false_positive"
+)
+
+// Fraudulent reports whether the label marks the payment as fraud
+func (t LabelType) Fraudulent() bool {
+	return t == LabelChargeback || t == LabelConfirmedFraud
+}
+
+var (
+	ErrUnknownPayment = errors.New("no fraud decision for payment")
+	ErrInvalidLabel   = errors.New("invalid label")
+)
+
+// Label is ground truth about a payment that was reported after the fraud
+// decision, e.g. a chargeback on an approved payment. It is linked to the
+// latest fraud decision for the payment at the time it was recorded.
+type Label struct {
+	ID         int64     `json:"id"`
+	PaymentID  string    `json:"payment_id"`
+	DecisionID int64     `json:"decision_id"`
+	Label      LabelType `json:"label"`
+	Source     string    `json:"source"`
+	Reason     string    `json:"reason,omitempty"`
+	ReportedAt time.Time `json:"reported_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Validate checks a label and fills in defaults
+func Validate(l *Label, defaultSource string, now time.Time) error {
+	l.PaymentID = strings.TrimSpace(l.PaymentID)
+	if l.PaymentID == "" {
+		return fmt.Errorf("%w: payment_id is required", ErrInvalidLabel)
+	}
+	switch l.Label {
+	case LabelChargeback, LabelConfirmedFraud, LabelFalsePositive:
+	default:
+		return fmt.Errorf("%w: label must be %q, %q or %q", ErrInvalidLabel,
+			LabelChargeback, LabelConfirmedFraud, LabelFalsePositive)
+	}
+	if l.Source == "" {
+		l.Source = defaultSource
+	}
+	if l.ReportedAt.IsZero() {
+		l.ReportedAt = now
+	}
+	return nil
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const labelColumns = `id, payment_id, decision_id, label, source, COALESCE(reason, ''), reported_at, created_at`
+
+func scanLabel(row interface{ Scan(...interface{}) error }) (*Label, error) {
+	var l Label
+	if err := row.Scan(&l.ID, &l.PaymentID, &l.DecisionID, &l.Label, &l.Source, &l.Reason, &l.ReportedAt, &l.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Record stores a label against the latest decision for the payment. A
+// repeated label of the same type for a payment replaces the earlier one, so
+// redelivered messages are harmless.
+func (r *Repository) Record(ctx context.Context, l *Label) (*Label, error) {
+	saved, err := scanLabel(r.db.QueryRowContext(ctx, `
+		INSERT INTO fraud_labels (payment_id, decision_id, label, source, reason, reported_at)
+		SELECT d.payment_id, d.id, $2, $3, $4, $5
+		FROM fraud_decisions d
+		WHERE d.payment_id = $1
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT 1
+		ON CONFLICT (payment_id, label)
+		DO UPDATE SET decision_id = EXCLUDED.decision_id, source = EXCLUDED.source,
+			reason = EXCLUDED.reason, reported_at = EXCLUDED.reported_at
+		RETURNING `+labelColumns,
+		l.PaymentID, l.Label, l.Source, l.Reason, l.ReportedAt))
+	if err == sql.ErrNoRows {
+		return nil, ErrUnknownPayment
+	}
+	return saved, err
+}
+
+func (r *Repository) ForPayment(ctx context.Context, paymentID string) ([]*Label, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+labelColumns+`
+		FROM fraud_labels
+		WHERE payment_id = $1
+		ORDER BY reported_at DESC, id DESC
+	`, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := []*Label{}
+	for rows.Next() {
+		l, err := scanLabel(rows)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, l)
+	}
+	return labels, rows.Err()
+}
+
+// outcomes returns the latest decision of every payment checked since the
+// given time together with its most recently reported label, if any
+func (r *Repository) outcomes(ctx context.Context, since time.Time) ([]Outcome, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (d.payment_id)
+			d.payment_id, d.decision, COALESCE(d.risk_score, 0), COALESCE(d.rules_triggered, '{}'), COALESCE(l.label, '')
+		FROM fraud_decisions d
+		LEFT JOIN LATERAL (
+			SELECT label FROM fraud_labels
+			WHERE payment_id = d.payment_id
+			ORDER BY reported_at DESC, id DESC
+			LIMIT 1
+		) l ON TRUE
+		WHERE d.created_at >= $1
+		ORDER BY d.payment_id, d.created_at DESC, d.id DESC
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outcomes []Outcome
+	for rows.Next() {
+		var o Outcome
+		if err := rows.Scan(&o.PaymentID, &o.Decision, &o.RiskScore, pq.Array(&o.Rules), &o.Label); err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, rows.Err()
+}
+
+// Performance builds the precision/recall report for decisions since the
+// given time
+func (r *Repository) Performance(ctx context.Context, since time.Time, bandWidth int, labeledOnly bool) (*Report, error) {
+	outcomes, err := r.outcomes(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	report := BuildReport(outcomes, bandWidth, labeledOnly)
+	report.Since = since
+	return report, nil
+}
@@ -0,0 +1,78 @@
+package feedback
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/pkg/platform/consumer"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	reported := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name         string
+		label        Label
+		wantErr      bool
+		wantSource   string
+		wantReported time.Time
+	}{
+		{"defaults", Label{PaymentID: " pay-1 ", Label: LabelChargeback}, false, "api", now},
+		{"kept values", Label{PaymentID: "pay-1", Label: LabelFalsePositive, Source: "acquirer", ReportedAt: reported}, false, "acquirer", reported},
+		{"missing payment", Label{PaymentID: "  ", Label: LabelChargeback}, true, "", time.Time{}},
+		{"unknown label", Label{PaymentID: "pay-1", Label: "suspicious"}, true, "", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := tt.label
+			err := Validate(&l, "api", now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidLabel) {
+					t.Fatalf("Validate error = %v, want ErrInvalidLabel", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.PaymentID != "pay-1" || l.Source != tt.wantSource || !l.ReportedAt.Equal(tt.wantReported) {
+				t.Fatalf("Validate = %+v", l)
+			}
+		})
+	}
+}
+
+func TestLabelFraudulent(t *testing.T) {
+	for label, want := range map[LabelType]bool{
+		LabelChargeback:     true,
+		LabelConfirmedFraud: true,
+		LabelFalsePositive:  false,
+		"":                  false,
+	} {
+		if got := label.Fraudulent(); got != want {
+			t.Fatalf("%q.Fraudulent() = %v, want %v", label, got, want)
+		}
+	}
+}
+
+func TestIngesterDeadLettersInvalidLabels(t *testing.T) {
+	// Invalid labels are rejected before the repository is used
+	ingester := NewIngester(nil, zap.NewNop())
+
+	for _, value := range []string{
+		`not json`,
+		`{"label": "chargeback"}`,
+		`{"payment_id": "pay-1", "label": "suspicious"}`,
+	} {
+		err := ingester.Handle(context.Background(), kafka.Message{Value: []byte(value)})
+		if !consumer.IsPermanent(err) {
+			t.Fatalf("Handle(%s) error = %v, want a permanent error", value, err)
+		}
+	}
+}
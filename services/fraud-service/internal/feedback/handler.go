@@ -0,0 +1,81 @@
+package feedback
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// Register mounts the label and model performance routes on the router
+func (h *Handler) Register(r gin.IRouter) {
+	r.POST("/fraud/labels", h.Create)
+	r.GET("/fraud/labels/:payment_id", h.ForPayment)
+	r.GET("/fraud/performance", h.Performance)
+}
+
+func (h *Handler) Create(c *gin.Context) {
+	var label Label
+	if err := c.ShouldBindJSON(&label); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := Validate(&label, "api", time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := h.repo.Record(c.Request.Context(), &label)
+	if err != nil {
+		if errors.Is(err, ErrUnknownPayment) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No fraud decision found for payment"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record label"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, saved)
+}
+
+func (h *Handler) ForPayment(c *gin.Context) {
+	labels, err := h.repo.ForPayment(c.Request.Context(), c.Param("payment_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch labels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+func (h *Handler) Performance(c *gin.Context) {
+	window, err := time.ParseDuration(c.DefaultQuery("since", "720h"))
+	if err != nil || window <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be a positive duration such as 720h"})
+		return
+	}
+	band, err := strconv.Atoi(c.DefaultQuery("band", "10"))
+	if err != nil || band <= 0 || band > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "band must be between 1 and 100"})
+		return
+	}
+	labeledOnly := c.Query("labeled_only") == "true"
+
+	report, err := h.repo.Performance(c.Request.Context(), time.Now().Add(-window), band, labeledOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build performance report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
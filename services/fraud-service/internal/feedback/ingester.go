@@ -0,0 +1,53 @@
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/pkg/platform/consumer"
+)
+
+// Ingester stores labels published to Kafka, e.g. chargebacks reported by
+// the acquirer. Messages use the same JSON shape as POST /fraud/labels.
+type Ingester struct {
+	repo   *Repository
+	logger *zap.Logger
+}
+
+func NewIngester(repo *Repository, logger *zap.Logger) *Ingester {
+	return &Ingester{repo: repo, logger: logger}
+}
+
+// Handle is the consumer.Handler of the labels topic. Malformed labels and
+// labels for unknown payments are dead-lettered at once; database errors
+// are retried, so outages delay ingestion instead of dropping labels.
+func (i *Ingester) Handle(ctx context.Context, msg kafka.Message) error {
+	var label Label
+	if err := json.Unmarshal(msg.Value, &label); err != nil {
+		return consumer.Permanent(fmt.Errorf("malformed fraud label: %w", err))
+	}
+	if err := Validate(&label, "kafka", time.Now()); err != nil {
+		return consumer.Permanent(fmt.Errorf("invalid fraud label for payment %s: %w", label.PaymentID, err))
+	}
+
+	saved, err := i.repo.Record(ctx, &label)
+	if errors.Is(err, ErrUnknownPayment) {
+		return consumer.Permanent(fmt.Errorf("fraud label for payment %s: %w", label.PaymentID, err))
+	}
+	if err != nil {
+		return fmt.Errorf("store fraud label for payment %s: %w", label.PaymentID, err)
+	}
+
+	i.logger.Info("Fraud label recorded",
+		zap.String("payment_id", saved.PaymentID),
+		zap.String("label", string(saved.Label)),
+		zap.Int64("decision_id", saved.DecisionID),
+	)
+	return nil
+}
@@ -0,0 +1,157 @@
+package feedback
+
+import (
+	"sort"
+	"time"
+)
+
+// Outcome is a fraud decision joined with its label. Label is empty when no
+// feedback has been reported.
+type Outcome struct {
+	PaymentID string
+	Decision  string
+	RiskScore int
+	Rules     []string
+	Label     LabelType
+}
+
+// Confusion counts how well a rule or threshold separates fraud from
+// legitimate payments. Precision and recall are 0 when undefined.
+type Confusion struct {
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+}
+
+func (c *Confusion) add(flagged, fraud bool) {
+	switch {
+	case flagged && fraud:
+		c.TruePositives++
+	case flagged:
+		c.FalsePositives++
+	case fraud:
+		c.FalseNegatives++
+	}
+}
+
+func (c *Confusion) finish() {
+	if flagged := c.TruePositives + c.FalsePositives; flagged > 0 {
+		c.Precision = float64(c.TruePositives) / float64(flagged)
+	}
+	if fraud := c.TruePositives + c.FalseNegatives; fraud > 0 {
+		c.Recall = float64(c.TruePositives) / float64(fraud)
+	}
+}
+
+// RuleStats treats a triggered rule as a fraud prediction
+type RuleStats struct {
+	Code      string `json:"code"`
+	Triggered int    `json:"triggered"`
+	Confusion
+}
+
+// BandStats describes payments scored in [MinScore, MaxScore]. Threshold is
+// the precision/recall of flagging every payment scoring MinScore or more,
+// i.e. of using MinScore as review or deny threshold.
+type BandStats struct {
+	MinScore  int       `json:"min_score"`
+	MaxScore  int       `json:"max_score"`
+	Decisions int       `json:"decisions"`
+	Fraud     int       `json:"fraud"`
+	FraudRate float64   `json:"fraud_rate"`
+	Threshold Confusion `json:"threshold"`
+}
+
+type Report struct {
+	Since       time.Time   `json:"since"`
+	LabeledOnly bool        `json:"labeled_only"`
+	Decisions   int         `json:"decisions"`
+	Labeled     int         `json:"labeled"`
+	Fraud       int         `json:"fraud"`
+	Rules       []RuleStats `json:"rules"`
+	ScoreBands  []BandStats `json:"score_bands"`
+}
+
+// BuildReport computes per-rule and per-score-band precision/recall.
+// Payments labeled chargeback or confirmed_fraud count as fraud. Unlabeled
+// payments count as legitimate unless labeledOnly is set, in which case only
+// payments with feedback are considered.
+func BuildReport(outcomes []Outcome, bandWidth int, labeledOnly bool) *Report {
+	if bandWidth <= 0 || bandWidth > 100 {
+		bandWidth = 10
+	}
+
+	report := &Report{LabeledOnly: labeledOnly, Rules: []RuleStats{}, ScoreBands: []BandStats{}}
+
+	var considered []Outcome
+	for _, o := range outcomes {
+		if o.Label != "" {
+			report.Labeled++
+		} else if labeledOnly {
+			continue
+		}
+		if o.Label.Fraudulent() {
+			report.Fraud++
+		}
+		considered = append(considered, o)
+	}
+	report.Decisions = len(considered)
+
+	// Per rule
+	rules := make(map[string]*RuleStats)
+	for _, o := range considered {
+		for _, code := range o.Rules {
+			if rules[code] == nil {
+				rules[code] = &RuleStats{Code: code}
+			}
+		}
+	}
+	for _, o := range considered {
+		triggered := make(map[string]bool, len(o.Rules))
+		for _, code := range o.Rules {
+			triggered[code] = true
+		}
+		for code, stats := range rules {
+			if triggered[code] {
+				stats.Triggered++
+			}
+			stats.add(triggered[code], o.Label.Fraudulent())
+		}
+	}
+	for _, stats := range rules {
+		stats.finish()
+		report.Rules = append(report.Rules, *stats)
+	}
+	sort.Slice(report.Rules, func(i, j int) bool { return report.Rules[i].Code < report.Rules[j].Code })
+
+	// Per score band
+	for min := 0; min <= 100; min += bandWidth {
+		// The last band is closed at 100 so a perfect score is not left alone
+		band := BandStats{MinScore: min, MaxScore: min + bandWidth - 1}
+		if band.MaxScore >= 99 {
+			band.MaxScore = 100
+		}
+		for _, o := range considered {
+			fraud := o.Label.Fraudulent()
+			if o.RiskScore >= band.MinScore && o.RiskScore <= band.MaxScore {
+				band.Decisions++
+				if fraud {
+					band.Fraud++
+				}
+			}
+			band.Threshold.add(o.RiskScore >= band.MinScore, fraud)
+		}
+		if band.Decisions > 0 {
+			band.FraudRate = float64(band.Fraud) / float64(band.Decisions)
+		}
+		band.Threshold.finish()
+		report.ScoreBands = append(report.ScoreBands, band)
+		if band.MaxScore == 100 {
+			break
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,107 @@
+package feedback
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testOutcomes = []Outcome{
+	{PaymentID: "p1", RiskScore: 90, Rules: []string{"A"}, Label: LabelChargeback},
+	{PaymentID: "p2", RiskScore: 85, Rules: []string{"A", "B"}, Label: LabelFalsePositive},
+	{PaymentID: "p3", RiskScore: 10, Label: LabelConfirmedFraud},
+	{PaymentID: "p4", RiskScore: 5, Rules: []string{"B"}},
+	{PaymentID: "p5", RiskScore: 100},
+}
+
+func TestBuildReportRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		labeledOnly bool
+		decisions   int
+		fraud       int
+		want        []RuleStats
+	}{
+		{
+			// Unlabeled payments count as legitimate
+			name:      "all decisions",
+			decisions: 5,
+			fraud:     2,
+			want: []RuleStats{
+				{Code: "A", Triggered: 2, Confusion: Confusion{TruePositives: 1, FalsePositives: 1, FalseNegatives: 1, Precision: 0.5, Recall: 0.5}},
+				{Code: "B", Triggered: 2, Confusion: Confusion{FalsePositives: 2, FalseNegatives: 2}},
+			},
+		},
+		{
+			name:        "labeled only",
+			labeledOnly: true,
+			decisions:   3,
+			fraud:       2,
+			want: []RuleStats{
+				{Code: "A", Triggered: 2, Confusion: Confusion{TruePositives: 1, FalsePositives: 1, FalseNegatives: 1, Precision: 0.5, Recall: 0.5}},
+				{Code: "B", Triggered: 1, Confusion: Confusion{FalsePositives: 1, FalseNegatives: 2}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := BuildReport(testOutcomes, 50, tt.labeledOnly)
+			if report.Decisions != tt.decisions || report.Labeled != 3 || report.Fraud != tt.fraud {
+				t.Fatalf("report counts = %d decisions, %d labeled, %d fraud; want %d, 3, %d",
+					report.Decisions, report.Labeled, report.Fraud, tt.decisions, tt.fraud)
+			}
+			if !reflect.DeepEqual(report.Rules, tt.want) {
+				t.Fatalf("rules = %+v, want %+v", report.Rules, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildReportScoreBands(t *testing.T) {
+	report := BuildReport(testOutcomes, 50, false)
+	want := []BandStats{
+		{
+			MinScore: 0, MaxScore: 49, Decisions: 2, Fraud: 1, FraudRate: 0.5,
+			// Flagging everything catches all fraud
+			Threshold: Confusion{TruePositives: 2, FalsePositives: 3, Precision: 0.4, Recall: 1},
+		},
+		{
+			// The last band is closed at 100
+			MinScore: 50, MaxScore: 100, Decisions: 3, Fraud: 1, FraudRate: 1.0 / 3,
+			Threshold: Confusion{TruePositives: 1, FalsePositives: 2, FalseNegatives: 1, Precision: 1.0 / 3, Recall: 0.5},
+		},
+	}
+	if !reflect.DeepEqual(report.ScoreBands, want) {
+		t.Fatalf("score bands = %+v, want %+v", report.ScoreBands, want)
+	}
+}
+
+func TestBuildReportBandWidths(t *testing.T) {
+	tests := []struct {
+		width    int
+		wantMins []int
+	}{
+		{100, []int{0}},
+		{30, []int{0, 30, 60, 90}},
+		// Out-of-range widths fall back to 10
+		{0, []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90}},
+		{101, []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90}},
+	}
+	for _, tt := range tests {
+		report := BuildReport(nil, tt.width, false)
+		var mins []int
+		for _, band := range report.ScoreBands {
+			mins = append(mins, band.MinScore)
+		}
+		if !reflect.DeepEqual(mins, tt.wantMins) {
+			t.Fatalf("width %d: bands start at %v, want %v", tt.width, mins, tt.wantMins)
+		}
+		if last := report.ScoreBands[len(report.ScoreBands)-1]; last.MaxScore != 100 {
+			t.Fatalf("width %d: last band ends at %d, want 100", tt.width, last.MaxScore)
+		}
+	}
+
+	// An empty report serializes rules as [] rather than null
+	if report := BuildReport(nil, 10, true); report.Rules == nil || report.Decisions != 0 {
+		t.Fatalf("empty report = %+v", report)
+	}
+}
@@ -0,0 +1,108 @@
+package lists
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	manager *Manager
+}
+
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+type createEntryRequest struct {
+	ListType   ListType   `json:"list_type" binding:"required"`
+	EntityType EntityType `json:"entity_type" binding:"required"`
+	Value      string     `json:"value" binding:"required"`
+	Reason     string     `json:"reason"`
+	CreatedBy  string     `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	// TTL is an alternative to ExpiresAt, e.g. "72h"
+	TTL string `json:"ttl"`
+}
+
+// Register mounts the list management routes on the router
+func (h *Handler) Register(r gin.IRouter) {
+	lists := r.Group("/fraud/lists")
+	{
+		lists.GET("", h.List)
+		lists.POST("", h.Create)
+		lists.DELETE("/:id", h.Delete)
+	}
+}
+
+func (h *Handler) List(c *gin.Context) {
+	entries, err := h.manager.List(c.Request.Context(),
+		ListType(c.Query("list_type")), EntityType(c.Query("entity_type")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch list entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+func (h *Handler) Create(c *gin.Context) {
+	var req createEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry := &Entry{
+		ListType:   req.ListType,
+		EntityType: req.EntityType,
+		Value:      req.Value,
+		Reason:     req.Reason,
+		CreatedBy:  req.CreatedBy,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be a positive duration such as 72h"})
+			return
+		}
+		expiresAt := time.Now().Add(ttl)
+		entry.ExpiresAt = &expiresAt
+	}
+
+	if err := Normalize(entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.manager.Add(c.Request.Context(), entry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create list entry"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *Handler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid list entry id"})
+		return
+	}
+
+	if err := h.manager.Remove(c.Request.Context(), id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete list entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted", "id": id})
+}
@@ -0,0 +1,198 @@
+package lists
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ListType string
+
+const (
+	ListBlock ListType = "block"
+	ListAllow ListType = "allow"
+)
+
+type EntityType string
+
+const (
+	EntityCountry     EntityType = "country"
+	EntityCustomer    EntityType = "customer"
+	EntityEmail       EntityType = "email"
+	EntityEmailDomain EntityType = "email_domain"
+	EntityIP          EntityType = "ip"
+	EntityBIN         EntityType = "bin"
+)
+
+// Entry is a single blocklist or allowlist item. IP entries may be a single
+// address or a CIDR range. A nil ExpiresAt never expires.
+type Entry struct {
+	ID         int64      `json:"id"`
+	ListType   ListType   `json:"list_type"`
+	EntityType EntityType `json:"entity_type"`
+	Value      string     `json:"value"`
+	Reason     string     `json:"reason,omitempty"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (e *Entry) expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !e.ExpiresAt.After(now)
+}
+
+var (
+	countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	binPattern     = regexp.MustCompile(`^[0-9]{6,8}$`)
+)
+
+// Normalize validates an entry and brings its value into canonical form
+func Normalize(e *Entry) error {
+	if e.ListType != ListBlock && e.ListType != ListAllow {
+		return fmt.Errorf("list_type must be %q or %q", ListBlock, ListAllow)
+	}
+
+	value := strings.TrimSpace(e.Value)
+	switch e.EntityType {
+	case EntityCountry:
+		value = strings.ToUpper(value)
+		if !countryPattern.MatchString(value) {
+			return fmt.Errorf("country must be an ISO 3166-1 alpha-2 code")
+		}
+	case EntityCustomer:
+		if value == "" {
+			return fmt.Errorf("customer id must not be empty")
+		}
+	case EntityEmail:
+		value = strings.ToLower(value)
+		if strings.Count(value, "@") != 1 || strings.HasPrefix(value, "@") || strings.HasSuffix(value, "@") {
+			return fmt.Errorf("email must be a single address")
+		}
+	case EntityEmailDomain:
+		value = strings.ToLower(strings.TrimPrefix(value, "@"))
+		if value == "" || strings.Contains(value, "@") {
+			return fmt.Errorf("email_domain must be a domain such as example.com")
+		}
+	case EntityIP:
+		if ip := net.ParseIP(value); ip != nil {
+			value = ip.String()
+		} else if _, network, err := net.ParseCIDR(value); err == nil {
+			value = network.String()
+		} else {
+			return fmt.Errorf("ip must be an IP address or CIDR range")
+		}
+	case EntityBIN:
+		if !binPattern.MatchString(value) {
+			return fmt.Errorf("bin must be 6 to 8 digits")
+		}
+	default:
+		return fmt.Errorf("unknown entity_type %q", e.EntityType)
+	}
+
+	e.Value = value
+	return nil
+}
+
+// Subject is the set of payment attributes checked against the lists
+type Subject struct {
+	Country     string
+	CustomerID  string
+	Email       string
+	EmailDomain string
+	IP          string
+	BIN         string
+}
+
+// Matcher is an in-memory snapshot of all active entries
+type Matcher struct {
+	mu     sync.RWMutex
+	exact  map[ListType]map[EntityType]map[string]*Entry
+	ranges map[ListType][]ipRange
+}
+
+type ipRange struct {
+	network *net.IPNet
+	entry   *Entry
+}
+
+type candidate struct {
+	entityType EntityType
+	value      string
+}
+
+func NewMatcher() *Matcher {
+	return &Matcher{}
+}
+
+// Replace swaps in a freshly loaded set of entries
+func (m *Matcher) Replace(entries []*Entry) {
+	exact := map[ListType]map[EntityType]map[string]*Entry{
+		ListBlock: {},
+		ListAllow: {},
+	}
+	ranges := map[ListType][]ipRange{}
+
+	for _, e := range entries {
+		if e.EntityType == EntityIP && strings.Contains(e.Value, "/") {
+			if _, network, err := net.ParseCIDR(e.Value); err == nil {
+				ranges[e.ListType] = append(ranges[e.ListType], ipRange{network: network, entry: e})
+			}
+			continue
+		}
+
+		byType := exact[e.ListType]
+		if byType[e.EntityType] == nil {
+			byType[e.EntityType] = make(map[string]*Entry)
+		}
+		byType[e.EntityType][e.Value] = e
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exact = exact
+	m.ranges = ranges
+}
+
+// Match returns the first entry of the given list that matches the subject,
+// or nil. BIN entries match any card number prefix of 6 to 8 digits.
+func (m *Matcher) Match(list ListType, s Subject, now time.Time) *Entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	candidates := []candidate{
+		{EntityCustomer, s.CustomerID},
+		{EntityEmail, strings.ToLower(s.Email)},
+		{EntityEmailDomain, strings.ToLower(s.EmailDomain)},
+		{EntityCountry, strings.ToUpper(s.Country)},
+	}
+	for n := 6; n <= 8 && n <= len(s.BIN); n++ {
+		candidates = append(candidates, candidate{EntityBIN, s.BIN[:n]})
+	}
+
+	ip := net.ParseIP(s.IP)
+	if ip != nil {
+		candidates = append(candidates, candidate{EntityIP, ip.String()})
+	}
+
+	for _, c := range candidates {
+		if c.value == "" {
+			continue
+		}
+		if e, ok := m.exact[list][c.entityType][c.value]; ok && !e.expired(now) {
+			return e
+		}
+	}
+
+	if ip != nil {
+		for _, r := range m.ranges[list] {
+			if r.network.Contains(ip) && !r.entry.expired(now) {
+				return r.entry
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package lists
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		entityType EntityType
+		value      string
+		want       string
+	}{
+		{EntityCountry, " kz ", "KZ"},
+		{EntityCustomer, "cust-001", "cust-001"},
+		{EntityEmail, "Fraud@Example.COM", "fraud@example.com"},
+		{EntityEmailDomain, "@Example.com", "example.com"},
+		{EntityIP, "10.0.0.1", "10.0.0.1"},
+		{EntityIP, "2001:0db8::0001", "2001:db8::1"},
+		{EntityIP, "10.1.2.3/16", "10.1.0.0/16"},
+		{EntityBIN, "400500", "400500"},
+		{EntityBIN, "40050012", "40050012"},
+	}
+	for _, tt := range tests {
+		e := &Entry{ListType: ListBlock, EntityType: tt.entityType, Value: tt.value}
+		if err := Normalize(e); err != nil {
+			t.Fatalf("Normalize(%s %q): %v", tt.entityType, tt.value, err)
+		}
+		if e.Value != tt.want {
+			t.Fatalf("Normalize(%s %q) = %q, want %q", tt.entityType, tt.value, e.Value, tt.want)
+		}
+	}
+
+	invalid := []Entry{
+		{ListType: "grey", EntityType: EntityCountry, Value: "KZ"},
+		{ListType: ListBlock, EntityType: "phone", Value: "+7"},
+		{ListType: ListBlock, EntityType: EntityCountry, Value: "KAZ"},
+		{ListType: ListBlock, EntityType: EntityCustomer, Value: "  "},
+		{ListType: ListBlock, EntityType: EntityEmail, Value: "a@b@c"},
+		{ListType: ListBlock, EntityType: EntityEmail, Value: "@example.com"},
+		{ListType: ListBlock, EntityType: EntityEmailDomain, Value: "user@example.com"},
+		{ListType: ListAllow, EntityType: EntityIP, Value: "10.0.0.300"},
+		{ListType: ListAllow, EntityType: EntityBIN, Value: "40050"},
+		{ListType: ListAllow, EntityType: EntityBIN, Value: "4005001234"},
+	}
+	for _, e := range invalid {
+		e := e
+		if err := Normalize(&e); err == nil {
+			t.Fatalf("Normalize(%s %s %q) succeeded", e.ListType, e.EntityType, e.Value)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	entry := func(list ListType, entityType EntityType, value string, expires *time.Time) *Entry {
+		return &Entry{ListType: list, EntityType: entityType, Value: value, ExpiresAt: expires}
+	}
+	blockedCountry := entry(ListBlock, EntityCountry, "KP", nil)
+	blockedDomain := entry(ListBlock, EntityEmailDomain, "spam.example", &future)
+	blockedBIN := entry(ListBlock, EntityBIN, "4005001", nil)
+	blockedRange := entry(ListBlock, EntityIP, "192.168.0.0/16", nil)
+	blockedIPv6 := entry(ListBlock, EntityIP, "2001:db8::1", nil)
+	expiredCustomer := entry(ListBlock, EntityCustomer, "cust-old", &past)
+	allowedCustomer := entry(ListAllow, EntityCustomer, "vip-1", nil)
+
+	m := NewMatcher()
+	if got := m.Match(ListBlock, Subject{Country: "KP"}, now); got != nil {
+		t.Fatalf("empty matcher matched %+v", got)
+	}
+	m.Replace([]*Entry{blockedCountry, blockedDomain, blockedBIN, blockedRange, blockedIPv6, expiredCustomer, allowedCustomer})
+
+	tests := []struct {
+		name    string
+		list    ListType
+		subject Subject
+		want    *Entry
+	}{
+		{"country, any case", ListBlock, Subject{Country: "kp"}, blockedCountry},
+		{"email domain, any case", ListBlock, Subject{EmailDomain: "SPAM.example"}, blockedDomain},
+		{"BIN prefix of the card", ListBlock, Subject{BIN: "40050012"}, blockedBIN},
+		{"BIN shorter than the entry", ListBlock, Subject{BIN: "400500"}, nil},
+		{"IP in a blocked range", ListBlock, Subject{IP: "192.168.10.20"}, blockedRange},
+		{"IP outside the range", ListBlock, Subject{IP: "192.169.0.1"}, nil},
+		{"IPv6 in another notation", ListBlock, Subject{IP: "2001:0db8:0:0::1"}, blockedIPv6},
+		{"expired entry", ListBlock, Subject{CustomerID: "cust-old"}, nil},
+		{"allowlist is separate", ListBlock, Subject{CustomerID: "vip-1"}, nil},
+		{"allowlisted customer", ListAllow, Subject{CustomerID: "vip-1", Country: "KP"}, allowedCustomer},
+		{"nothing matches", ListBlock, Subject{CustomerID: "cust-1", Country: "KZ", IP: "10.0.0.1", BIN: "411111"}, nil},
+		// Exact entries are checked before IP ranges
+		{"first match wins", ListBlock, Subject{Country: "KP", IP: "192.168.0.1"}, blockedCountry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Match(tt.list, tt.subject, now); got != tt.want {
+				t.Fatalf("Match = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Entries expire while the snapshot is in memory
+	if got := m.Match(ListBlock, Subject{EmailDomain: "spam.example"}, future); got != nil {
+		t.Fatalf("entry matched at its expiry: %+v", got)
+	}
+
+	// Replace drops entries that were removed
+	m.Replace([]*Entry{allowedCustomer})
+	if got := m.Match(ListBlock, Subject{Country: "KP"}, now); got != nil {
+		t.Fatalf("removed entry still matches: %+v", got)
+	}
+}
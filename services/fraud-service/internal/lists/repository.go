@@ -0,0 +1,196 @@
+package lists
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var ErrNotFound = errors.New("list entry not found")
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const entryColumns = `id, list_type, entity_type, value, COALESCE(reason, ''), COALESCE(created_by, ''), expires_at, created_at`
+
+func scanEntry(row interface{ Scan(...interface{}) error }) (*Entry, error) {
+	var e Entry
+	var expiresAt sql.NullTime
+	if err := row.Scan(&e.ID, &e.ListType, &e.EntityType, &e.Value, &e.Reason, &e.CreatedBy, &expiresAt, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		e.ExpiresAt = &expiresAt.Time
+	}
+	return &e, nil
+}
+
+// Create adds an entry, replacing reason and expiry of an identical one
+func (r *Repository) Create(ctx context.Context, e *Entry) (*Entry, error) {
+	return scanEntry(r.db.QueryRowContext(ctx, `
+		INSERT INTO fraud_list_entries (list_type, entity_type, value, reason, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (list_type, entity_type, value)
+		DO UPDATE SET reason = EXCLUDED.reason, created_by = EXCLUDED.created_by, expires_at = EXCLUDED.expires_at
+		RETURNING `+entryColumns,
+		e.ListType, e.EntityType, e.Value, e.Reason, e.CreatedBy, e.ExpiresAt))
+}
+
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM fraud_list_entries WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns active entries, optionally filtered by list and entity type
+func (r *Repository) List(ctx context.Context, listType ListType, entityType EntityType) ([]*Entry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+entryColumns+`
+		FROM fraud_list_entries
+		WHERE (expires_at IS NULL OR expires_at > NOW())
+			AND ($1 = '' OR list_type = $1)
+			AND ($2 = '' OR entity_type = $2)
+		ORDER BY created_at DESC
+	`, string(listType), string(entityType))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*Entry{}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ruleCountries loads the country lists configured on active fraud_rules,
+// so countries_blacklist / countries_whitelist are enforced as well
+func (r *Repository) ruleCountries(ctx context.Context) ([]*Entry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, COALESCE(countries_blacklist, '{}'), COALESCE(countries_whitelist, '{}')
+		FROM fraud_rules
+		WHERE active = TRUE
+			AND (countries_blacklist IS NOT NULL OR countries_whitelist IS NOT NULL)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*Entry
+	for rows.Next() {
+		var id int64
+		var name string
+		var blacklist, whitelist []string
+		if err := rows.Scan(&id, &name, pq.Array(&blacklist), pq.Array(&whitelist)); err != nil {
+			return nil, err
+		}
+		for listType, countries := range map[ListType][]string{ListBlock: blacklist, ListAllow: whitelist} {
+			for _, country := range countries {
+				e := &Entry{ListType: listType, EntityType: EntityCountry, Value: country, Reason: "fraud rule: " + name}
+				if Normalize(e) == nil {
+					entries = append(entries, e)
+				}
+			}
+		}
+	}
+	return entries, rows.Err()
+}
+
+// Manager keeps the in-memory matcher in sync with the database
+type Manager struct {
+	repo    *Repository
+	matcher *Matcher
+	logger  *zap.Logger
+}
+
+func NewManager(repo *Repository, logger *zap.Logger) *Manager {
+	return &Manager{repo: repo, matcher: NewMatcher(), logger: logger}
+}
+
+func (m *Manager) Matcher() *Matcher {
+	return m.matcher
+}
+
+// Refresh reloads every active entry into the matcher
+func (m *Manager) Refresh(ctx context.Context) error {
+	entries, err := m.repo.List(ctx, "", "")
+	if err != nil {
+		return err
+	}
+
+	ruleEntries, err := m.repo.ruleCountries(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.matcher.Replace(append(entries, ruleEntries...))
+	return nil
+}
+
+// Run refreshes the matcher periodically so entries added on other replicas
+// and expiries are picked up
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil {
+				m.logger.Error("Error refreshing fraud lists", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (m *Manager) Add(ctx context.Context, e *Entry) (*Entry, error) {
+	if err := Normalize(e); err != nil {
+		return nil, err
+	}
+	created, err := m.repo.Create(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	m.refreshAfterWrite(ctx)
+	return created, nil
+}
+
+func (m *Manager) Remove(ctx context.Context, id int64) error {
+	if err := m.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	m.refreshAfterWrite(ctx)
+	return nil
+}
+
+func (m *Manager) List(ctx context.Context, listType ListType, entityType EntityType) ([]*Entry, error) {
+	return m.repo.List(ctx, listType, entityType)
+}
+
+func (m *Manager) refreshAfterWrite(ctx context.Context) {
+	if err := m.Refresh(ctx); err != nil {
+		m.logger.Warn("Error refreshing fraud lists after update", zap.Error(err))
+	}
+}
@@ -0,0 +1,42 @@
+package profile
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Register mounts the customer profile routes on the router
+func (h *Handler) Register(r gin.IRouter) {
+	r.GET("/fraud/customers/:id/profile", h.Get)
+}
+
+// Get returns the cached profile with live velocity counters. Pass
+// refresh=true to bypass the cache.
+func (h *Handler) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	customerID := c.Param("id")
+
+	get := h.service.Get
+	if c.Query("refresh") == "true" {
+		get = h.service.Refresh
+	}
+
+	p, err := get(ctx, customerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load customer profile"})
+		return
+	}
+	h.service.WithVelocity(ctx, p, time.Now())
+
+	c.JSON(http.StatusOK, p)
+}
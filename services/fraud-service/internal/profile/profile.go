@@ -0,0 +1,213 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/akylbek/payment-system/fraud-service/internal/velocity"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// AmountDistribution summarises the amounts a customer has paid in one
+// currency. Avg and the percentiles are rounded to a minor unit.
+type AmountDistribution struct {
+	Payments int         `json:"payments"`
+	Total    money.Money `json:"total"`
+	Min      money.Money `json:"min"`
+	Max      money.Money `json:"max"`
+	Avg      money.Money `json:"avg"`
+	P50      money.Money `json:"p50"`
+	P90      money.Money `json:"p90"`
+	P99      money.Money `json:"p99"`
+}
+
+// Profile is the fraud history of a customer. Counts are per payment, using
+// the latest decision when a payment was checked more than once.
+type Profile struct {
+	CustomerID        string                        `json:"customer_id"`
+	LifetimePayments  int                           `json:"lifetime_payments"`
+	ApprovedCount     int                           `json:"approved_count"`
+	DeniedCount       int                           `json:"denied_count"`
+	ManualReviewCount int                           `json:"manual_review_count"`
+	ApproveRatio      float64                       `json:"approve_ratio"`
+	DenyRatio         float64                       `json:"deny_ratio"`
+	FirstSeen         *time.Time                    `json:"first_seen,omitempty"`
+	LastSeen          *time.Time                    `json:"last_seen,omitempty"`
+	Amounts           map[string]AmountDistribution `json:"amounts"`
+	DistinctMerchants int                           `json:"distinct_merchants"`
+	Velocity          map[string]velocity.Stats     `json:"velocity,omitempty"`
+	ComputedAt        time.Time                     `json:"computed_at"`
+}
+
+// AgeDays is how long ago the customer was first seen, 0 for new customers
+func (p *Profile) AgeDays(now time.Time) float64 {
+	if p.FirstSeen == nil {
+		return 0
+	}
+	return now.Sub(*p.FirstSeen).Hours() / 24
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Load aggregates the customer's fraud_decisions
+func (r *Repository) Load(ctx context.Context, customerID string) (*Profile, error) {
+	p := &Profile{CustomerID: customerID, Amounts: map[string]AmountDistribution{}, ComputedAt: time.Now()}
+	var firstSeen, lastSeen sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `
+		WITH payments AS (
+			SELECT DISTINCT ON (payment_id) payment_id, merchant_id, decision, created_at
+			FROM fraud_decisions
+			WHERE customer_id = $1
+			ORDER BY payment_id, created_at DESC, id DESC
+		)
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE decision = 'approve'),
+			COUNT(*) FILTER (WHERE decision = 'deny'),
+			COUNT(*) FILTER (WHERE decision = 'manual_review'),
+			MIN(created_at),
+			MAX(created_at),
+			COUNT(DISTINCT merchant_id)
+		FROM payments
+	`, customerID).Scan(&p.LifetimePayments, &p.ApprovedCount, &p.DeniedCount, &p.ManualReviewCount,
+		&firstSeen, &lastSeen, &p.DistinctMerchants)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadAmounts(ctx, p); err != nil {
+		return nil, err
+	}
+
+	if firstSeen.Valid {
+		p.FirstSeen = &firstSeen.Time
+	}
+	if lastSeen.Valid {
+		p.LastSeen = &lastSeen.Time
+	}
+	if p.LifetimePayments > 0 {
+		p.ApproveRatio = float64(p.ApprovedCount) / float64(p.LifetimePayments)
+		p.DenyRatio = float64(p.DeniedCount) / float64(p.LifetimePayments)
+	}
+	return p, nil
+}
+
+// loadAmounts fills the amount distribution of every currency the customer
+// paid in. Amounts of different currencies are never aggregated together.
+func (r *Repository) loadAmounts(ctx context.Context, p *Profile) error {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH payments AS (
+			SELECT DISTINCT ON (payment_id) payment_id, amount_minor, currency
+			FROM fraud_decisions
+			WHERE customer_id = $1
+			ORDER BY payment_id, created_at DESC, id DESC
+		)
+		SELECT
+			currency,
+			COUNT(*),
+			SUM(amount_minor),
+			MIN(amount_minor),
+			MAX(amount_minor),
+			ROUND(AVG(amount_minor))::BIGINT,
+			ROUND(percentile_cont(0.5) WITHIN GROUP (ORDER BY amount_minor))::BIGINT,
+			ROUND(percentile_cont(0.9) WITHIN GROUP (ORDER BY amount_minor))::BIGINT,
+			ROUND(percentile_cont(0.99) WITHIN GROUP (ORDER BY amount_minor))::BIGINT
+		FROM payments
+		WHERE currency IS NOT NULL AND amount_minor IS NOT NULL
+		GROUP BY currency
+	`, p.CustomerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var currency string
+		var d AmountDistribution
+		var total, lo, hi, avg, p50, p90, p99 int64
+		if err := rows.Scan(&currency, &d.Payments, &total, &lo, &hi, &avg, &p50, &p90, &p99); err != nil {
+			return err
+		}
+		amount := func(minor int64) money.Money {
+			return money.Money{Minor: minor, Currency: currency}
+		}
+		d.Total, d.Min, d.Max, d.Avg = amount(total), amount(lo), amount(hi), amount(avg)
+		d.P50, d.P90, d.P99 = amount(p50), amount(p90), amount(p99)
+		p.Amounts[currency] = d
+	}
+	return rows.Err()
+}
+
+// Service serves profiles from a Redis cache so checkFraud does not
+// aggregate the customer's history on every payment
+type Service struct {
+	repo    *Repository
+	client  *redis.Client
+	tracker *velocity.Tracker
+	ttl     time.Duration
+	logger  *zap.Logger
+}
+
+func NewService(repo *Repository, client *redis.Client, tracker *velocity.Tracker, ttl time.Duration, logger *zap.Logger) *Service {
+	return &Service{repo: repo, client: client, tracker: tracker, ttl: ttl, logger: logger}
+}
+
+func cacheKey(customerID string) string {
+	return "fraud:profile:" + customerID
+}
+
+// Get returns the cached profile, loading and caching it on a miss. Cache
+// errors are logged and fall back to the database.
+func (s *Service) Get(ctx context.Context, customerID string) (*Profile, error) {
+	data, err := s.client.Get(ctx, cacheKey(customerID)).Bytes()
+	if err == nil {
+		var p Profile
+		if err := json.Unmarshal(data, &p); err == nil {
+			return &p, nil
+		}
+	} else if err != redis.Nil {
+		s.logger.Warn("Error reading cached customer profile",
+			zap.String("customer_id", customerID), zap.Error(err))
+	}
+
+	return s.Refresh(ctx, customerID)
+}
+
+// Refresh reloads the profile from the database and replaces the cached copy
+func (s *Service) Refresh(ctx context.Context, customerID string) (*Profile, error) {
+	p, err := s.repo.Load(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(p)
+	if err := s.client.Set(ctx, cacheKey(customerID), data, s.ttl).Err(); err != nil {
+		s.logger.Warn("Error caching customer profile",
+			zap.String("customer_id", customerID), zap.Error(err))
+	}
+	return p, nil
+}
+
+// WithVelocity adds the customer's current velocity counters, which change
+// too quickly to be cached
+func (s *Service) WithVelocity(ctx context.Context, p *Profile, now time.Time) {
+	snapshot, _, err := s.tracker.Snapshot(ctx,
+		[]velocity.Entity{{Dimension: velocity.DimensionCustomer, ID: p.CustomerID}}, now)
+	if err != nil {
+		s.logger.Warn("Error reading customer velocity counters",
+			zap.String("customer_id", p.CustomerID), zap.Error(err))
+		return
+	}
+	p.Velocity = snapshot[velocity.DimensionCustomer]
+}
@@ -0,0 +1,107 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/fraud-service/internal/velocity"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func TestAgeDays(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		firstSeen *time.Time
+		want      float64
+	}{
+		{"new customer", nil, 0},
+		{"seen now", at(0), 0},
+		{"half a day", at(12 * time.Hour), 0.5},
+		{"ten days", at(240 * time.Hour), 10},
+	}
+	for _, tt := range tests {
+		p := &Profile{FirstSeen: tt.firstSeen}
+		if got := p.AgeDays(now); got != tt.want {
+			t.Fatalf("%s: AgeDays = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// newService returns a service without a database, so only cached profiles
+// can be served
+func newService(t *testing.T) (*Service, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	tracker := velocity.NewTracker(client, nil, zap.NewNop(), nil, []velocity.Dimension{velocity.DimensionCustomer})
+	return NewService(nil, client, tracker, time.Minute, zap.NewNop()), client
+}
+
+func TestServiceServesCachedProfiles(t *testing.T) {
+	service, client := newService(t)
+	ctx := context.Background()
+
+	cached := &Profile{
+		CustomerID:       "cust-1",
+		LifetimePayments: 4,
+		ApprovedCount:    3,
+		DeniedCount:      1,
+		Amounts: map[string]AmountDistribution{
+			"USD": {Payments: 4, Max: money.Money{Minor: 5000, Currency: "USD"}},
+		},
+	}
+	data, _ := json.Marshal(cached)
+	if err := client.Set(ctx, cacheKey("cust-1"), data, time.Minute).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := service.Get(ctx, "cust-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.LifetimePayments != 4 || p.DeniedCount != 1 || p.Amounts["USD"].Max.Minor != 5000 {
+		t.Fatalf("Get = %+v, want the cached profile", p)
+	}
+}
+
+func TestWithVelocity(t *testing.T) {
+	service, client := newService(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	// Counters as the tracker stores them: <payment>:<minor>:<currency>
+	// scored by the time of the payment
+	score := float64(now.Add(-time.Minute).UnixMilli())
+	if err := client.ZAdd(ctx, "fraud:velocity:customer:cust-1",
+		redis.Z{Score: score, Member: "p1:1000:USD"},
+		redis.Z{Score: score, Member: "p2:2500:USD"},
+	).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Profile{CustomerID: "cust-1"}
+	service.WithVelocity(ctx, p, now)
+	if got := p.Velocity["1h"]; got.Count != 2 || got.Amount("USD").Minor != 3500 {
+		t.Fatalf("1h velocity = %+v, want 2 payments of 3500 USD", got)
+	}
+
+	// Counters are per customer
+	other := &Profile{CustomerID: "cust-2"}
+	service.WithVelocity(ctx, other, now)
+	if got := other.Velocity["1h"]; got.Count != 0 {
+		t.Fatalf("velocity of another customer = %+v", got)
+	}
+}
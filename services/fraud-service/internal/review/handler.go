@@ -0,0 +1,141 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	queue *Queue
+}
+
+func NewHandler(queue *Queue) *Handler {
+	return &Handler{queue: queue}
+}
+
+type claimRequest struct {
+	Analyst string `json:"analyst" binding:"required"`
+}
+
+type decisionRequest struct {
+	Analyst string `json:"analyst" binding:"required"`
+	Notes   string `json:"notes"`
+}
+
+// Register mounts the review queue routes on the router
+func (h *Handler) Register(r gin.IRouter) {
+	reviews := r.Group("/fraud/reviews")
+	{
+		reviews.GET("", h.List)
+		reviews.GET("/:id", h.Get)
+		reviews.POST("/:id/claim", h.Claim)
+		reviews.POST("/:id/approve", h.Approve)
+		reviews.POST("/:id/reject", h.Reject)
+	}
+}
+
+func (h *Handler) List(c *gin.Context) {
+	status := Status(c.DefaultQuery("status", string(StatusPending)))
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	cases, err := h.queue.List(c.Request.Context(), status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review cases"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cases)
+}
+
+func (h *Handler) Get(c *gin.Context) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+
+	rc, err := h.queue.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rc)
+}
+
+func (h *Handler) Claim(c *gin.Context) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+
+	var req claimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rc, err := h.queue.Claim(c.Request.Context(), id, req.Analyst)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rc)
+}
+
+func (h *Handler) Approve(c *gin.Context) {
+	h.decide(c, h.queue.Approve)
+}
+
+func (h *Handler) Reject(c *gin.Context) {
+	h.decide(c, h.queue.Reject)
+}
+
+func (h *Handler) decide(c *gin.Context, decide func(ctx context.Context, id int64, analyst, notes string) (*Case, error)) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+
+	var req decisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rc, err := decide(c.Request.Context(), id, req.Analyst, req.Notes)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rc)
+}
+
+func caseID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review case id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review case not found"})
+	case errors.Is(err, ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review case"})
+	}
+}
@@ -0,0 +1,228 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+	"github.com/akylbek/payment-system/pkg/platform/natsclient"
+)
+
+const (
+	// StreamName is the JetStream stream review outcomes are published to
+	StreamName = "FRAUD_REVIEWS"
+	// DecidedSubject carries a Decision for every closed case
+	DecidedSubject = "fraud.review.decided"
+	// RequestStreamName is the work-queue stream of review requests
+	RequestStreamName = "FRAUD_REVIEW_REQUESTS"
+	// RequestedSubject carries a Request from the orchestrator when it sent
+	// a payment to review without a fraud check (fraud service unavailable)
+	RequestedSubject = "fraud.review.requested"
+)
+
+// Request opens a case for a payment the fraud service never checked
+type Request struct {
+	PaymentID  string        `json:"payment_id"`
+	CustomerID string        `json:"customer_id"`
+	Amount     money.Decimal `json:"amount"`
+	money.Money
+	Reason string `json:"reason"`
+}
+
+// legacyCurrency is assumed for requests sent without a currency by
+// orchestrators predating amount_minor, as by the payments table default
+const legacyCurrency = "USD"
+
+// amount returns the amount of the request. Requests without amount_minor
+// carry it in major units only.
+func (r *Request) amount() (money.Money, error) {
+	if r.Minor != 0 {
+		return r.Money, nil
+	}
+	currency := r.Currency
+	if currency == "" {
+		currency = legacyCurrency
+	}
+	return r.Amount.Money(currency)
+}
+
+// Queue ties the review repository to outcome delivery. Outcomes go through
+// JetStream so the orchestrator receives them even if it was down when the
+// analyst made the call; cases are marked published only after the stream
+// acknowledged the message and the sweeper retries anything left behind.
+type Queue struct {
+	repo   *Repository
+	js     nats.JetStreamContext
+	sla    time.Duration
+	logger *zap.Logger
+}
+
+func NewQueue(repo *Repository, js nats.JetStreamContext, sla time.Duration, logger *zap.Logger) *Queue {
+	return &Queue{repo: repo, js: js, sla: sla, logger: logger}
+}
+
+// EnsureStream creates the review outcome and request streams if they do not
+// exist yet
+func EnsureStream(js nats.JetStreamContext) error {
+	streams := []*nats.StreamConfig{
+		{
+			Name:     StreamName,
+			Subjects: []string{DecidedSubject},
+			Storage:  nats.FileStorage,
+		},
+		{
+			Name:      RequestStreamName,
+			Subjects:  []string{RequestedSubject},
+			Retention: nats.WorkQueuePolicy,
+			Storage:   nats.FileStorage,
+		},
+	}
+
+	return natsclient.EnsureStreams(js, streams...)
+}
+
+// HandleRequest is the JetStream handler for RequestedSubject. Enqueue is
+// idempotent per payment, so redelivered requests are safe.
+func (q *Queue) HandleRequest(msg *nats.Msg) {
+	var req Request
+	if err := json.Unmarshal(msg.Data, &req); err != nil || req.PaymentID == "" {
+		q.logger.Error("Error unmarshaling review request", zap.Error(err))
+		msg.Term()
+		return
+	}
+
+	amount, err := req.amount()
+	if err != nil {
+		q.logger.Error("Invalid review request amount", zap.String("payment_id", req.PaymentID), zap.Error(err))
+		msg.Term()
+		return
+	}
+
+	ctx, span := natsclient.StartHandlerSpan(context.Background(), msg)
+	defer span.End()
+
+	err = q.Enqueue(ctx, &Case{
+		PaymentID:  req.PaymentID,
+		CustomerID: req.CustomerID,
+		Money:      amount,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		q.logger.Error("Error opening requested review case",
+			zap.String("payment_id", req.PaymentID),
+			zap.Error(err),
+		)
+		msg.NakWithDelay(5 * time.Second)
+		return
+	}
+
+	q.logger.Info("Review case opened on request", zap.String("payment_id", req.PaymentID))
+	msg.Ack()
+}
+
+// Enqueue opens a review case for a payment flagged as manual_review
+func (q *Queue) Enqueue(ctx context.Context, c *Case) error {
+	c.SLADeadline = time.Now().Add(q.sla)
+	return q.repo.Create(ctx, c)
+}
+
+func (q *Queue) List(ctx context.Context, status Status, limit int) ([]*Case, error) {
+	return q.repo.List(ctx, status, limit)
+}
+
+func (q *Queue) Get(ctx context.Context, id int64) (*Case, error) {
+	return q.repo.Get(ctx, id)
+}
+
+func (q *Queue) Claim(ctx context.Context, id int64, analyst string) (*Case, error) {
+	return q.repo.Claim(ctx, id, analyst)
+}
+
+func (q *Queue) Approve(ctx context.Context, id int64, analyst, notes string) (*Case, error) {
+	return q.decide(ctx, id, analyst, StatusApproved, notes)
+}
+
+func (q *Queue) Reject(ctx context.Context, id int64, analyst, notes string) (*Case, error) {
+	return q.decide(ctx, id, analyst, StatusRejected, notes)
+}
+
+func (q *Queue) decide(ctx context.Context, id int64, analyst string, status Status, notes string) (*Case, error) {
+	c, err := q.repo.Decide(ctx, id, analyst, status, notes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delivery failures are retried by the sweeper
+	q.publish(ctx, c)
+	return c, nil
+}
+
+func (q *Queue) publish(ctx context.Context, c *Case) {
+	data, _ := json.Marshal(c.Decision())
+
+	// Msg-Id lets JetStream drop duplicates if the sweeper republishes
+	msg := natsclient.NewMsg(ctx, DecidedSubject, data)
+	msg.Header.Set(nats.MsgIdHdr, c.PaymentID)
+
+	if _, err := q.js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+		q.logger.Error("Error publishing review decision",
+			zap.Int64("case_id", c.ID),
+			zap.String("payment_id", c.PaymentID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if err := q.repo.MarkPublished(ctx, c.ID); err != nil {
+		q.logger.Error("Error marking review decision as published",
+			zap.Int64("case_id", c.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	q.logger.Info("Review decision published",
+		zap.Int64("case_id", c.ID),
+		zap.String("payment_id", c.PaymentID),
+		zap.String("status", string(c.Status)),
+	)
+}
+
+// Run expires cases past their SLA deadline (auto-decline) and redelivers
+// decisions that could not be published earlier
+func (q *Queue) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		expired, err := q.repo.ExpireOverdue(ctx, time.Now())
+		if err != nil {
+			q.logger.Error("Error expiring review cases", zap.Error(err))
+		}
+		for _, c := range expired {
+			q.logger.Warn("Review SLA expired, declining payment",
+				zap.Int64("case_id", c.ID),
+				zap.String("payment_id", c.PaymentID),
+			)
+		}
+
+		pending, err := q.repo.Unpublished(ctx, 100)
+		if err != nil {
+			q.logger.Error("Error loading unpublished review decisions", zap.Error(err))
+			continue
+		}
+		for _, c := range pending {
+			q.publish(ctx, c)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package review
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusClaimed  Status = "claimed"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+	StatusExpired  Status = "expired"
+)
+
+var (
+	ErrNotFound = errors.New("review case not found")
+	ErrConflict = errors.New("review case cannot be changed in its current state")
+)
+
+// Case is a payment waiting for (or decided by) an analyst
+type Case struct {
+	ID         int64         `json:"id"`
+	PaymentID  string        `json:"payment_id"`
+	CustomerID string        `json:"customer_id"`
+	Amount     money.Decimal `json:"amount"`
+	money.Money
+	Reason      string     `json:"reason"`
+	RiskScore   int        `json:"risk_score"`
+	Status      Status     `json:"status"`
+	AssignedTo  string     `json:"assigned_to,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	SLADeadline time.Time  `json:"sla_deadline"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ClaimedAt   *time.Time `json:"claimed_at,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+}
+
+// Decision is published once a case is approved, rejected or expired
+type Decision struct {
+	CaseID    int64     `json:"case_id"`
+	PaymentID string    `json:"payment_id"`
+	Decision  string    `json:"decision"` // approve, deny
+	Reason    string    `json:"reason"`
+	Analyst   string    `json:"analyst,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// Decision converts a decided case into the message sent to the orchestrator
+func (c *Case) Decision() Decision {
+	d := Decision{
+		CaseID:    c.ID,
+		PaymentID: c.PaymentID,
+		Decision:  "deny",
+		Analyst:   c.AssignedTo,
+	}
+	if c.DecidedAt != nil {
+		d.DecidedAt = *c.DecidedAt
+	}
+
+	switch c.Status {
+	case StatusApproved:
+		d.Decision = "approve"
+		d.Reason = "Approved by analyst"
+	case StatusRejected:
+		d.Reason = "Rejected by analyst"
+	case StatusExpired:
+		d.Reason = "Manual review SLA expired"
+	}
+	if c.Notes != "" {
+		d.Reason += ": " + c.Notes
+	}
+	return d
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const caseColumns = `id, payment_id, customer_id, amount_minor, COALESCE(currency, ''), reason, risk_score, status,
+	COALESCE(assigned_to, ''), COALESCE(notes, ''), sla_deadline, created_at, claimed_at, decided_at`
+
+func scanCase(row interface{ Scan(...interface{}) error }) (*Case, error) {
+	var c Case
+	var claimedAt, decidedAt sql.NullTime
+	err := row.Scan(&c.ID, &c.PaymentID, &c.CustomerID, &c.Minor, &c.Currency, &c.Reason, &c.RiskScore, &c.Status,
+		&c.AssignedTo, &c.Notes, &c.SLADeadline, &c.CreatedAt, &claimedAt, &decidedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.Amount = c.Money.Decimal()
+	if claimedAt.Valid {
+		c.ClaimedAt = &claimedAt.Time
+	}
+	if decidedAt.Valid {
+		c.DecidedAt = &decidedAt.Time
+	}
+	return &c, nil
+}
+
+// Create opens a case for a payment. Opening a case twice for the same
+// payment keeps the existing one.
+func (r *Repository) Create(ctx context.Context, c *Case) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO review_cases (payment_id, customer_id, amount, amount_minor, currency, reason, risk_score, status, sla_deadline)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (payment_id) DO NOTHING
+	`, c.PaymentID, c.CustomerID, c.Money.String(), c.Minor, c.Currency, c.Reason, c.RiskScore, StatusPending, c.SLADeadline)
+	return err
+}
+
+func (r *Repository) Get(ctx context.Context, id int64) (*Case, error) {
+	c, err := scanCase(r.db.QueryRowContext(ctx,
+		`SELECT `+caseColumns+` FROM review_cases WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return c, err
+}
+
+// List returns cases in the given status, oldest SLA deadline first
+func (r *Repository) List(ctx context.Context, status Status, limit int) ([]*Case, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+caseColumns+`
+		FROM review_cases
+		WHERE status = $1
+		ORDER BY sla_deadline ASC
+		LIMIT $2
+	`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cases := []*Case{}
+	for rows.Next() {
+		c, err := scanCase(rows)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, rows.Err()
+}
+
+// Claim assigns a pending case to an analyst. Re-claiming a case already
+// assigned to the same analyst is allowed.
+func (r *Repository) Claim(ctx context.Context, id int64, analyst string) (*Case, error) {
+	c, err := scanCase(r.db.QueryRowContext(ctx, `
+		UPDATE review_cases
+		SET status = $2, assigned_to = $3, claimed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND (status = $4 OR (status = $2 AND assigned_to = $3))
+		RETURNING `+caseColumns,
+		id, StatusClaimed, analyst, StatusPending))
+	if err == sql.ErrNoRows {
+		return nil, r.conflictOrNotFound(ctx, id)
+	}
+	return c, err
+}
+
+// Decide closes an open case. A claimed case can only be decided by the
+// analyst who claimed it.
+func (r *Repository) Decide(ctx context.Context, id int64, analyst string, status Status, notes string) (*Case, error) {
+	c, err := scanCase(r.db.QueryRowContext(ctx, `
+		UPDATE review_cases
+		SET status = $2, assigned_to = $3, notes = $4, decided_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND (status = $5 OR (status = $6 AND assigned_to = $3))
+		RETURNING `+caseColumns,
+		id, status, analyst, notes, StatusPending, StatusClaimed))
+	if err == sql.ErrNoRows {
+		return nil, r.conflictOrNotFound(ctx, id)
+	}
+	return c, err
+}
+
+// ExpireOverdue closes every open case whose SLA deadline has passed
+func (r *Repository) ExpireOverdue(ctx context.Context, now time.Time) ([]*Case, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE review_cases
+		SET status = $1, decided_at = NOW(), updated_at = NOW()
+		WHERE status IN ($2, $3) AND sla_deadline < $4
+		RETURNING `+caseColumns,
+		StatusExpired, StatusPending, StatusClaimed, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cases []*Case
+	for rows.Next() {
+		c, err := scanCase(rows)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, rows.Err()
+}
+
+// Unpublished returns decided cases whose outcome has not been delivered yet
+func (r *Repository) Unpublished(ctx context.Context, limit int) ([]*Case, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+caseColumns+`
+		FROM review_cases
+		WHERE status IN ($1, $2, $3) AND published = FALSE
+		ORDER BY decided_at ASC
+		LIMIT $4
+	`, StatusApproved, StatusRejected, StatusExpired, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cases []*Case
+	for rows.Next() {
+		c, err := scanCase(rows)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, rows.Err()
+}
+
+func (r *Repository) MarkPublished(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE review_cases SET published = TRUE, updated_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func (r *Repository) conflictOrNotFound(ctx context.Context, id int64) error {
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrConflict
+}
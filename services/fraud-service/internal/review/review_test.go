@@ -0,0 +1,133 @@
+package review
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func TestCaseDecision(t *testing.T) {
+	decided := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		c            Case
+		wantDecision string
+		wantReason   string
+	}{
+		{"approved", Case{Status: StatusApproved}, "approve", "Approved by analyst"},
+		{"rejected", Case{Status: StatusRejected}, "deny", "Rejected by analyst"},
+		{"expired", Case{Status: StatusExpired}, "deny", "Manual review SLA expired"},
+		{"notes are appended", Case{Status: StatusRejected, Notes: "stolen card"}, "deny", "Rejected by analyst: stolen card"},
+		// An open case never approves a payment
+		{"open case", Case{Status: StatusClaimed}, "deny", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.c.ID = 7
+			tt.c.PaymentID = "pay-1"
+			tt.c.AssignedTo = "alice"
+			tt.c.DecidedAt = &decided
+
+			d := tt.c.Decision()
+			if d.Decision != tt.wantDecision || d.Reason != tt.wantReason {
+				t.Fatalf("Decision() = %q, %q; want %q, %q", d.Decision, d.Reason, tt.wantDecision, tt.wantReason)
+			}
+			if d.CaseID != 7 || d.PaymentID != "pay-1" || d.Analyst != "alice" || !d.DecidedAt.Equal(decided) {
+				t.Fatalf("Decision() = %+v", d)
+			}
+		})
+	}
+
+	if d := (&Case{Status: StatusExpired}).Decision(); !d.DecidedAt.IsZero() {
+		t.Fatalf("undecided case has decided_at %v", d.DecidedAt)
+	}
+}
+
+func TestHandlerRejectsInvalidRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Requests rejected before reaching the queue never touch the database
+	NewHandler(NewQueue(nil, nil, time.Hour, nil)).Register(router)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/fraud/reviews?limit=0", ""},
+		{http.MethodGet, "/fraud/reviews?limit=501", ""},
+		{http.MethodGet, "/fraud/reviews?limit=ten", ""},
+		{http.MethodGet, "/fraud/reviews/abc", ""},
+		{http.MethodPost, "/fraud/reviews/abc/claim", `{"analyst": "alice"}`},
+		{http.MethodPost, "/fraud/reviews/1/claim", `{}`},
+		{http.MethodPost, "/fraud/reviews/1/approve", `{"notes": "ok"}`},
+		{http.MethodPost, "/fraud/reviews/1/reject", `not json`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s %s %s: status %d, want 400", tt.method, tt.path, tt.body, w.Code)
+		}
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{ErrNotFound, http.StatusNotFound},
+		{fmt.Errorf("claim case 1: %w", ErrConflict), http.StatusConflict},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		respondError(c, tt.err)
+		if w.Code != tt.want {
+			t.Fatalf("respondError(%v) status = %d, want %d", tt.err, w.Code, tt.want)
+		}
+	}
+}
+
+func TestRequestMoney(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    money.Money
+		wantErr bool
+	}{
+		{`{"payment_id": "p", "amount": 100.1, "amount_minor": 10010, "currency": "USD"}`, money.Money{Minor: 10010, Currency: "USD"}, false},
+		{`{"payment_id": "p", "amount": 1500, "amount_minor": 1500, "currency": "JPY"}`, money.Money{Minor: 1500, Currency: "JPY"}, false},
+		// Orchestrators predating amount_minor send major units, and no
+		// currency before currencies were supported
+		{`{"payment_id": "p", "amount": 1.25, "currency": "KWD"}`, money.Money{Minor: 1250, Currency: "KWD"}, false},
+		{`{"payment_id": "p", "amount": 100.1}`, money.Money{Minor: 10010, Currency: "USD"}, false},
+		{`{"payment_id": "p", "amount": 10.5, "currency": "JPY"}`, money.Money{}, true},
+		{`{"payment_id": "p", "amount": 1, "currency": "XXX"}`, money.Money{}, true},
+	}
+	for _, tt := range tests {
+		var req Request
+		if err := json.Unmarshal([]byte(tt.data), &req); err != nil {
+			t.Fatal(err)
+		}
+		got, err := req.amount()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Fatalf("%s: amount() = %v %s, %v; want %v %s", tt.data, got, got.Currency, err, tt.want, tt.want.Currency)
+		}
+	}
+}
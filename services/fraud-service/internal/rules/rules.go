@@ -0,0 +1,204 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/akylbek/payment-system/fraud-service/internal/lists"
+	"github.com/akylbek/payment-system/fraud-service/internal/scoring"
+	"github.com/akylbek/payment-system/fraud-service/internal/velocity"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// Stable reason codes. Velocity and score feature codes are derived from the
+// configuration, e.g. VELOCITY_CUSTOMER_1H_COUNT or SCORE_AMOUNT_OVER_5000.
+const (
+	CodeAmountLimit         = "AMOUNT_LIMIT"
+	CodeVelocityUnavailable = "VELOCITY_UNAVAILABLE"
+	CodeRiskScoreDeny       = "RISK_SCORE_DENY"
+	CodeRiskScoreReview     = "RISK_SCORE_REVIEW"
+	CodeReviewUnavailable   = "MANUAL_REVIEW_UNAVAILABLE"
+)
+
+const (
+	ActionApprove      = "approve"
+	ActionDeny         = "deny"
+	ActionManualReview = "manual_review"
+)
+
+type Outcome string
+
+const (
+	OutcomePass      Outcome = "pass"
+	OutcomeTriggered Outcome = "triggered"
+	OutcomeSkipped   Outcome = "skipped"
+)
+
+// Result is the outcome of a single evaluated rule. Action is the decision
+// the rule asks for when triggered.
+type Result struct {
+	Code              string  `json:"code"`
+	Name              string  `json:"name"`
+	Outcome           Outcome `json:"outcome"`
+	Action            string  `json:"action,omitempty"`
+	ScoreContribution int     `json:"score_contribution"`
+	Detail            string  `json:"detail,omitempty"`
+}
+
+// Decide picks the strictest action among triggered rules. The reason is
+// the detail of the first rule that asked for that action.
+func Decide(results []Result) (decision, reason string) {
+	for _, action := range []string{ActionDeny, ActionManualReview} {
+		for _, r := range results {
+			if r.Outcome == OutcomeTriggered && r.Action == action {
+				return action, r.Detail
+			}
+		}
+	}
+	return ActionApprove, "All fraud checks passed"
+}
+
+// Triggered returns the codes of all triggered rules
+func Triggered(results []Result) []string {
+	codes := []string{}
+	for _, r := range results {
+		if r.Outcome == OutcomeTriggered {
+			codes = append(codes, r.Code)
+		}
+	}
+	return codes
+}
+
+// AmountLimit denies payments above the limit of their currency. Payments
+// in a currency without a limit skip the rule.
+func AmountLimit(amount money.Money, limits money.Limits) Result {
+	r := Result{Code: CodeAmountLimit, Name: "High Amount Check", Outcome: OutcomePass}
+	limit, ok := limits.Get(amount.Currency)
+	if !ok {
+		r.Outcome = OutcomeSkipped
+		r.Detail = fmt.Sprintf("No amount limit for %s", amount.Currency)
+		return r
+	}
+	if amount.Minor > limit.Minor {
+		r.Outcome = OutcomeTriggered
+		r.Action = ActionDeny
+		r.Detail = fmt.Sprintf("Amount exceeds %s %s limit", limit, limit.Currency)
+	}
+	return r
+}
+
+// Velocity reports one result per configured limit. When the counters could
+// not be read every limit is skipped; with a fail-closed policy an extra
+// VELOCITY_UNAVAILABLE rule denies the payment.
+func Velocity(snapshot velocity.Snapshot, limits []velocity.Limit, amount money.Money, available, failClosed bool) []Result {
+	results := make([]Result, 0, len(limits)+1)
+
+	if !available {
+		r := Result{Code: CodeVelocityUnavailable, Name: "Velocity Counters Available", Outcome: OutcomeSkipped}
+		if failClosed {
+			r.Outcome = OutcomeTriggered
+			r.Action = ActionDeny
+			r.Detail = "Velocity check unavailable"
+		}
+		results = append(results, r)
+	}
+
+	violated := make(map[velocity.Limit]velocity.Violation)
+	if available {
+		for _, v := range velocity.Evaluate(snapshot, limits, amount) {
+			violated[v.Limit] = v
+		}
+	}
+
+	for _, l := range limits {
+		r := Result{
+			Code:    strings.ToUpper(fmt.Sprintf("VELOCITY_%s_%s_COUNT", l.Dimension, l.Window)),
+			Name:    fmt.Sprintf("Velocity count %s per %s", l.Dimension, l.Window),
+			Outcome: OutcomePass,
+		}
+		if l.MaxCount == 0 {
+			currency := l.MaxAmount.Currency
+			r.Code = strings.ToUpper(fmt.Sprintf("VELOCITY_%s_%s_AMOUNT_%s", l.Dimension, l.Window, currency))
+			r.Name = fmt.Sprintf("Velocity amount %s per %s in %s", l.Dimension, l.Window, currency)
+		}
+		if !available || !l.Applies(amount) {
+			r.Outcome = OutcomeSkipped
+		} else if v, ok := violated[l]; ok {
+			r.Outcome = OutcomeTriggered
+			r.Action = ActionDeny
+			r.Detail = v.String()
+		}
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// Score reports how much each feature contributed to the risk score and
+// whether the score crossed the review or deny thresholds
+func Score(scorer scoring.RiskScorer, features scoring.Features, score, reviewThreshold, denyThreshold int) []Result {
+	contributions := scoring.Contributions(scorer, features, score)
+
+	names := make([]string, 0, len(contributions))
+	for name := range contributions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]Result, 0, len(names)+2)
+	for _, name := range names {
+		results = append(results, Result{
+			Code:              "SCORE_" + strings.ToUpper(name),
+			Name:              "Risk feature " + name,
+			Outcome:           OutcomePass,
+			ScoreContribution: contributions[name],
+		})
+	}
+
+	deny := Result{Code: CodeRiskScoreDeny, Name: "Risk Score Deny Threshold", Outcome: OutcomePass, ScoreContribution: score}
+	review := Result{Code: CodeRiskScoreReview, Name: "Risk Score Review Threshold", Outcome: OutcomePass, ScoreContribution: score}
+	switch {
+	case score >= denyThreshold:
+		deny.Outcome = OutcomeTriggered
+		deny.Action = ActionDeny
+		deny.Detail = fmt.Sprintf("Risk score %d exceeds deny threshold %d", score, denyThreshold)
+	case score >= reviewThreshold:
+		review.Outcome = OutcomeTriggered
+		review.Action = ActionManualReview
+		review.Detail = fmt.Sprintf("Risk score %d requires manual review", score)
+	}
+
+	return append(results, deny, review)
+}
+
+// ListMatch reports a blocklist (deny) or allowlist (approve) hit, e.g.
+// LIST_BLOCK_COUNTRY or LIST_ALLOW_CUSTOMER
+func ListMatch(entry *lists.Entry) Result {
+	r := Result{
+		Code:    strings.ToUpper(fmt.Sprintf("LIST_%s_%s", entry.ListType, entry.EntityType)),
+		Name:    fmt.Sprintf("%s list %s", entry.ListType, entry.EntityType),
+		Outcome: OutcomeTriggered,
+		Action:  ActionDeny,
+		Detail:  fmt.Sprintf("%s %s is blocklisted", entry.EntityType, entry.Value),
+	}
+	if entry.ListType == lists.ListAllow {
+		r.Action = ActionApprove
+		r.Detail = fmt.Sprintf("%s %s is allowlisted", entry.EntityType, entry.Value)
+	}
+	if entry.Reason != "" {
+		r.Detail += ": " + entry.Reason
+	}
+	return r
+}
+
+// ReviewUnavailable denies a payment whose review case could not be opened
+func ReviewUnavailable() Result {
+	return Result{
+		Code:    CodeReviewUnavailable,
+		Name:    "Manual Review Queue Available",
+		Outcome: OutcomeTriggered,
+		Action:  ActionDeny,
+		Detail:  "Manual review unavailable",
+	}
+}
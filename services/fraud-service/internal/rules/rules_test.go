@@ -0,0 +1,246 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/akylbek/payment-system/fraud-service/internal/lists"
+	"github.com/akylbek/payment-system/fraud-service/internal/scoring"
+	"github.com/akylbek/payment-system/fraud-service/internal/velocity"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func triggered(code, action, detail string) Result {
+	return Result{Code: code, Outcome: OutcomeTriggered, Action: action, Detail: detail}
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name         string
+		results      []Result
+		wantDecision string
+		wantReason   string
+	}{
+		{"nothing triggered", []Result{{Code: "A", Outcome: OutcomePass}}, ActionApprove, "All fraud checks passed"},
+		{"no rules", nil, ActionApprove, "All fraud checks passed"},
+		{"review", []Result{triggered("A", ActionManualReview, "review me")}, ActionManualReview, "review me"},
+		{
+			"deny beats an earlier review",
+			[]Result{triggered("A", ActionManualReview, "review me"), triggered("B", ActionDeny, "deny me")},
+			ActionDeny, "deny me",
+		},
+		{
+			"first deny gives the reason",
+			[]Result{triggered("A", ActionDeny, "first"), triggered("B", ActionDeny, "second")},
+			ActionDeny, "first",
+		},
+		{
+			"skipped and passing rules with actions are ignored",
+			[]Result{{Code: "A", Outcome: OutcomeSkipped, Action: ActionDeny}, {Code: "B", Outcome: OutcomePass, Action: ActionDeny}},
+			ActionApprove, "All fraud checks passed",
+		},
+		// An allowlist hit asks for approve, which never overrides a deny
+		{
+			"triggered approve",
+			[]Result{triggered("LIST_ALLOW_CUSTOMER", ActionApprove, "allowlisted")},
+			ActionApprove, "All fraud checks passed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, reason := Decide(tt.results)
+			if decision != tt.wantDecision || reason != tt.wantReason {
+				t.Fatalf("Decide = %q, %q; want %q, %q", decision, reason, tt.wantDecision, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestTriggered(t *testing.T) {
+	results := []Result{
+		triggered("A", ActionDeny, ""),
+		{Code: "B", Outcome: OutcomePass},
+		{Code: "C", Outcome: OutcomeSkipped},
+		triggered("D", ActionManualReview, ""),
+	}
+	if got := Triggered(results); !reflect.DeepEqual(got, []string{"A", "D"}) {
+		t.Fatalf("Triggered = %v", got)
+	}
+	// Stored as a non-null array
+	if got := Triggered(nil); got == nil || len(got) != 0 {
+		t.Fatalf("Triggered(nil) = %#v, want an empty slice", got)
+	}
+}
+
+func TestAmountLimit(t *testing.T) {
+	limits, err := money.ParseLimits("USD:10000,JPY:1500000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		amount      money.Money
+		wantOutcome Outcome
+		wantDetail  string
+	}{
+		{money.Money{Minor: 1000000, Currency: "USD"}, OutcomePass, ""},
+		{money.Money{Minor: 1000001, Currency: "USD"}, OutcomeTriggered, "Amount exceeds 10000.00 USD limit"},
+		{money.Money{Minor: 1500000, Currency: "JPY"}, OutcomePass, ""},
+		{money.Money{Minor: 1500001, Currency: "JPY"}, OutcomeTriggered, "Amount exceeds 1500000 JPY limit"},
+		{money.Money{Minor: 99999999, Currency: "KZT"}, OutcomeSkipped, "No amount limit for KZT"},
+	}
+	for _, tt := range tests {
+		r := AmountLimit(tt.amount, limits)
+		if r.Code != CodeAmountLimit || r.Outcome != tt.wantOutcome || r.Detail != tt.wantDetail {
+			t.Fatalf("AmountLimit(%v %s) = %+v", tt.amount, tt.amount.Currency, r)
+		}
+		if (r.Outcome == OutcomeTriggered) != (r.Action == ActionDeny) {
+			t.Fatalf("AmountLimit(%v %s) action = %q", tt.amount, tt.amount.Currency, r.Action)
+		}
+	}
+}
+
+func TestVelocity(t *testing.T) {
+	limits := []velocity.Limit{
+		{Dimension: velocity.DimensionCustomer, Window: "1h", MaxCount: 2},
+		{Dimension: velocity.DimensionCustomer, Window: "24h", MaxAmount: money.Money{Minor: 100000, Currency: "USD"}},
+	}
+	snapshot := velocity.Snapshot{velocity.DimensionCustomer: {
+		"1h":  {Count: 2},
+		"24h": {Count: 2, Amounts: map[string]int64{"USD": 50000}},
+	}}
+	usd := money.Money{Minor: 1000, Currency: "USD"}
+	jpy := money.Money{Minor: 1000, Currency: "JPY"}
+
+	tests := []struct {
+		name       string
+		amount     money.Money
+		available  bool
+		failClosed bool
+		want       map[string]Outcome
+	}{
+		{"count exceeded", usd, true, false, map[string]Outcome{
+			"VELOCITY_CUSTOMER_1H_COUNT":       OutcomeTriggered,
+			"VELOCITY_CUSTOMER_24H_AMOUNT_USD": OutcomePass,
+		}},
+		// The USD amount limit does not apply to a JPY payment
+		{"other currency", jpy, true, false, map[string]Outcome{
+			"VELOCITY_CUSTOMER_1H_COUNT":       OutcomeTriggered,
+			"VELOCITY_CUSTOMER_24H_AMOUNT_USD": OutcomeSkipped,
+		}},
+		{"unavailable fails open", usd, false, false, map[string]Outcome{
+			CodeVelocityUnavailable:            OutcomeSkipped,
+			"VELOCITY_CUSTOMER_1H_COUNT":       OutcomeSkipped,
+			"VELOCITY_CUSTOMER_24H_AMOUNT_USD": OutcomeSkipped,
+		}},
+		{"unavailable fails closed", usd, false, true, map[string]Outcome{
+			CodeVelocityUnavailable:            OutcomeTriggered,
+			"VELOCITY_CUSTOMER_1H_COUNT":       OutcomeSkipped,
+			"VELOCITY_CUSTOMER_24H_AMOUNT_USD": OutcomeSkipped,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := Velocity(snapshot, limits, tt.amount, tt.available, tt.failClosed)
+			got := make(map[string]Outcome, len(results))
+			for _, r := range results {
+				got[r.Code] = r.Outcome
+				if (r.Outcome == OutcomeTriggered) != (r.Action == ActionDeny) {
+					t.Fatalf("%s: outcome %s with action %q", r.Code, r.Outcome, r.Action)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Velocity outcomes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	results := Velocity(snapshot, limits, usd, true, false)
+	if want := "customer velocity exceeded: more than 2 payments in 1h"; results[0].Detail != want {
+		t.Fatalf("detail = %q, want %q", results[0].Detail, want)
+	}
+}
+
+func TestScore(t *testing.T) {
+	scorer := scoring.NewWeightedScorer(scoring.DefaultWeights)
+
+	tests := []struct {
+		name        string
+		features    scoring.Features
+		wantCodes   []string
+		wantAction  string
+		wantTrigger string
+	}{
+		{"low score", scoring.Features{"amount": 10}, []string{CodeRiskScoreDeny, CodeRiskScoreReview}, "", ""},
+		{
+			"review threshold",
+			scoring.Features{"amount_over_1000": 1, "amount_over_5000": 1},
+			[]string{"SCORE_AMOUNT_OVER_1000", "SCORE_AMOUNT_OVER_5000", CodeRiskScoreDeny, CodeRiskScoreReview},
+			ActionManualReview, CodeRiskScoreReview,
+		},
+		{
+			"deny threshold",
+			scoring.Features{"amount_over_1000": 3, "amount_over_5000": 1},
+			[]string{"SCORE_AMOUNT_OVER_1000", "SCORE_AMOUNT_OVER_5000", CodeRiskScoreDeny, CodeRiskScoreReview},
+			ActionDeny, CodeRiskScoreDeny,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, _ := scorer.Score(tt.features)
+			results := Score(scorer, tt.features, score, 80, 95)
+
+			var codes []string
+			var trigger string
+			for _, r := range results {
+				codes = append(codes, r.Code)
+				if r.Outcome == OutcomeTriggered {
+					if trigger != "" {
+						t.Fatalf("both thresholds triggered: %+v", results)
+					}
+					trigger = r.Code
+					if r.Action != tt.wantAction {
+						t.Fatalf("%s action = %q, want %q", r.Code, r.Action, tt.wantAction)
+					}
+				}
+			}
+			if !reflect.DeepEqual(codes, tt.wantCodes) || trigger != tt.wantTrigger {
+				t.Fatalf("Score codes = %v triggered %q; want %v triggered %q", codes, trigger, tt.wantCodes, tt.wantTrigger)
+			}
+		})
+	}
+
+	features := scoring.Features{"amount_over_1000": 1, "amount_over_5000": 1}
+	results := Score(scorer, features, 80, 80, 95)
+	if results[0].ScoreContribution != 30 || results[1].ScoreContribution != 50 {
+		t.Fatalf("feature contributions = %+v", results[:2])
+	}
+}
+
+func TestListMatch(t *testing.T) {
+	tests := []struct {
+		entry      lists.Entry
+		wantCode   string
+		wantAction string
+		wantDetail string
+	}{
+		{
+			lists.Entry{ListType: lists.ListBlock, EntityType: lists.EntityCountry, Value: "KP"},
+			"LIST_BLOCK_COUNTRY", ActionDeny, "country KP is blocklisted",
+		},
+		{
+			lists.Entry{ListType: lists.ListAllow, EntityType: lists.EntityCustomer, Value: "vip-1", Reason: "verified"},
+			"LIST_ALLOW_CUSTOMER", ActionApprove, "customer vip-1 is allowlisted: verified",
+		},
+		{
+			lists.Entry{ListType: lists.ListBlock, EntityType: lists.EntityEmailDomain, Value: "spam.example"},
+			"LIST_BLOCK_EMAIL_DOMAIN", ActionDeny, "email_domain spam.example is blocklisted",
+		},
+	}
+	for _, tt := range tests {
+		r := ListMatch(&tt.entry)
+		if r.Code != tt.wantCode || r.Action != tt.wantAction || r.Detail != tt.wantDetail || r.Outcome != OutcomeTriggered {
+			t.Fatalf("ListMatch(%+v) = %+v", tt.entry, r)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/akylbek/payment-system/fraud-service/internal/profile"
+	"github.com/akylbek/payment-system/fraud-service/internal/velocity"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// Features are the numeric inputs a scorer works on, keyed by name
+type Features map[string]float64
+
+// Units is the amount of each currency that counts as one unit in amount
+// features, e.g. USD:1,JPY:150, so thresholds such as amount_over_5000 mean
+// roughly the same value in every currency
+type Units = money.Limits
+
+// ParseUnits parses a comma separated list of "<currency>:<amount>" with
+// amounts in major units, e.g. "USD:1,EUR:1,JPY:150"
+func ParseUnits(spec string) (Units, error) {
+	units, err := money.ParseLimits(spec)
+	if err != nil {
+		return nil, err
+	}
+	for currency, unit := range units {
+		if !unit.IsPositive() {
+			return nil, fmt.Errorf("amount unit of %s must be positive", currency)
+		}
+	}
+	return units, nil
+}
+
+// inUnits converts amount to units, false for a currency without a unit
+func inUnits(amount money.Money, units Units) (float64, bool) {
+	unit, ok := units.Get(amount.Currency)
+	if !ok || !unit.IsPositive() {
+		return 0, false
+	}
+	return float64(amount.Minor) / float64(unit.Minor), true
+}
+
+// Input is everything known about a payment at scoring time
+type Input struct {
+	Amount money.Money
+	// Units converts Amount and velocity sums for the amount features
+	Units         Units
+	CustomerID    string
+	MerchantID    string
+	PaymentMethod string
+	IPAddress     string
+	DeviceID      string
+	Velocity      velocity.Snapshot
+	// Profile is the customer's history, nil when it could not be loaded
+	Profile *profile.Profile
+	Now     time.Time
+}
+
+// ExtractFeatures turns a payment into features. Velocity features are named
+// velocity_<dimension>_<window>_count and velocity_<dimension>_<window>_amount,
+// customer history features are prefixed with customer_. Velocity and
+// history amounts only cover payments in the currency of the payment.
+//
+// Amounts are converted with in.Units. A payment in a currency without a
+// unit gets no amount features, the same way AMOUNT_LIMIT skips it, instead
+// of comparing e.g. JPY against thresholds meant for USD.
+func ExtractFeatures(in Input) Features {
+	f := Features{
+		"has_merchant": indicator(in.MerchantID != ""),
+		"has_ip":       indicator(in.IPAddress != ""),
+		"has_device":   indicator(in.DeviceID != ""),
+	}
+
+	amount, converted := inUnits(in.Amount, in.Units)
+	if converted {
+		f["amount"] = amount
+		f["log_amount"] = math.Log1p(math.Max(amount, 0))
+		f["amount_over_1000"] = indicator(amount > 1000)
+		f["amount_over_5000"] = indicator(amount > 5000)
+	}
+
+	for dimension, windows := range in.Velocity {
+		for window, stats := range windows {
+			prefix := "velocity_" + string(dimension) + "_" + window
+			f[prefix+"_count"] = float64(stats.Count)
+			if converted {
+				f[prefix+"_amount"], _ = inUnits(stats.Amount(in.Amount.Currency), in.Units)
+			}
+		}
+	}
+
+	if p := in.Profile; p != nil {
+		f["customer_payments"] = float64(p.LifetimePayments)
+		f["customer_new"] = indicator(p.LifetimePayments == 0)
+		f["customer_age_days"] = p.AgeDays(in.Now)
+		f["customer_deny_ratio"] = p.DenyRatio
+		f["customer_distinct_merchants"] = float64(p.DistinctMerchants)
+		amounts, paid := p.Amounts[in.Amount.Currency]
+		f["customer_amount_over_max"] = indicator(paid && in.Amount.Minor > amounts.Max.Minor)
+		if amounts.Avg.IsPositive() {
+			f["customer_amount_to_avg"] = float64(in.Amount.Minor) / float64(amounts.Avg.Minor)
+		}
+	}
+
+	return f
+}
+
+func indicator(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,154 @@
+package scoring
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akylbek/payment-system/fraud-service/internal/profile"
+	"github.com/akylbek/payment-system/fraud-service/internal/velocity"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func mustUnits(t *testing.T, spec string) Units {
+	t.Helper()
+	units, err := ParseUnits(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return units
+}
+
+func TestAmountFeaturesUseCurrencyUnits(t *testing.T) {
+	units := mustUnits(t, "USD:1,JPY:150,KWD:0.3")
+	scorer := NewWeightedScorer(DefaultWeights)
+
+	tests := []struct {
+		name      string
+		amount    money.Money
+		over1000  float64
+		over5000  float64
+		wantScore int
+	}{
+		// 9000 JPY is about 60 USD and must not look like a 9000 USD payment
+		{"small JPY payment", money.Money{Minor: 9000, Currency: "JPY"}, 0, 0, 0},
+		{"JPY above 1000 units", money.Money{Minor: 300000, Currency: "JPY"}, 1, 0, 30},
+		{"JPY above 5000 units", money.Money{Minor: 900000, Currency: "JPY"}, 1, 1, 80},
+		{"USD above 5000", money.Money{Minor: 500100, Currency: "USD"}, 1, 1, 80},
+		{"USD at 1000", money.Money{Minor: 100000, Currency: "USD"}, 0, 0, 0},
+		// 400 KWD is about 1333 units
+		{"KWD above 1000 units", money.Money{Minor: 400000, Currency: "KWD"}, 1, 0, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := ExtractFeatures(Input{Amount: tt.amount, Units: units, Now: time.Now()})
+			if f["amount_over_1000"] != tt.over1000 || f["amount_over_5000"] != tt.over5000 {
+				t.Fatalf("amount_over_1000 = %v, amount_over_5000 = %v; want %v, %v",
+					f["amount_over_1000"], f["amount_over_5000"], tt.over1000, tt.over5000)
+			}
+			score, err := scorer.Score(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if score != tt.wantScore {
+				t.Fatalf("score = %d, want %d", score, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestAmountFeaturesSkippedWithoutUnit(t *testing.T) {
+	f := ExtractFeatures(Input{
+		Amount: money.Money{Minor: 9000000, Currency: "KZT"},
+		Units:  mustUnits(t, "USD:1"),
+		Velocity: velocity.Snapshot{
+			velocity.DimensionCustomer: {"1h": {Count: 2, Amounts: map[string]int64{"KZT": 500000}}},
+		},
+		Now: time.Now(),
+	})
+	for _, name := range []string{"amount", "log_amount", "amount_over_1000", "amount_over_5000", "velocity_customer_1h_amount"} {
+		if _, ok := f[name]; ok {
+			t.Fatalf("%s set for a currency without a unit", name)
+		}
+	}
+	if f["velocity_customer_1h_count"] != 2 {
+		t.Fatalf("velocity count = %v, want 2", f["velocity_customer_1h_count"])
+	}
+}
+
+func TestVelocityAndProfileFeaturesStayInPaymentCurrency(t *testing.T) {
+	f := ExtractFeatures(Input{
+		Amount: money.Money{Minor: 30000, Currency: "JPY"},
+		Units:  mustUnits(t, "USD:1,JPY:150"),
+		Velocity: velocity.Snapshot{
+			velocity.DimensionCustomer: {"24h": {Count: 3, Amounts: map[string]int64{"JPY": 150000, "USD": 99999999}}},
+		},
+		Profile: &profile.Profile{
+			LifetimePayments: 3,
+			Amounts: map[string]profile.AmountDistribution{
+				"JPY": {Max: money.Money{Minor: 20000, Currency: "JPY"}, Avg: money.Money{Minor: 10000, Currency: "JPY"}},
+				"USD": {Max: money.Money{Minor: 100000000, Currency: "USD"}, Avg: money.Money{Minor: 1, Currency: "USD"}},
+			},
+		},
+		Now: time.Now(),
+	})
+
+	if got := f["velocity_customer_24h_amount"]; got != 1000 {
+		t.Fatalf("velocity amount = %v units, want 1000 (150000 JPY)", got)
+	}
+	if f["customer_amount_over_max"] != 1 {
+		t.Fatal("30000 JPY is above the customer's JPY maximum")
+	}
+	if got := f["customer_amount_to_avg"]; got != 3 {
+		t.Fatalf("customer_amount_to_avg = %v, want 3", got)
+	}
+}
+
+func TestParseUnitsRejectsZero(t *testing.T) {
+	if _, err := ParseUnits("USD:1,JPY:0"); err == nil {
+		t.Fatal("ParseUnits accepted a zero unit")
+	}
+}
+
+func TestCustomerFeatures(t *testing.T) {
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	firstSeen := now.AddDate(0, 0, -30)
+	usd := money.Money{Minor: 5000, Currency: "USD"}
+
+	tests := []struct {
+		name    string
+		profile *profile.Profile
+		want    Features
+	}{
+		{"no profile", nil, Features{}},
+		{"new customer", &profile.Profile{}, Features{
+			"customer_payments": 0, "customer_new": 1, "customer_age_days": 0,
+			"customer_deny_ratio": 0, "customer_distinct_merchants": 0, "customer_amount_over_max": 0,
+		}},
+		{"returning customer", &profile.Profile{
+			LifetimePayments: 4, DenyRatio: 0.25, DistinctMerchants: 2, FirstSeen: &firstSeen,
+			Amounts: map[string]profile.AmountDistribution{
+				"USD": {Max: money.Money{Minor: 4000, Currency: "USD"}, Avg: money.Money{Minor: 2000, Currency: "USD"}},
+			},
+		}, Features{
+			"customer_payments": 4, "customer_new": 0, "customer_age_days": 30,
+			"customer_deny_ratio": 0.25, "customer_distinct_merchants": 2,
+			"customer_amount_over_max": 1, "customer_amount_to_avg": 2.5,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := ExtractFeatures(Input{Amount: usd, Units: mustUnits(t, "USD:1"), Profile: tt.profile, Now: now})
+			got := Features{}
+			for name, value := range f {
+				if strings.HasPrefix(name, "customer_") {
+					got[name] = value
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("customer features = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
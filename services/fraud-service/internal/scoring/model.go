@@ -0,0 +1,132 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	ModelLogisticRegression = "logistic_regression"
+	ModelGradientBoosted    = "gradient_boosted"
+)
+
+// ModelFile is the on-disk format of a trained model. Only the fields that
+// belong to Type are used.
+//
+// Logistic regression:
+//
+//	{"type": "logistic_regression", "intercept": -4.2, "coefficients": {"log_amount": 0.35}}
+//
+// Gradient boosted trees (binary logistic objective, raw margins in leaves):
+//
+//	{"type": "gradient_boosted", "base_score": -2.0, "trees": [{"nodes": [
+//	  {"feature": "amount", "threshold": 5000, "left": 1, "right": 2},
+//	  {"leaf": -0.4}, {"leaf": 1.1}
+//	]}]}
+type ModelFile struct {
+	Type         string             `json:"type"`
+	Intercept    float64            `json:"intercept"`
+	Coefficients map[string]float64 `json:"coefficients"`
+	BaseScore    float64            `json:"base_score"`
+	Trees        []Tree             `json:"trees"`
+}
+
+// Tree is a binary decision tree stored as a flat node list, root first
+type Tree struct {
+	Nodes []TreeNode `json:"nodes"`
+}
+
+// TreeNode is a split when Feature is set, a leaf otherwise. Samples with
+// feature < Threshold go Left.
+type TreeNode struct {
+	Feature   string  `json:"feature,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Left      int     `json:"left,omitempty"`
+	Right     int     `json:"right,omitempty"`
+	Leaf      float64 `json:"leaf,omitempty"`
+}
+
+// ModelScorer scores with a logistic regression or gradient boosted model;
+// the predicted fraud probability is scaled to 0..100
+type ModelScorer struct {
+	model ModelFile
+}
+
+// LoadModel reads and validates a model file
+func LoadModel(path string) (*ModelScorer, error) {
+	var model ModelFile
+	if err := readJSON(path, &model); err != nil {
+		return nil, err
+	}
+
+	switch model.Type {
+	case ModelLogisticRegression:
+	case ModelGradientBoosted:
+		for i, tree := range model.Trees {
+			if err := tree.validate(); err != nil {
+				return nil, fmt.Errorf("tree %d: %w", i, err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown model type %q", model.Type)
+	}
+
+	return &ModelScorer{model: model}, nil
+}
+
+func (s *ModelScorer) Name() string {
+	return s.model.Type
+}
+
+func (s *ModelScorer) Score(features Features) (int, error) {
+	var margin float64
+	switch s.model.Type {
+	case ModelLogisticRegression:
+		margin = s.model.Intercept
+		for name, coef := range s.model.Coefficients {
+			margin += coef * features[name]
+		}
+	case ModelGradientBoosted:
+		margin = s.model.BaseScore
+		for _, tree := range s.model.Trees {
+			margin += tree.predict(features)
+		}
+	}
+
+	probability := 1 / (1 + math.Exp(-margin))
+	return clamp(probability * MaxScore), nil
+}
+
+func (t Tree) predict(features Features) float64 {
+	i := 0
+	for {
+		node := t.Nodes[i]
+		if node.Feature == "" {
+			return node.Leaf
+		}
+		if features[node.Feature] < node.Threshold {
+			i = node.Left
+		} else {
+			i = node.Right
+		}
+	}
+}
+
+// validate makes sure every child index points forward in the node list,
+// which rules out cycles and out-of-range lookups in predict
+func (t Tree) validate() error {
+	if len(t.Nodes) == 0 {
+		return fmt.Errorf("tree has no nodes")
+	}
+	for i, node := range t.Nodes {
+		if node.Feature == "" {
+			continue
+		}
+		for _, child := range []int{node.Left, node.Right} {
+			if child <= i || child >= len(t.Nodes) {
+				return fmt.Errorf("node %d has invalid child %d", i, child)
+			}
+		}
+	}
+	return nil
+}
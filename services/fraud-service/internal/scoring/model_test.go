@@ -0,0 +1,82 @@
+package scoring
+
+import (
+	"strings"
+	"testing"
+)
+
+const testTrees = `{"type": "gradient_boosted", "base_score": -2, "trees": [
+	{"nodes": [
+		{"feature": "amount", "threshold": 5000, "left": 1, "right": 2},
+		{"leaf": -1},
+		{"feature": "new_customer", "threshold": 0.5, "left": 3, "right": 4},
+		{"leaf": 1},
+		{"leaf": 3}
+	]},
+	{"nodes": [{"leaf": 0.5}]}
+]}`
+
+func TestModelScorer(t *testing.T) {
+	logistic := writeFile(t, "logistic.json",
+		`{"type": "logistic_regression", "intercept": -2, "coefficients": {"log_amount": 0.5, "new_customer": 2}}`)
+	trees := writeFile(t, "trees.json", testTrees)
+
+	tests := []struct {
+		name     string
+		path     string
+		features Features
+		want     int
+	}{
+		// sigmoid(-2) = 0.119
+		{"logistic intercept only", logistic, Features{}, 12},
+		// sigmoid(-2 + 0.5*4 + 2) = sigmoid(2) = 0.881
+		{"logistic", logistic, Features{"log_amount": 4, "new_customer": 1}, 88},
+		// sigmoid(-2 - 1 + 0.5) = 0.076
+		{"small amount goes left", trees, Features{"amount": 100}, 8},
+		// sigmoid(-2 + 1 + 0.5) = 0.378
+		{"large amount, known customer", trees, Features{"amount": 5000}, 38},
+		// sigmoid(-2 + 3 + 0.5) = 0.818
+		{"large amount, new customer", trees, Features{"amount": 9000, "new_customer": 1}, 82},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scorer, err := LoadModel(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := scorer.Score(tt.features)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Score = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadModelRejectsInvalidModels(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   string
+		wantErr string
+	}{
+		{"unknown type", `{"type": "random_forest"}`, "unknown model type"},
+		{"tree without nodes", `{"type": "gradient_boosted", "trees": [{"nodes": []}]}`, "tree 0: tree has no nodes"},
+		{"child pointing back", `{"type": "gradient_boosted", "trees": [{"nodes": [
+			{"feature": "amount", "threshold": 1, "left": 1, "right": 2}, {"leaf": 1},
+			{"feature": "amount", "threshold": 2, "left": 0, "right": 1}
+		]}]}`, "node 2 has invalid child 0"},
+		{"child out of range", `{"type": "gradient_boosted", "trees": [{"nodes": [{"leaf": 1}]}, {"nodes": [
+			{"feature": "amount", "threshold": 1, "left": 1, "right": 5}, {"leaf": 1}
+		]}]}`, "tree 1: node 0 has invalid child 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadModel(writeFile(t, "model.json", tt.model))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadModel error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package scoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+const (
+	MinScore = 0
+	MaxScore = 100
+)
+
+// RiskScorer turns payment features into a risk score between 0 and 100
+type RiskScorer interface {
+	Name() string
+	Score(features Features) (int, error)
+}
+
+// WeightedConfig is the file format of the weighted-feature scorer:
+//
+//	{"bias": 0, "weights": {"amount_over_1000": 30, "amount_over_5000": 50}}
+type WeightedConfig struct {
+	Bias    float64            `json:"bias"`
+	Weights map[string]float64 `json:"weights"`
+}
+
+// DefaultWeights matches the original amount based scoring
+var DefaultWeights = WeightedConfig{
+	Weights: map[string]float64{
+		"amount_over_1000": 30,
+		"amount_over_5000": 50,
+	},
+}
+
+// WeightedScorer sums weight * feature value and clamps the result to 0..100.
+// Features without a weight are ignored.
+type WeightedScorer struct {
+	config WeightedConfig
+}
+
+func NewWeightedScorer(config WeightedConfig) *WeightedScorer {
+	return &WeightedScorer{config: config}
+}
+
+// LoadWeightedScorer reads a WeightedConfig from a JSON file
+func LoadWeightedScorer(path string) (*WeightedScorer, error) {
+	var config WeightedConfig
+	if err := readJSON(path, &config); err != nil {
+		return nil, err
+	}
+	return NewWeightedScorer(config), nil
+}
+
+func (s *WeightedScorer) Name() string {
+	return "weighted"
+}
+
+func (s *WeightedScorer) Score(features Features) (int, error) {
+	score := s.config.Bias
+	for name, weight := range s.config.Weights {
+		score += weight * features[name]
+	}
+	return clamp(score), nil
+}
+
+// New builds the scorer selected by kind. "weighted" uses weightsFile when
+// set and DefaultWeights otherwise, "model" loads modelFile.
+func New(kind, weightsFile, modelFile string) (RiskScorer, error) {
+	switch kind {
+	case "weighted":
+		if weightsFile == "" {
+			return NewWeightedScorer(DefaultWeights), nil
+		}
+		return LoadWeightedScorer(weightsFile)
+	case "model":
+		if modelFile == "" {
+			return nil, fmt.Errorf("model scorer requires a model file")
+		}
+		return LoadModel(modelFile)
+	default:
+		return nil, fmt.Errorf("unknown risk scorer %q", kind)
+	}
+}
+
+// Contributions attributes a score to individual features by removing one
+// feature at a time and measuring how much the score drops. It works for any
+// scorer; features that do not move the score are left out.
+func Contributions(scorer RiskScorer, features Features, score int) map[string]int {
+	contributions := make(map[string]int)
+	for name, value := range features {
+		if value == 0 {
+			continue
+		}
+
+		features[name] = 0
+		without, err := scorer.Score(features)
+		features[name] = value
+		if err != nil {
+			continue
+		}
+
+		if delta := score - without; delta != 0 {
+			contributions[name] = delta
+		}
+	}
+	return contributions
+}
+
+func clamp(score float64) int {
+	if math.IsNaN(score) {
+		return MaxScore
+	}
+	return int(math.Round(math.Max(MinScore, math.Min(MaxScore, score))))
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package scoring
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWeightedScorer(t *testing.T) {
+	config := WeightedConfig{
+		Bias:    5,
+		Weights: map[string]float64{"amount_over_1000": 30, "new_customer": 20.4, "trusted": -40},
+	}
+	scorer := NewWeightedScorer(config)
+
+	tests := []struct {
+		name     string
+		features Features
+		want     int
+	}{
+		{"bias only", Features{}, 5},
+		{"weights sum up", Features{"amount_over_1000": 1, "new_customer": 1}, 55},
+		{"feature values scale weights", Features{"new_customer": 0.5}, 15},
+		{"features without a weight are ignored", Features{"amount": 9000}, 5},
+		{"clamped at 0", Features{"trusted": 1}, 0},
+		{"clamped at 100", Features{"amount_over_1000": 10}, 100},
+		{"NaN scores as maximum risk", Features{"new_customer": math.NaN()}, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scorer.Score(tt.features)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Score = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	weights := writeFile(t, "weights.json", `{"bias": 10, "weights": {"new_customer": 15}}`)
+	model := writeFile(t, "model.json", `{"type": "logistic_regression", "intercept": 0}`)
+
+	tests := []struct {
+		kind, weightsFile, modelFile string
+		wantName                     string
+		features                     Features
+		wantScore                    int
+		wantErr                      string
+	}{
+		{kind: "weighted", wantName: "weighted", features: Features{"amount_over_5000": 1, "amount_over_1000": 1}, wantScore: 80},
+		{kind: "weighted", weightsFile: weights, wantName: "weighted", features: Features{"new_customer": 1}, wantScore: 25},
+		{kind: "model", modelFile: model, wantName: ModelLogisticRegression, features: Features{}, wantScore: 50},
+		{kind: "model", wantErr: "requires a model file"},
+		{kind: "weighted", weightsFile: filepath.Join(t.TempDir(), "missing.json"), wantErr: "failed to read"},
+		{kind: "weighted", weightsFile: writeFile(t, "broken.json", `{"weights": [`), wantErr: "failed to parse"},
+		{kind: "neural", wantErr: "unknown risk scorer"},
+	}
+	for _, tt := range tests {
+		scorer, err := New(tt.kind, tt.weightsFile, tt.modelFile)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New(%q) error = %v, want one mentioning %q", tt.kind, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		score, err := scorer.Score(tt.features)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scorer.Name() != tt.wantName || score != tt.wantScore {
+			t.Fatalf("New(%q) scores %d with %s, want %d with %s", tt.kind, score, scorer.Name(), tt.wantScore, tt.wantName)
+		}
+	}
+}
+
+func TestContributions(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   WeightedConfig
+		features Features
+		want     map[string]int
+	}{
+		{
+			name:     "default weights",
+			config:   DefaultWeights,
+			features: Features{"amount": 6000, "amount_over_1000": 1, "amount_over_5000": 1},
+			want:     map[string]int{"amount_over_1000": 30, "amount_over_5000": 50},
+		},
+		{
+			// Each feature alone would reach 80; together they are clamped
+			// at 100, so removing one only drops the score by 20
+			name:     "clamped score",
+			config:   WeightedConfig{Weights: map[string]float64{"a": 80, "b": 80}},
+			features: Features{"a": 1, "b": 1},
+			want:     map[string]int{"a": 20, "b": 20},
+		},
+		{
+			name:     "features lowering the score",
+			config:   WeightedConfig{Bias: 50, Weights: map[string]float64{"trusted": -30}},
+			features: Features{"trusted": 1, "unused": 1},
+			want:     map[string]int{"trusted": -30},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scorer := NewWeightedScorer(tt.config)
+			before := make(Features, len(tt.features))
+			for name, value := range tt.features {
+				before[name] = value
+			}
+
+			score, _ := scorer.Score(tt.features)
+			got := Contributions(scorer, tt.features, score)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Contributions = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.features, before) {
+				t.Fatalf("Contributions changed the features to %v", tt.features)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package velocity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// Limit caps the count or the summed amount of payments for a dimension
+// within a window. A zero MaxCount or MaxAmount means "no limit". An amount
+// limit only sums and applies to payments in the currency of MaxAmount.
+type Limit struct {
+	Dimension Dimension
+	Window    string
+	MaxCount  int64
+	MaxAmount money.Money
+}
+
+// Applies reports whether the limit covers a payment of the given amount
+func (l Limit) Applies(amount money.Money) bool {
+	return l.MaxCount > 0 || l.MaxAmount.Currency == amount.Currency
+}
+
+// Violation describes a limit that the current payment would exceed
+type Violation struct {
+	Limit Limit
+	Stats Stats
+}
+
+func (v Violation) String() string {
+	if v.Limit.MaxCount > 0 && v.Stats.Count+1 > v.Limit.MaxCount {
+		return fmt.Sprintf("%s velocity exceeded: more than %d payments in %s",
+			v.Limit.Dimension, v.Limit.MaxCount, v.Limit.Window)
+	}
+	return fmt.Sprintf("%s velocity exceeded: more than %s %s in %s",
+		v.Limit.Dimension, v.Limit.MaxAmount, v.Limit.MaxAmount.Currency, v.Limit.Window)
+}
+
+// Evaluate checks whether adding a payment of the given amount to the
+// snapshot would break any of the limits
+func Evaluate(snapshot Snapshot, limits []Limit, amount money.Money) []Violation {
+	var violations []Violation
+	for _, l := range limits {
+		stats, ok := snapshot[l.Dimension][l.Window]
+		if !ok {
+			continue
+		}
+		if l.MaxCount > 0 && stats.Count+1 > l.MaxCount {
+			violations = append(violations, Violation{Limit: l, Stats: stats})
+			continue
+		}
+		if l.MaxAmount.IsPositive() && l.MaxAmount.Currency == amount.Currency &&
+			stats.Amount(amount.Currency).Minor+amount.Minor > l.MaxAmount.Minor {
+			violations = append(violations, Violation{Limit: l, Stats: stats})
+		}
+	}
+	return violations
+}
+
+// ParseDimensions parses a comma separated list such as "customer,merchant,ip"
+func ParseDimensions(spec string) ([]Dimension, error) {
+	var dimensions []Dimension
+	for _, part := range splitList(spec) {
+		d := Dimension(part)
+		switch d {
+		case DimensionCustomer, DimensionMerchant, DimensionPaymentMethod, DimensionIP, DimensionDevice:
+			dimensions = append(dimensions, d)
+		default:
+			return nil, fmt.Errorf("unknown velocity dimension %q", part)
+		}
+	}
+	return dimensions, nil
+}
+
+// ParseWindows parses a comma separated list of durations such as "1m,1h,24h"
+func ParseWindows(spec string) ([]Window, error) {
+	var windows []Window
+	for _, part := range splitList(spec) {
+		d, err := time.ParseDuration(part)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid velocity window %q", part)
+		}
+		windows = append(windows, Window{Name: part, Duration: d})
+	}
+	return windows, nil
+}
+
+// ParseLimits parses a comma separated list of "<dimension>:<window>:count:<max>"
+// and "<dimension>:<window>:amount:<currency>:<max>" entries with max in
+// major units, e.g. "customer:1h:count:5,customer:24h:amount:USD:20000"
+func ParseLimits(spec string, windows []Window) ([]Limit, error) {
+	known := make(map[string]bool, len(windows))
+	for _, w := range windows {
+		known[w.Name] = true
+	}
+
+	var limits []Limit
+	for _, part := range splitList(spec) {
+		fields := strings.Split(part, ":")
+		if len(fields) != 4 && (len(fields) != 5 || fields[2] != "amount") {
+			return nil, fmt.Errorf("invalid velocity limit %q", part)
+		}
+
+		dimensions, err := ParseDimensions(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		if !known[fields[1]] {
+			return nil, fmt.Errorf("velocity limit %q uses untracked window %q", part, fields[1])
+		}
+
+		limit := Limit{Dimension: dimensions[0], Window: fields[1]}
+		switch fields[2] {
+		case "count":
+			limit.MaxCount, err = strconv.ParseInt(fields[3], 10, 64)
+		case "amount":
+			if len(fields) != 5 {
+				err = fmt.Errorf("amount limit needs a currency, e.g. USD:20000")
+				break
+			}
+			limit.MaxAmount, err = money.ParseLimit(fields[3], fields[4])
+		default:
+			err = fmt.Errorf("unknown limit type %q", fields[2])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid velocity limit %q: %w", part, err)
+		}
+
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+func splitList(spec string) []string {
+	var parts []string
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
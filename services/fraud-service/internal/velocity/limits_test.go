@@ -0,0 +1,153 @@
+package velocity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func TestParseDimensions(t *testing.T) {
+	dimensions, err := ParseDimensions(" customer, ip,,device ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Dimension{DimensionCustomer, DimensionIP, DimensionDevice}
+	if !reflect.DeepEqual(dimensions, want) {
+		t.Fatalf("ParseDimensions = %v, want %v", dimensions, want)
+	}
+	if _, err := ParseDimensions("customer,country"); err == nil {
+		t.Fatal("ParseDimensions accepted an unknown dimension")
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows("1m,1h,24h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Window{{"1m", time.Minute}, {"1h", time.Hour}, {"24h", 24 * time.Hour}}
+	if !reflect.DeepEqual(windows, want) {
+		t.Fatalf("ParseWindows = %v, want %v", windows, want)
+	}
+	for _, spec := range []string{"1d", "0s", "-1h"} {
+		if _, err := ParseWindows(spec); err == nil {
+			t.Fatalf("ParseWindows(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("customer:1h:count:5, ip:24h:amount:jpy:1500000,customer:24h:amount:USD:20000.50", DefaultWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Limit{
+		{Dimension: DimensionCustomer, Window: "1h", MaxCount: 5},
+		{Dimension: DimensionIP, Window: "24h", MaxAmount: money.Money{Minor: 1500000, Currency: "JPY"}},
+		{Dimension: DimensionCustomer, Window: "24h", MaxAmount: money.Money{Minor: 2000050, Currency: "USD"}},
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Fatalf("ParseLimits = %+v, want %+v", limits, want)
+	}
+
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{"customer:1h:count", "invalid velocity limit"},
+		{"customer:1h:count:5:6", "invalid velocity limit"},
+		{"country:1h:count:5", "unknown velocity dimension"},
+		{"customer:5m:count:5", "untracked window"},
+		{"customer:1h:count:many", "invalid velocity limit"},
+		{"customer:1h:amount:20000", "needs a currency"},
+		{"customer:1h:amount:XXX:20000", "unknown currency"},
+		{"customer:1h:amount:USD:-1", "cannot be negative"},
+		{"customer:1h:sum:5", "unknown limit type"},
+	}
+	for _, tt := range tests {
+		_, err := ParseLimits(tt.spec, DefaultWindows)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("ParseLimits(%q) error = %v, want one mentioning %q", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	countLimit := Limit{Dimension: DimensionCustomer, Window: "1h", MaxCount: 3}
+	usdLimit := Limit{Dimension: DimensionCustomer, Window: "24h", MaxAmount: money.Money{Minor: 100000, Currency: "USD"}}
+	ipLimit := Limit{Dimension: DimensionIP, Window: "1h", MaxCount: 1}
+	limits := []Limit{countLimit, usdLimit, ipLimit}
+
+	snapshot := func(count1h, count24h int64, amounts map[string]int64) Snapshot {
+		return Snapshot{DimensionCustomer: {
+			"1h":  {Count: count1h},
+			"24h": {Count: count24h, Amounts: amounts},
+		}}
+	}
+	usd := func(minor int64) money.Money { return money.Money{Minor: minor, Currency: "USD"} }
+
+	tests := []struct {
+		name     string
+		snapshot Snapshot
+		amount   money.Money
+		want     []Limit
+	}{
+		{"under every limit", snapshot(1, 1, map[string]int64{"USD": 1000}), usd(1000), nil},
+		{"count reaches the limit", snapshot(2, 2, nil), usd(1000), nil},
+		{"count exceeds the limit", snapshot(3, 3, nil), usd(1000), []Limit{countLimit}},
+		{"amount reaches the limit", snapshot(0, 1, map[string]int64{"USD": 90000}), usd(10000), nil},
+		{"amount exceeds the limit", snapshot(0, 1, map[string]int64{"USD": 90000}), usd(10001), []Limit{usdLimit}},
+		// Amounts in other currencies are never added to the USD limit
+		{"other currency ignored", snapshot(0, 1, map[string]int64{"USD": 90000, "JPY": 9000000}), money.Money{Minor: 9000000, Currency: "JPY"}, nil},
+		{"count and amount limits exceeded", snapshot(3, 3, map[string]int64{"USD": 100000}), usd(1), []Limit{countLimit, usdLimit}},
+		// Windows or dimensions missing from the snapshot are not checked
+		{"untracked dimension", Snapshot{}, usd(1000000), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Limit
+			for _, v := range Evaluate(tt.snapshot, limits, tt.amount) {
+				got = append(got, v.Limit)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Evaluate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateReportsALimitOnce(t *testing.T) {
+	limit := Limit{Dimension: DimensionCustomer, Window: "1h", MaxCount: 1, MaxAmount: money.Money{Minor: 100, Currency: "USD"}}
+	snapshot := Snapshot{DimensionCustomer: {"1h": {Count: 1, Amounts: map[string]int64{"USD": 100}}}}
+	violations := Evaluate(snapshot, []Limit{limit}, money.Money{Minor: 1, Currency: "USD"})
+	if len(violations) != 1 {
+		t.Fatalf("Evaluate = %+v, want one violation", violations)
+	}
+	if got := violations[0].String(); got != "customer velocity exceeded: more than 1 payments in 1h" {
+		t.Fatalf("violation = %q, want the count message", got)
+	}
+}
+
+func TestViolationString(t *testing.T) {
+	count := Violation{Limit: Limit{Dimension: DimensionCustomer, Window: "1h", MaxCount: 3}, Stats: Stats{Count: 3}}
+	if got := count.String(); got != "customer velocity exceeded: more than 3 payments in 1h" {
+		t.Fatalf("count violation = %q", got)
+	}
+	amount := Violation{Limit: Limit{Dimension: DimensionIP, Window: "24h", MaxAmount: money.Money{Minor: 1500000, Currency: "JPY"}}}
+	if got := amount.String(); got != "ip velocity exceeded: more than 1500000 JPY in 24h" {
+		t.Fatalf("amount violation = %q", got)
+	}
+}
+
+func TestLimitApplies(t *testing.T) {
+	jpy := money.Money{Minor: 500, Currency: "JPY"}
+	if !(Limit{MaxCount: 1}).Applies(jpy) {
+		t.Fatal("count limit does not apply to every currency")
+	}
+	if (Limit{MaxAmount: money.Money{Minor: 100, Currency: "USD"}}).Applies(jpy) {
+		t.Fatal("USD amount limit applies to a JPY payment")
+	}
+}
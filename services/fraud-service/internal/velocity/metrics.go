@@ -0,0 +1,18 @@
+package velocity
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	ModePostgresFallback = "postgres_fallback"
+	ModeFailOpen         = "fail_open"
+	ModeFailClosed       = "fail_closed"
+)
+
+// DegradedEvaluations counts velocity evaluations that could not use Redis
+var DegradedEvaluations = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "fraud_velocity_degraded_evaluations_total",
+	Help: "Velocity evaluations served without Redis, by mode",
+}, []string{"mode"})
@@ -0,0 +1,134 @@
+package velocity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// bucketSize is the granularity counters are persisted with in Postgres.
+// Windows read from Postgres are therefore rounded to whole buckets.
+const bucketSize = time.Minute
+
+const counterTypePayments = "payments"
+
+// Bucket is an aggregated counter row for a single entity, minute and
+// currency. Rows recorded before amounts were kept per currency have an
+// empty Amount.
+type Bucket struct {
+	Start  time.Time
+	Count  int64
+	Amount money.Money
+}
+
+// Store persists velocity counters to the velocity_counters table so they
+// survive Redis outages and can be used to rebuild Redis afterwards
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Record adds a payment to the current bucket of every entity in the
+// payment's currency
+func (s *Store) Record(ctx context.Context, entities []Entity, amount money.Money, at time.Time) error {
+	start := at.UTC().Truncate(bucketSize)
+	end := start.Add(bucketSize)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, e := range entities {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO velocity_counters (entity_type, entity_id, counter_type, count, amount_minor, currency, window_start, window_end)
+			VALUES ($1, $2, $3, 1, $4, $5, $6, $7)
+			ON CONFLICT (entity_type, entity_id, counter_type, window_start, currency)
+			DO UPDATE SET count = velocity_counters.count + 1,
+				amount_minor = velocity_counters.amount_minor + EXCLUDED.amount_minor,
+				updated_at = NOW()
+		`, string(e.Dimension), e.ID, counterTypePayments, amount.Minor, amount.Currency, start, end)
+		if err != nil {
+			return fmt.Errorf("failed to persist velocity counter: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Buckets returns the buckets of an entity starting at or after since
+func (s *Store) Buckets(ctx context.Context, e Entity, since time.Time) ([]Bucket, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT window_start, count, amount_minor, currency
+		FROM velocity_counters
+		WHERE entity_type = $1 AND entity_id = $2 AND counter_type = $3 AND window_start >= $4
+		ORDER BY window_start, currency
+	`, string(e.Dimension), e.ID, counterTypePayments, since.UTC().Truncate(bucketSize))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []Bucket
+	for rows.Next() {
+		var b Bucket
+		var minor int64
+		var currency string
+		if err := rows.Scan(&b.Start, &b.Count, &minor, &currency); err != nil {
+			return nil, err
+		}
+		b.Amount = amountOf(minor, currency)
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// Snapshot aggregates persisted buckets into the same shape the Redis tracker returns
+func (s *Store) Snapshot(ctx context.Context, entities []Entity, windows []Window, retention time.Duration, now time.Time) (Snapshot, error) {
+	snapshot := make(Snapshot, len(entities))
+	for _, e := range entities {
+		buckets, err := s.Buckets(ctx, e, now.Add(-retention))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read persisted velocity counters: %w", err)
+		}
+
+		snapshot[e.Dimension] = bucketStats(buckets, windows, now)
+	}
+	return snapshot, nil
+}
+
+// bucketStats sums the buckets that start inside each window. A window
+// starts at the beginning of its first bucket, so it may reach up to a
+// bucket further back than in Redis.
+func bucketStats(buckets []Bucket, windows []Window, now time.Time) map[string]Stats {
+	stats := make(map[string]Stats, len(windows))
+	for _, w := range windows {
+		from := now.Add(-w.Duration).UTC().Truncate(bucketSize)
+		var st Stats
+		for _, b := range buckets {
+			if !b.Start.Before(from) {
+				st.add(b.Count, b.Amount)
+			}
+		}
+		stats[w.Name] = st
+	}
+	return stats
+}
+
+// Cleanup removes buckets that fell out of every window
+func (s *Store) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM velocity_counters WHERE counter_type = $1 AND window_end < $2`,
+		counterTypePayments, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,428 @@
+package velocity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// Dimension is an attribute of a payment that velocity is tracked by
+type Dimension string
+
+const (
+	DimensionCustomer      Dimension = "customer"
+	DimensionMerchant      Dimension = "merchant"
+	DimensionPaymentMethod Dimension = "payment_method"
+	DimensionIP            Dimension = "ip"
+	DimensionDevice        Dimension = "device"
+)
+
+// Window is a sliding time window counters are aggregated over
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+// DefaultWindows are the windows tracked when none are configured
+var DefaultWindows = []Window{
+	{Name: "1m", Duration: time.Minute},
+	{Name: "1h", Duration: time.Hour},
+	{Name: "24h", Duration: 24 * time.Hour},
+}
+
+// Entity identifies a single counter, e.g. customer "cust-001"
+type Entity struct {
+	Dimension Dimension
+	ID        string
+}
+
+// Stats holds the number of payments within a window and their summed
+// amount in minor units, per currency
+type Stats struct {
+	Count   int64            `json:"count"`
+	Amounts map[string]int64 `json:"amounts,omitempty"`
+}
+
+// Amount returns the summed amount of payments in currency
+func (s Stats) Amount(currency string) money.Money {
+	return money.Money{Minor: s.Amounts[currency], Currency: currency}
+}
+
+// add counts payments and their amount. Amounts without a currency, left
+// by counters recorded before amounts were kept per currency, only count.
+func (s *Stats) add(count int64, amount money.Money) {
+	s.Count += count
+	if amount.Currency == "" {
+		return
+	}
+	if s.Amounts == nil {
+		s.Amounts = make(map[string]int64)
+	}
+	s.Amounts[amount.Currency] += amount.Minor
+}
+
+// Snapshot maps dimension -> window name -> stats
+type Snapshot map[Dimension]map[string]Stats
+
+// Get returns the stats for a dimension and window, zero if not tracked
+func (s Snapshot) Get(dimension Dimension, window string) Stats {
+	return s[dimension][window]
+}
+
+// Source tells where a snapshot was read from
+type Source string
+
+const (
+	SourceRedis    Source = "redis"
+	SourcePostgres Source = "postgres"
+)
+
+// Tracker maintains sliding-window velocity counters in Redis sorted sets.
+// Every tracked payment is a member "<payment_id>:<amount_minor>:<currency>"
+// scored by its timestamp in milliseconds, so a window is a ZRANGEBYSCORE over the set.
+//
+// Counters are also written through to Postgres. While Redis is unavailable
+// the tracker serves snapshots from Postgres and remembers which entities
+// changed, then rebuilds those keys once Redis is reachable again.
+type Tracker struct {
+	client     *redis.Client
+	store      *Store
+	windows    []Window
+	dimensions map[Dimension]bool
+	retention  time.Duration
+	logger     *zap.Logger
+
+	mu       sync.Mutex
+	degraded bool
+	dirty    map[Entity]bool
+}
+
+func NewTracker(client *redis.Client, store *Store, logger *zap.Logger, windows []Window, dimensions []Dimension) *Tracker {
+	if len(windows) == 0 {
+		windows = DefaultWindows
+	}
+
+	retention := time.Duration(0)
+	for _, w := range windows {
+		if w.Duration > retention {
+			retention = w.Duration
+		}
+	}
+
+	enabled := make(map[Dimension]bool, len(dimensions))
+	for _, d := range dimensions {
+		enabled[d] = true
+	}
+
+	return &Tracker{
+		client:     client,
+		store:      store,
+		windows:    windows,
+		dimensions: enabled,
+		retention:  retention,
+		logger:     logger,
+		dirty:      make(map[Entity]bool),
+	}
+}
+
+// Windows returns the windows maintained by the tracker
+func (t *Tracker) Windows() []Window {
+	return t.windows
+}
+
+// Degraded reports whether the tracker is currently running without Redis
+func (t *Tracker) Degraded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.degraded
+}
+
+// Snapshot returns the current stats for every tracked entity over all
+// windows. It falls back to Postgres when Redis is unavailable and only
+// returns an error when neither store can be read.
+func (t *Tracker) Snapshot(ctx context.Context, entities []Entity, now time.Time) (Snapshot, Source, error) {
+	entities = t.filter(entities)
+	if len(entities) == 0 {
+		return make(Snapshot), SourceRedis, nil
+	}
+
+	if !t.Degraded() {
+		snapshot, err := t.redisSnapshot(ctx, entities, now)
+		if err == nil {
+			return snapshot, SourceRedis, nil
+		}
+		t.markDegraded(err)
+	}
+
+	snapshot, err := t.store.Snapshot(ctx, entities, t.windows, t.retention, now)
+	if err != nil {
+		return nil, "", err
+	}
+	DegradedEvaluations.WithLabelValues(ModePostgresFallback).Inc()
+	return snapshot, SourcePostgres, nil
+}
+
+func (t *Tracker) redisSnapshot(ctx context.Context, entities []Entity, now time.Time) (Snapshot, error) {
+	snapshot := make(Snapshot, len(entities))
+	oldest := now.Add(-t.retention).UnixMilli()
+
+	pipe := t.client.Pipeline()
+	cmds := make([]*redis.ZSliceCmd, len(entities))
+	for i, e := range entities {
+		key := t.key(e)
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(oldest, 10))
+		cmds[i] = pipe.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Min: strconv.FormatInt(oldest, 10),
+			Max: "+inf",
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read velocity counters: %w", err)
+	}
+
+	for i, e := range entities {
+		stats := make(map[string]Stats, len(t.windows))
+		for _, w := range t.windows {
+			stats[w.Name] = Stats{}
+		}
+
+		for _, z := range cmds[i].Val() {
+			member, _ := z.Member.(string)
+			count, amount := memberStats(member)
+			for _, w := range t.windows {
+				if int64(z.Score) >= now.Add(-w.Duration).UnixMilli() {
+					s := stats[w.Name]
+					s.add(count, amount)
+					stats[w.Name] = s
+				}
+			}
+		}
+
+		snapshot[e.Dimension] = stats
+	}
+
+	return snapshot, nil
+}
+
+// Record adds a payment to the counters of every tracked entity, in Postgres
+// first and then in Redis. Recording the same payment twice in Redis is a
+// no-op since the member is keyed by payment id.
+func (t *Tracker) Record(ctx context.Context, entities []Entity, paymentID string, amount money.Money, at time.Time) error {
+	entities = t.filter(entities)
+	if len(entities) == 0 {
+		return nil
+	}
+
+	storeErr := t.store.Record(ctx, entities, amount, at)
+
+	if t.Degraded() {
+		t.markDirty(entities)
+		return storeErr
+	}
+
+	if err := t.redisRecord(ctx, entities, paymentID, amount, at); err != nil {
+		t.markDegraded(err)
+		t.markDirty(entities)
+		if storeErr != nil {
+			return fmt.Errorf("%v; %w", storeErr, err)
+		}
+	}
+
+	return storeErr
+}
+
+func (t *Tracker) redisRecord(ctx context.Context, entities []Entity, paymentID string, amount money.Money, at time.Time) error {
+	member := paymentID + ":" + strconv.FormatInt(amount.Minor, 10) + ":" + amount.Currency
+	oldest := at.Add(-t.retention).UnixMilli()
+
+	pipe := t.client.TxPipeline()
+	for _, e := range entities {
+		key := t.key(e)
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(at.UnixMilli()), Member: member})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(oldest, 10))
+		pipe.Expire(ctx, key, t.retention)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record velocity counters: %w", err)
+	}
+
+	return nil
+}
+
+// Run periodically checks whether Redis is back while degraded, rebuilds the
+// keys that changed during the outage and prunes expired Postgres buckets
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if t.Degraded() {
+			if err := t.client.Ping(ctx).Err(); err == nil {
+				t.rehydrate(ctx)
+			}
+		}
+
+		if time.Since(lastCleanup) >= t.retention/4 {
+			lastCleanup = time.Now()
+			deleted, err := t.store.Cleanup(ctx, time.Now().Add(-t.retention))
+			if err != nil {
+				t.logger.Warn("Failed to clean up velocity counters", zap.Error(err))
+			} else if deleted > 0 {
+				t.logger.Info("Cleaned up velocity counters", zap.Int64("deleted", deleted))
+			}
+		}
+	}
+}
+
+// rehydrate replaces the Redis keys of every entity that changed while
+// degraded with the persisted buckets. Records that happen concurrently mark
+// their entities dirty again, so the tracker only leaves degraded mode once a
+// pass completes with nothing left to rebuild.
+func (t *Tracker) rehydrate(ctx context.Context) {
+	t.mu.Lock()
+	entities := make([]Entity, 0, len(t.dirty))
+	for e := range t.dirty {
+		entities = append(entities, e)
+	}
+	t.dirty = make(map[Entity]bool)
+	t.mu.Unlock()
+
+	now := time.Now()
+	for _, e := range entities {
+		if err := t.rebuildKey(ctx, e, now); err != nil {
+			t.logger.Warn("Failed to rehydrate velocity counter",
+				zap.String("dimension", string(e.Dimension)),
+				zap.String("entity_id", e.ID),
+				zap.Error(err),
+			)
+			t.markDirty([]Entity{e})
+			return
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.dirty) == 0 {
+		t.degraded = false
+		t.logger.Info("Redis velocity counters recovered", zap.Int("rehydrated", len(entities)))
+	}
+}
+
+func (t *Tracker) rebuildKey(ctx context.Context, e Entity, now time.Time) error {
+	buckets, err := t.store.Buckets(ctx, e, now.Add(-t.retention))
+	if err != nil {
+		return err
+	}
+
+	key := t.key(e)
+	pipe := t.client.TxPipeline()
+	pipe.Del(ctx, key)
+	for _, b := range buckets {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(b.Start.UnixMilli()), Member: bucketMember(b)})
+	}
+	pipe.Expire(ctx, key, t.retention)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (t *Tracker) markDegraded(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.degraded {
+		t.degraded = true
+		t.logger.Warn("Redis unavailable, velocity counters falling back to Postgres", zap.Error(err))
+	}
+}
+
+func (t *Tracker) markDirty(entities []Entity) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range entities {
+		t.dirty[e] = true
+	}
+}
+
+func (t *Tracker) filter(entities []Entity) []Entity {
+	filtered := make([]Entity, 0, len(entities))
+	for _, e := range entities {
+		if e.ID != "" && t.dimensions[e.Dimension] {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+func (t *Tracker) key(e Entity) string {
+	return fmt.Sprintf("fraud:velocity:%s:%s", e.Dimension, e.ID)
+}
+
+// memberStats decodes a sorted set member. Payments are stored as
+// "<payment_id>:<amount_minor>:<currency>", buckets rebuilt from Postgres as
+// "bucket:<start>:<currency>:<amount_minor>:<count>". Members written before
+// amounts were kept per currency only count.
+func memberStats(member string) (int64, money.Money) {
+	if strings.HasPrefix(member, "bucket:") {
+		parts := strings.Split(member, ":")
+		if len(parts) != 5 {
+			count, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+			return count, money.Money{}
+		}
+		minor, _ := strconv.ParseInt(parts[3], 10, 64)
+		count, _ := strconv.ParseInt(parts[4], 10, 64)
+		return count, amountOf(minor, parts[2])
+	}
+
+	rest, currency, ok := cutLast(member)
+	if !ok {
+		return 1, money.Money{}
+	}
+	_, minorPart, ok := cutLast(rest)
+	if !ok {
+		return 1, money.Money{}
+	}
+	minor, err := strconv.ParseInt(minorPart, 10, 64)
+	if err != nil {
+		return 1, money.Money{}
+	}
+	return 1, amountOf(minor, currency)
+}
+
+// bucketMember encodes a persisted bucket as a sorted set member, see
+// memberStats
+func bucketMember(b Bucket) string {
+	return fmt.Sprintf("bucket:%d:%s:%d:%d", b.Start.UnixMilli(), b.Amount.Currency, b.Amount.Minor, b.Count)
+}
+
+// amountOf returns minor units of currency, or no amount for an unknown
+// or empty currency
+func amountOf(minor int64, currency string) money.Money {
+	m, err := money.New(minor, currency)
+	if err != nil {
+		return money.Money{}
+	}
+	return m
+}
+
+func cutLast(s string) (before, after string, found bool) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+1:], true
+}
@@ -0,0 +1,136 @@
+package velocity
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// newRedisTracker returns a tracker whose Redis counters are backed by
+// miniredis. It has no Postgres store, so tests call the Redis paths
+// directly.
+func newRedisTracker(t *testing.T, dimensions ...Dimension) (*Tracker, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewTracker(client, nil, zap.NewNop(), nil, dimensions), mr
+}
+
+func TestRedisSlidingWindows(t *testing.T) {
+	tracker, _ := newRedisTracker(t, DimensionCustomer)
+	ctx := context.Background()
+	now := time.Now()
+	customer := []Entity{{Dimension: DimensionCustomer, ID: "cust-1"}}
+
+	payments := []struct {
+		id     string
+		amount money.Money
+		age    time.Duration
+	}{
+		{"p1", money.Money{Minor: 1000, Currency: "USD"}, 30 * time.Second},
+		{"p2", money.Money{Minor: 500, Currency: "JPY"}, 30 * time.Minute},
+		{"p3", money.Money{Minor: 2000, Currency: "USD"}, 12 * time.Hour},
+		// Older than the longest window
+		{"p4", money.Money{Minor: 9000, Currency: "USD"}, 25 * time.Hour},
+	}
+	for _, p := range payments {
+		if err := tracker.redisRecord(ctx, customer, p.id, p.amount, now.Add(-p.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Recording a payment again does not count it twice
+	if err := tracker.redisRecord(ctx, customer, "p1", payments[0].amount, now.Add(-payments[0].age)); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := tracker.redisSnapshot(ctx, customer, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]Stats{
+		"1m":  {Count: 1, Amounts: map[string]int64{"USD": 1000}},
+		"1h":  {Count: 2, Amounts: map[string]int64{"USD": 1000, "JPY": 500}},
+		"24h": {Count: 3, Amounts: map[string]int64{"USD": 3000, "JPY": 500}},
+	}
+	for window, stats := range want {
+		if got := snapshot.Get(DimensionCustomer, window); !reflect.DeepEqual(got, stats) {
+			t.Fatalf("%s window = %+v, want %+v", window, got, stats)
+		}
+	}
+}
+
+func TestRedisCountersPerEntity(t *testing.T) {
+	tracker, mr := newRedisTracker(t, DimensionCustomer, DimensionIP)
+	ctx := context.Background()
+	now := time.Now()
+	usd := money.Money{Minor: 100, Currency: "USD"}
+
+	entities := []Entity{
+		{Dimension: DimensionCustomer, ID: "cust-1"},
+		{Dimension: DimensionIP, ID: "10.0.0.1"},
+		// Not tracked: disabled dimension and empty id
+		{Dimension: DimensionDevice, ID: "dev-1"},
+		{Dimension: DimensionCustomer, ID: ""},
+	}
+	entities = tracker.filter(entities)
+	if len(entities) != 2 {
+		t.Fatalf("filter kept %v, want the customer and the IP", entities)
+	}
+	if err := tracker.redisRecord(ctx, entities, "p1", usd, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.redisRecord(ctx, entities[:1], "p2", usd, now); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := tracker.redisSnapshot(ctx, entities, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := snapshot.Get(DimensionCustomer, "1h").Count; got != 2 {
+		t.Fatalf("customer count = %d, want 2", got)
+	}
+	if got := snapshot.Get(DimensionIP, "1h").Count; got != 1 {
+		t.Fatalf("IP count = %d, want 1", got)
+	}
+	if !mr.Exists("fraud:velocity:customer:cust-1") || mr.Exists("fraud:velocity:device:dev-1") {
+		t.Fatalf("unexpected keys %v", mr.Keys())
+	}
+	if ttl := mr.TTL("fraud:velocity:ip:10.0.0.1"); ttl != 24*time.Hour {
+		t.Fatalf("key TTL = %v, want the longest window", ttl)
+	}
+}
+
+func TestMemberStats(t *testing.T) {
+	tests := []struct {
+		member string
+		count  int64
+		amount money.Money
+	}{
+		{"pay-1:10010:USD", 1, money.Money{Minor: 10010, Currency: "USD"}},
+		// Payment ids may contain colons
+		{"order:42:500:JPY", 1, money.Money{Minor: 500, Currency: "JPY"}},
+		// Members recorded before amounts were kept per currency only count
+		{"pay-1:100.10", 1, money.Money{}},
+		{"pay-1", 1, money.Money{}},
+		{"pay-1:10010:XXX", 1, money.Money{}},
+		{"bucket:1700000000000:KWD:1250:3", 3, money.Money{Minor: 1250, Currency: "KWD"}},
+		{"bucket:1700000000000:100.10:4", 4, money.Money{}},
+	}
+	for _, tt := range tests {
+		count, amount := memberStats(tt.member)
+		if count != tt.count || amount != tt.amount {
+			t.Fatalf("memberStats(%q) = %d, %v %s; want %d, %v %s",
+				tt.member, count, amount, amount.Currency, tt.count, tt.amount, tt.amount.Currency)
+		}
+	}
+}
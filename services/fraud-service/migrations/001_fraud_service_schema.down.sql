@@ -0,0 +1,10 @@
+-- Reverts 001_fraud_service_schema
+
+DROP VIEW IF EXISTS fraud_statistics;
+DROP TABLE IF EXISTS review_cases;
+DROP TABLE IF EXISTS fraud_list_entries;
+DROP TABLE IF EXISTS velocity_counters;
+DROP TABLE IF EXISTS fraud_labels;
+DROP TABLE IF EXISTS fraud_decisions;
+DROP TABLE IF EXISTS fraud_rules;
+DROP FUNCTION IF EXISTS update_updated_at_column();
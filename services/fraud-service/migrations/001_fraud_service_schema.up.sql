@@ -0,0 +1,197 @@
+-- Fraud Service Database Schema
+-- Version: 001
+-- Description: Schema for Fraud Detection service
+
+-- =====================================================
+-- FRAUD SERVICE TABLES
+-- =====================================================
+
+-- Fraud rules
+CREATE TABLE IF NOT EXISTS fraud_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    rule_type VARCHAR(50) NOT NULL DEFAULT 'custom',
+    max_amount DECIMAL(15,2),
+    max_per_hour INTEGER,
+    max_per_day INTEGER,
+    countries_blacklist TEXT[],
+    countries_whitelist TEXT[],
+    priority INTEGER DEFAULT 0,
+    active BOOLEAN DEFAULT TRUE,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Columns missing from tables created by the service before migrations
+ALTER TABLE fraud_rules ADD COLUMN IF NOT EXISTS rule_type VARCHAR(50) NOT NULL DEFAULT 'custom';
+ALTER TABLE fraud_rules ADD COLUMN IF NOT EXISTS max_per_day INTEGER;
+ALTER TABLE fraud_rules ADD COLUMN IF NOT EXISTS countries_blacklist TEXT[];
+ALTER TABLE fraud_rules ADD COLUMN IF NOT EXISTS countries_whitelist TEXT[];
+ALTER TABLE fraud_rules ADD COLUMN IF NOT EXISTS priority INTEGER DEFAULT 0;
+ALTER TABLE fraud_rules ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- Composite index for active rules ordered by priority
+CREATE INDEX IF NOT EXISTS idx_fraud_rules_active_priority ON fraud_rules(active, priority DESC) 
+    WHERE active = TRUE;
+
+-- Fraud decisions
+CREATE TABLE IF NOT EXISTS fraud_decisions (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id VARCHAR(255) NOT NULL,
+    customer_id VARCHAR(255) NOT NULL,
+    merchant_id VARCHAR(255),
+    amount DECIMAL(15,2) NOT NULL,
+    currency VARCHAR(3) DEFAULT 'USD',
+    decision VARCHAR(50) NOT NULL,
+    reason TEXT,
+    risk_score INTEGER CHECK (risk_score >= 0 AND risk_score <= 100),
+    rules_triggered TEXT[],
+    rule_results JSONB,
+    metadata JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE fraud_decisions ADD COLUMN IF NOT EXISTS merchant_id VARCHAR(255);
+ALTER TABLE fraud_decisions ADD COLUMN IF NOT EXISTS currency VARCHAR(3) DEFAULT 'USD';
+ALTER TABLE fraud_decisions ADD COLUMN IF NOT EXISTS rules_triggered TEXT[];
+ALTER TABLE fraud_decisions ADD COLUMN IF NOT EXISTS rule_results JSONB;
+ALTER TABLE fraud_decisions ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+-- Primary lookup by payment_id
+CREATE INDEX IF NOT EXISTS idx_fraud_decisions_payment_id ON fraud_decisions(payment_id);
+-- Composite for customer fraud history
+CREATE INDEX IF NOT EXISTS idx_fraud_decisions_customer_created ON fraud_decisions(customer_id, created_at DESC);
+-- Time window scans for performance reports and /fraud/stats
+CREATE INDEX IF NOT EXISTS idx_fraud_decisions_created_at ON fraud_decisions(created_at);
+CREATE INDEX IF NOT EXISTS idx_fraud_decisions_merchant_created ON fraud_decisions(merchant_id, created_at);
+
+-- Feedback labels (chargeback, confirmed_fraud, false_positive)
+CREATE TABLE IF NOT EXISTS fraud_labels (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id VARCHAR(255) NOT NULL,
+    decision_id BIGINT NOT NULL REFERENCES fraud_decisions(id),
+    label VARCHAR(50) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    reason TEXT,
+    reported_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (payment_id, label)
+);
+
+-- Velocity counters (backup for Redis)
+CREATE TABLE IF NOT EXISTS velocity_counters (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(255) NOT NULL,
+    counter_type VARCHAR(50) NOT NULL,
+    count INTEGER DEFAULT 0,
+    amount DECIMAL(15,2) DEFAULT 0,
+    window_start TIMESTAMP NOT NULL,
+    window_end TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE velocity_counters ADD COLUMN IF NOT EXISTS amount DECIMAL(15,2) DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_velocity_counters_entity ON velocity_counters(entity_type, entity_id);
+-- One row per entity and minute bucket (upsert target)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_velocity_counters_bucket ON velocity_counters(entity_type, entity_id, counter_type, window_start);
+CREATE INDEX IF NOT EXISTS idx_velocity_counters_window ON velocity_counters(window_start, window_end);
+
+-- Blocklists and allowlists (country, customer, email, email_domain, ip, bin)
+CREATE TABLE IF NOT EXISTS fraud_list_entries (
+    id BIGSERIAL PRIMARY KEY,
+    list_type VARCHAR(10) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    reason TEXT,
+    created_by VARCHAR(255),
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (list_type, entity_type, value)
+);
+
+-- Manual review queue
+CREATE TABLE IF NOT EXISTS review_cases (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id VARCHAR(255) NOT NULL UNIQUE,
+    customer_id VARCHAR(255) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    reason TEXT,
+    risk_score INTEGER,
+    status VARCHAR(50) NOT NULL,
+    assigned_to VARCHAR(255),
+    notes TEXT,
+    sla_deadline TIMESTAMP NOT NULL,
+    published BOOLEAN DEFAULT FALSE,
+    claimed_at TIMESTAMP,
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Queue listing and SLA sweeps
+CREATE INDEX IF NOT EXISTS idx_review_cases_status_deadline ON review_cases(status, sla_deadline);
+
+-- =====================================================
+-- FUNCTIONS AND TRIGGERS
+-- =====================================================
+
+-- Function to update updated_at timestamp
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Triggers for updated_at
+DROP TRIGGER IF EXISTS update_fraud_rules_updated_at ON fraud_rules;
+CREATE TRIGGER update_fraud_rules_updated_at BEFORE UPDATE ON fraud_rules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_velocity_counters_updated_at ON velocity_counters;
+CREATE TRIGGER update_velocity_counters_updated_at BEFORE UPDATE ON velocity_counters
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- =====================================================
+-- INITIAL DATA
+-- =====================================================
+
+-- Insert default fraud rules unless the table was seeded before
+INSERT INTO fraud_rules (name, rule_type, max_amount, max_per_hour, description, priority)
+SELECT * FROM (VALUES
+    ('High Amount Check', 'amount_limit', 10000.00, NULL::INTEGER, 'Flag payments over $10,000', 100),
+    ('Medium Amount Check', 'amount_limit', 5000.00, NULL::INTEGER, 'Review payments over $5,000', 50),
+    ('Velocity Check Hourly', 'velocity', NULL::DECIMAL, 5, 'Max 5 payments per hour per customer', 80)
+) AS defaults
+WHERE NOT EXISTS (SELECT 1 FROM fraud_rules);
+
+-- =====================================================
+-- VIEWS FOR ANALYTICS
+-- =====================================================
+
+-- Fraud statistics view
+CREATE OR REPLACE VIEW fraud_statistics AS
+SELECT 
+    DATE(created_at) as date,
+    decision,
+    COUNT(*) as count,
+    AVG(risk_score) as avg_risk_score,
+    SUM(amount) as total_amount
+FROM fraud_decisions
+GROUP BY DATE(created_at), decision
+ORDER BY date DESC;
+
+-- =====================================================
+-- COMMENTS
+-- =====================================================
+
+COMMENT ON TABLE fraud_rules IS 'Configuration rules for fraud detection';
+COMMENT ON TABLE fraud_decisions IS 'Fraud detection results for each payment';
+COMMENT ON TABLE fraud_labels IS 'Post-decision ground truth used to measure rule precision and recall';
+COMMENT ON TABLE velocity_counters IS 'Backup velocity counters (primary storage in Redis)';
+COMMENT ON TABLE review_cases IS 'Manual review queue with analyst decisions';
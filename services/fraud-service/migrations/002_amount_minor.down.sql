@@ -0,0 +1,19 @@
+-- Reverts 002_amount_minor
+
+ALTER TABLE review_cases DROP COLUMN IF EXISTS currency;
+ALTER TABLE review_cases DROP COLUMN IF EXISTS amount_minor;
+ALTER TABLE review_cases ALTER COLUMN amount TYPE DECIMAL(15,2);
+
+ALTER TABLE fraud_decisions DROP COLUMN IF EXISTS amount_minor;
+DROP VIEW IF EXISTS fraud_statistics;
+ALTER TABLE fraud_decisions ALTER COLUMN amount TYPE DECIMAL(15,2);
+CREATE VIEW fraud_statistics AS
+SELECT 
+    DATE(created_at) as date,
+    decision,
+    COUNT(*) as count,
+    AVG(risk_score) as avg_risk_score,
+    SUM(amount) as total_amount
+FROM fraud_decisions
+GROUP BY DATE(created_at), decision
+ORDER BY date DESC;
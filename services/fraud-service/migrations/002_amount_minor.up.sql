@@ -0,0 +1,51 @@
+-- Store checked amounts as integer minor units of their currency. amount
+-- stays for reporting and is widened to three decimals for currencies
+-- such as KWD.
+
+-- fraud_statistics depends on fraud_decisions.amount and is recreated
+-- around the type change
+DROP VIEW IF EXISTS fraud_statistics;
+ALTER TABLE fraud_decisions ALTER COLUMN amount TYPE DECIMAL(20,3);
+CREATE VIEW fraud_statistics AS
+SELECT 
+    DATE(created_at) as date,
+    decision,
+    COUNT(*) as count,
+    AVG(risk_score) as avg_risk_score,
+    SUM(amount) as total_amount
+FROM fraud_decisions
+GROUP BY DATE(created_at), decision
+ORDER BY date DESC;
+ALTER TABLE fraud_decisions ADD COLUMN IF NOT EXISTS amount_minor BIGINT;
+
+ALTER TABLE review_cases ALTER COLUMN amount TYPE DECIMAL(20,3);
+ALTER TABLE review_cases ADD COLUMN IF NOT EXISTS amount_minor BIGINT;
+ALTER TABLE review_cases ADD COLUMN IF NOT EXISTS currency VARCHAR(3);
+
+UPDATE review_cases rc
+SET currency = COALESCE(
+    (SELECT fd.currency FROM fraud_decisions fd
+     WHERE fd.payment_id = rc.payment_id AND fd.currency IS NOT NULL
+     ORDER BY fd.created_at DESC LIMIT 1),
+    'USD')
+WHERE rc.currency IS NULL;
+
+UPDATE fraud_decisions
+SET amount_minor = ROUND(amount * CASE
+        WHEN currency IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+                          'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1
+        WHEN currency IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        ELSE 100
+    END)
+WHERE amount_minor IS NULL;
+
+UPDATE review_cases
+SET amount_minor = ROUND(amount * CASE
+        WHEN currency IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+                          'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1
+        WHEN currency IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        ELSE 100
+    END)
+WHERE amount_minor IS NULL;
+
+ALTER TABLE review_cases ALTER COLUMN amount_minor SET NOT NULL;
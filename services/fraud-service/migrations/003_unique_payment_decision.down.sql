@@ -0,0 +1,5 @@
+-- Reverts 003_unique_payment_decision
+
+-- Duplicate decisions removed by the up migration are not restored
+ALTER TABLE fraud_decisions DROP CONSTRAINT IF EXISTS fraud_decisions_payment_id_key;
+CREATE INDEX IF NOT EXISTS idx_fraud_decisions_payment_id ON fraud_decisions(payment_id);
@@ -0,0 +1,20 @@
+-- One fraud decision per payment. A repeated check answers with the stored
+-- decision instead of recording the payment again, which also counted it
+-- twice towards velocity limits.
+
+-- Keep the latest decision of each payment, the one the API returned
+CREATE TEMPORARY TABLE latest_fraud_decisions ON COMMIT DROP AS
+SELECT DISTINCT ON (payment_id) payment_id, id
+FROM fraud_decisions
+ORDER BY payment_id, created_at DESC NULLS LAST, id DESC;
+
+UPDATE fraud_labels l
+SET decision_id = latest.id
+FROM latest_fraud_decisions latest
+WHERE l.payment_id = latest.payment_id AND l.decision_id <> latest.id;
+
+DELETE FROM fraud_decisions d
+WHERE NOT EXISTS (SELECT 1 FROM latest_fraud_decisions latest WHERE latest.id = d.id);
+
+DROP INDEX IF EXISTS idx_fraud_decisions_payment_id;
+ALTER TABLE fraud_decisions ADD CONSTRAINT fraud_decisions_payment_id_key UNIQUE (payment_id);
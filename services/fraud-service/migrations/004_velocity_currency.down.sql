@@ -0,0 +1,24 @@
+-- Reverts 004_velocity_currency
+
+-- Merge the currencies of each bucket back into one row; amounts of
+-- different currencies cannot be summed and are dropped
+DROP INDEX IF EXISTS idx_velocity_counters_bucket;
+WITH merged AS (
+    SELECT MIN(id) AS id, SUM(count) AS count
+    FROM velocity_counters
+    GROUP BY entity_type, entity_id, counter_type, window_start
+)
+UPDATE velocity_counters v
+SET count = merged.count, amount = 0
+FROM merged
+WHERE v.id = merged.id;
+DELETE FROM velocity_counters v
+USING velocity_counters o
+WHERE o.entity_type = v.entity_type AND o.entity_id = v.entity_id
+  AND o.counter_type = v.counter_type AND o.window_start = v.window_start
+  AND o.id < v.id;
+CREATE UNIQUE INDEX idx_velocity_counters_bucket
+    ON velocity_counters(entity_type, entity_id, counter_type, window_start);
+
+ALTER TABLE velocity_counters DROP COLUMN IF EXISTS amount_minor;
+ALTER TABLE velocity_counters DROP COLUMN IF EXISTS currency;
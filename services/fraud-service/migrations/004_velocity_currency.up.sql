@@ -0,0 +1,9 @@
+-- Sum velocity amounts per currency in minor units. Buckets recorded
+-- before this migration have no currency and only add to the count.
+ALTER TABLE velocity_counters ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT '';
+ALTER TABLE velocity_counters ADD COLUMN IF NOT EXISTS amount_minor BIGINT NOT NULL DEFAULT 0;
+
+-- One row per entity, minute bucket and currency (upsert target)
+DROP INDEX IF EXISTS idx_velocity_counters_bucket;
+CREATE UNIQUE INDEX idx_velocity_counters_bucket
+    ON velocity_counters(entity_type, entity_id, counter_type, window_start, currency);
@@ -7,27 +7,41 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/lib/pq"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/segmentio/kafka-go"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 
-	"github.com/akylbek/payment-system/ledger-service/internal/telemetry"
+	"github.com/akylbek/payment-system/ledger-service/migrations"
+	"github.com/akylbek/payment-system/pkg/platform/consumer"
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/httpserver"
+	"github.com/akylbek/payment-system/pkg/platform/kafkaclient"
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+	"github.com/akylbek/payment-system/pkg/platform/migrate"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+	"github.com/akylbek/payment-system/pkg/platform/postgres"
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
 )
 
 type PaymentStateChangedEvent struct {
-	PaymentID     string    `json:"payment_id"`
-	State         string    `json:"state"`
-	PreviousState string    `json:"previous_state"`
-	Timestamp     time.Time `json:"timestamp"`
+	PaymentID     string        `json:"payment_id"`
+	State         string        `json:"state"`
+	PreviousState string        `json:"previous_state"`
+	Amount        money.Decimal `json:"amount"`
+	money.Money
+	Timestamp time.Time `json:"timestamp"`
 }
 
+// platformFeeBasis is the platform fee in basis points of the payment
+const platformFeeBasis = 200
+
+// defaultCurrency is the currency of the accounts created before accounts
+// were kept per currency
+const defaultCurrency = "USD"
+
 type LedgerEntry struct {
 	ID         int64
 	AccountID  string
@@ -56,37 +70,46 @@ func main() {
 	}
 	defer telemetry.Shutdown(context.Background())
 
+	brokers := kafkaclient.Brokers(os.Getenv("KAFKA_BROKERS"))
+
+	// Inspect or replay dead-lettered messages instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		if err := consumer.RunCommand(context.Background(), os.Args[2:], brokers, os.Stdout); err != nil {
+			telemetry.Logger.Fatal("dlq command failed", zap.Error(err))
+		}
+		return
+	}
+
 	telemetry.Logger.Info("Starting Ledger Service")
 
 	// Connect to PostgreSQL
-	dbURL := os.Getenv("DATABASE_URL")
-	db, err = sql.Open("postgres", dbURL)
+	db, err = postgres.Open(os.Getenv("DATABASE_URL"))
 	if err != nil {
 		telemetry.Logger.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer db.Close()
 
-	// Initialize database
-	if err := initDB(); err != nil {
-		telemetry.Logger.Fatal("Failed to initialize database", zap.Error(err))
+	migrator, err := migrate.New(db, migrations.FS, telemetry.Logger)
+	if err != nil {
+		telemetry.Logger.Fatal("Failed to load migrations", zap.Error(err))
 	}
 
-	// Start Kafka consumer
-	go consumePaymentStateChanges()
-
-	// Setup Gin router
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(telemetry.TracingMiddleware())
+	// Inspect, apply or roll back migrations instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.RunCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			telemetry.Logger.Fatal("migrate command failed", zap.Error(err))
+		}
+		return
+	}
 
-	// Prometheus metrics endpoint
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	if err := migrator.Up(context.Background()); err != nil {
+		telemetry.Logger.Fatal("Failed to migrate database", zap.Error(err))
+	}
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "ledger-service"})
-	})
+	checker := health.New("ledger-service", 2*time.Second)
+	checker.Add("postgres", postgres.Check(db))
+	checker.Add("kafka", kafkaclient.Check(brokers))
 
+	r := httpserver.NewRouter(checker)
 	r.GET("/accounts/:id/balance", getAccountBalance)
 	r.GET("/accounts/:id/entries", getAccountEntries)
 	r.GET("/payments/:id/entries", getPaymentEntries)
@@ -96,174 +119,212 @@ func main() {
 		port = "8084"
 	}
 
-	// Setup HTTP server
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: r,
-	}
-
-	// Start server in goroutine
-	go func() {
-		telemetry.Logger.Info("Ledger Service starting", zap.String("port", port))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			telemetry.Logger.Fatal("Failed to start server", zap.Error(err))
-		}
-	}()
+	paymentStates := consumer.New(consumer.Config{
+		Brokers: brokers,
+		Topic:   "payment.state.changed",
+		GroupID: "ledger-service",
+	}, telemetry.Logger)
 
-	// Wait for interrupt signal for graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	timeout := os.Getenv("SHUTDOWN_TIMEOUT")
+	if timeout == "" {
+		timeout = "10s"
+	}
+	shutdownTimeout, err := time.ParseDuration(timeout)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid SHUTDOWN_TIMEOUT", zap.Error(err))
+	}
+	delay := os.Getenv("DRAIN_DELAY")
+	if delay == "" {
+		delay = "0s"
+	}
+	drainDelay, err := time.ParseDuration(delay)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid DRAIN_DELAY", zap.Error(err))
+	}
 
-	telemetry.Logger.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	
-	if err := srv.Shutdown(ctx); err != nil {
-		telemetry.Logger.Error("Server forced to shutdown", zap.Error(err))
+	app := lifecycle.New(telemetry.Logger, shutdownTimeout)
+	app.Append(
+		postgres.CloseHook(db),
+		paymentStates.Hook(handlePaymentStateChanged),
+		httpserver.Hook(port, r),
+		checker.Hook(drainDelay),
+	)
+	if err := app.Run(context.Background()); err != nil {
+		telemetry.Logger.Fatal("Ledger Service stopped with errors", zap.Error(err))
 	}
-	
+
 	telemetry.Logger.Info("Server exited")
 }
 
-func initDB() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS accounts (
-			id VARCHAR(255) PRIMARY KEY,
-			type VARCHAR(50) NOT NULL,
-			balance DECIMAL(20,2) DEFAULT 0,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_accounts_type ON accounts(type)`,
-		
-		`CREATE TABLE IF NOT EXISTS ledger_entries (
-			id BIGSERIAL PRIMARY KEY,
-			account_id VARCHAR(255) NOT NULL,
-			payment_id VARCHAR(255) NOT NULL,
-			type VARCHAR(50) NOT NULL,
-			amount DECIMAL(20,2) NOT NULL,
-			balance DECIMAL(20,2) NOT NULL,
-			idempotency_key VARCHAR(255) UNIQUE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_id ON ledger_entries(account_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_ledger_entries_payment_id ON ledger_entries(payment_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_ledger_entries_idempotency_key ON ledger_entries(idempotency_key)`,
-	}
-
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return err
-		}
+func handlePaymentStateChanged(ctx context.Context, msg kafka.Message) error {
+	var event PaymentStateChangedEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return consumer.Permanent(fmt.Errorf("unmarshal state change event: %w", err))
 	}
 
-	// Create default platform account
-	db.Exec(`
-		INSERT INTO accounts (id, type, balance)
-		VALUES ('platform-001', 'platform', 0)
-		ON CONFLICT (id) DO NOTHING
-	`)
+	// Only succeeded and refunded payments move money
+	var record func(context.Context, *PaymentStateChangedEvent) error
+	switch event.State {
+	case "SUCCEEDED":
+		record = recordPaymentSuccess
+	case "REFUNDED":
+		record = recordPaymentRefund
+	default:
+		return nil
+	}
 
-	return nil
+	telemetry.Logger.Info("Processing ledger entry",
+		zap.String("payment_id", event.PaymentID),
+		zap.String("state", event.State),
+	)
+	return record(ctx, &event)
 }
 
-func consumePaymentStateChanges() {
-	kafkaBrokers := os.Getenv("KAFKA_BROKERS")
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:  []string{kafkaBrokers},
-		Topic:    "payment.state.changed",
-		GroupID:  "ledger-service",
-		MinBytes: 10e3,
-		MaxBytes: 10e6,
+func recordPaymentSuccess(ctx context.Context, event *PaymentStateChangedEvent) error {
+	if len(event.PaymentID) < 8 {
+		return consumer.Permanent(fmt.Errorf("invalid payment_id %q", event.PaymentID))
+	}
+
+	amount, err := money.New(event.Minor, event.Currency)
+	if err != nil {
+		return consumer.Permanent(fmt.Errorf("payment %s amount: %w", event.PaymentID, err))
+	}
+	// Orchestrators predating amount_minor published no amount at all
+	if !amount.IsPositive() {
+		return consumer.Permanent(fmt.Errorf("payment %s has no amount", event.PaymentID))
+	}
+	platformFee := amount.Percent(platformFeeBasis)
+	merchantAmount := money.Money{Minor: amount.Minor - platformFee.Minor, Currency: amount.Currency}
+
+	merchantAccount := accountID("merchant-"+event.PaymentID[:8], amount.Currency)
+	platformAccount := accountID("platform-001", amount.Currency)
+
+	// Ensure both accounts exist
+	for _, account := range []struct{ id, kind string }{
+		{merchantAccount, "merchant"},
+		{platformAccount, "platform"},
+	} {
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO accounts (id, type, currency, balance)
+			VALUES ($1, $2, $3, 0)
+			ON CONFLICT (id) DO NOTHING
+		`, account.id, account.kind, amount.Currency); err != nil {
+			return err
+		}
+	}
+
+	// Credit the merchant with the amount and the platform with its fee
+	err = postEntries(ctx, event.PaymentID, "credit", []posting{
+		{account: merchantAccount, accountType: "merchant", amount: merchantAmount, key: successKey(event.PaymentID, "merchant")},
+		{account: platformAccount, accountType: "platform", amount: platformFee, key: successKey(event.PaymentID, "platform")},
 	})
-	defer reader.Close()
+	if err != nil {
+		return err
+	}
 
-	ctx := context.Background()
+	telemetry.Logger.Info("Recorded ledger entries",
+		zap.String("payment_id", event.PaymentID),
+		zap.Stringer("merchant_amount", merchantAmount),
+		zap.Stringer("platform_fee", platformFee),
+		zap.String("currency", amount.Currency),
+	)
 
-	telemetry.Logger.Info("Started consuming payment.state.changed events")
+	return nil
+}
 
-	for {
-		msg, err := reader.ReadMessage(ctx)
-		if err != nil {
-			telemetry.Logger.Error("Error reading message from Kafka", zap.Error(err))
-			continue
+// recordPaymentRefund reverses the entries posted when the payment
+// succeeded, debiting the same accounts by the same amounts. Refunds are
+// always full, so the platform fee is returned too.
+func recordPaymentRefund(ctx context.Context, event *PaymentStateChangedEvent) error {
+	var postings []posting
+	for _, side := range []string{"merchant", "platform"} {
+		p := posting{key: event.PaymentID + "-" + event.State + "-" + side}
+		err := db.QueryRowContext(ctx, `
+			SELECT e.account_id, a.type, e.amount_minor, COALESCE(e.currency, $2)
+			FROM ledger_entries e
+			JOIN accounts a ON a.id = e.account_id
+			WHERE e.idempotency_key = $1
+		`, successKey(event.PaymentID, side), defaultCurrency).Scan(&p.account, &p.accountType, &p.amount.Minor, &p.amount.Currency)
+		if err == sql.ErrNoRows {
+			// The success was never posted, e.g. its event is still in the
+			// dead-letter topic; replay both in order
+			return consumer.Permanent(fmt.Errorf("refund of payment %s: no %s entry to reverse", event.PaymentID, side))
 		}
-
-		var event PaymentStateChangedEvent
-		if err := json.Unmarshal(msg.Value, &event); err != nil {
-			telemetry.Logger.Error("Error unmarshaling event", zap.Error(err))
-			continue
+		if err != nil {
+			return err
 		}
+		postings = append(postings, p)
+	}
 
-		// Only process SUCCEEDED state
-		if event.State == "SUCCEEDED" {
-			telemetry.Logger.Info("Processing ledger entry",
-				zap.String("payment_id", event.PaymentID),
-				zap.String("state", event.State),
-			)
-			if err := recordPaymentSuccess(ctx, &event); err != nil {
-				telemetry.Logger.Error("Error recording payment success",
-					zap.String("payment_id", event.PaymentID),
-					zap.Error(err),
-				)
-			}
-		}
+	if err := postEntries(ctx, event.PaymentID, "debit", postings); err != nil {
+		return err
 	}
+
+	telemetry.Logger.Info("Reversed ledger entries",
+		zap.String("payment_id", event.PaymentID),
+		zap.Stringer("merchant_amount", postings[0].amount),
+		zap.Stringer("platform_fee", postings[1].amount),
+		zap.String("currency", postings[0].amount.Currency),
+	)
+	return nil
 }
 
-func recordPaymentSuccess(ctx context.Context, event *PaymentStateChangedEvent) error {
-	// Get payment details (in real system, this would come from the event or API call)
-	// For now, we'll create mock entries
-	
-	merchantAccount := "merchant-" + event.PaymentID[:8]
-	platformAccount := "platform-001"
-	
-	// Ensure merchant account exists
-	db.Exec(`
-		INSERT INTO accounts (id, type, balance)
-		VALUES ($1, 'merchant', 0)
-		ON CONFLICT (id) DO NOTHING
-	`, merchantAccount)
-
-	// Mock payment amount (in real system, this would come from payment data)
-	amount := decimal.NewFromFloat(100.00)
-	platformFee := decimal.NewFromFloat(2.00)
-	merchantAmount := amount.Sub(platformFee)
-
-	idempotencyKey := event.PaymentID + "-" + event.State
-
-	// Record double-entry bookkeeping
+// successKey is the idempotency key of the entry posted to side (merchant
+// or platform) when the payment succeeded
+func successKey(paymentID, side string) string {
+	return paymentID + "-SUCCEEDED-" + side
+}
+
+// posting is one ledger entry of a payment event
+type posting struct {
+	account     string
+	accountType string
+	amount      money.Money
+	key         string
+}
+
+// postEntries records the entries of a payment event in one transaction.
+// Entries already recorded by an earlier delivery are skipped.
+func postEntries(ctx context.Context, paymentID, entryType string, postings []posting) error {
+	start := time.Now()
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// Credit merchant account
-	if err := recordEntry(tx, merchantAccount, event.PaymentID, "credit", merchantAmount, idempotencyKey+"-merchant"); err != nil {
-		return err
-	}
-
-	// Credit platform account (fee)
-	if err := recordEntry(tx, platformAccount, event.PaymentID, "credit", platformFee, idempotencyKey+"-platform"); err != nil {
-		return err
+	posted := make([]bool, len(postings))
+	for i, p := range postings {
+		if posted[i], err = recordEntry(tx, p.account, paymentID, entryType, p.amount, p.key); err != nil {
+			return err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 
-	telemetry.Logger.Info("Recorded ledger entries",
-		zap.String("payment_id", event.PaymentID),
-		zap.String("merchant_amount", merchantAmount.String()),
-		zap.String("platform_fee", platformFee.String()),
-	)
-
+	postingDuration.Observe(time.Since(start).Seconds())
+	for i, p := range postings {
+		if posted[i] {
+			observeEntry(p.accountType, entryType, p.amount)
+		}
+	}
 	return nil
 }
 
-func recordEntry(tx *sql.Tx, accountID, paymentID, entryType string, amount decimal.Decimal, idempotencyKey string) error {
+// accountID names the account of owner in currency. Accounts in the
+// default currency keep their original IDs.
+func accountID(owner, currency string) string {
+	if currency == defaultCurrency {
+		return owner
+	}
+	return owner + "-" + currency
+}
+
+// recordEntry posts an entry and updates the account balance. It reports
+// false for an entry already recorded by an earlier delivery.
+func recordEntry(tx *sql.Tx, accountID, paymentID, entryType string, entry money.Money, idempotencyKey string) (bool, error) {
 	// Get current balance
 	var balance decimal.Decimal
 	err := tx.QueryRow(`
@@ -271,10 +332,11 @@ func recordEntry(tx *sql.Tx, accountID, paymentID, entryType string, amount deci
 	`, accountID).Scan(&balance)
 
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	// Calculate new balance
+	// Calculate new balance; the decimal string is exact
+	amount := decimal.RequireFromString(entry.String())
 	newBalance := balance
 	if entryType == "credit" {
 		newBalance = balance.Add(amount)
@@ -283,14 +345,19 @@ func recordEntry(tx *sql.Tx, accountID, paymentID, entryType string, amount deci
 	}
 
 	// Insert ledger entry (idempotency check)
-	_, err = tx.Exec(`
-		INSERT INTO ledger_entries (account_id, payment_id, type, amount, balance, idempotency_key)
-		VALUES ($1, $2, $3, $4, $5, $6)
+	result, err := tx.Exec(`
+		INSERT INTO ledger_entries (account_id, payment_id, type, amount, amount_minor, currency, balance, idempotency_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		ON CONFLICT (idempotency_key) DO NOTHING
-	`, accountID, paymentID, entryType, amount, newBalance, idempotencyKey)
+	`, accountID, paymentID, entryType, amount, entry.Minor, entry.Currency, newBalance, idempotencyKey)
 
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	// Entry already recorded by an earlier delivery, keep the balance
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, nil
 	}
 
 	// Update account balance
@@ -299,7 +366,7 @@ func recordEntry(tx *sql.Tx, accountID, paymentID, entryType string, amount deci
 		WHERE id = $2
 	`, newBalance, accountID)
 
-	return err
+	return err == nil, err
 }
 
 func getAccountBalance(c *gin.Context) {
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+var (
+	postingDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ledger_posting_duration_seconds",
+		Help:    "Time to post the entries of a payment in one transaction",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	postedEntries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ledger_entries_posted_total",
+		Help: "Ledger entries posted, by account type and entry type",
+	}, []string{"account_type", "entry_type"})
+
+	postedAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ledger_posted_amount_total",
+		Help: "Sum of posted entry amounts in major units, by account type, entry type and currency",
+	}, []string{"account_type", "entry_type", "currency"})
+)
+
+func observeEntry(accountType, entryType string, amount money.Money) {
+	postedEntries.WithLabelValues(accountType, entryType).Inc()
+	postedAmount.WithLabelValues(accountType, entryType, amount.Currency).Add(amount.Float64())
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func TestObserveEntry(t *testing.T) {
+	tests := []struct {
+		accountType string
+		entryType   string
+		amount      money.Money
+		wantAmount  float64
+	}{
+		{"customer", "debit", money.Money{Minor: 10050, Currency: "USD"}, 100.5},
+		{"merchant", "credit", money.Money{Minor: 1500, Currency: "JPY"}, 1500},
+		{"merchant", "credit", money.Money{Minor: 1234, Currency: "KWD"}, 1.234},
+	}
+	for _, tt := range tests {
+		entries := postedEntries.WithLabelValues(tt.accountType, tt.entryType)
+		amount := postedAmount.WithLabelValues(tt.accountType, tt.entryType, tt.amount.Currency)
+		entriesBefore, amountBefore := testutil.ToFloat64(entries), testutil.ToFloat64(amount)
+
+		observeEntry(tt.accountType, tt.entryType, tt.amount)
+
+		if got := testutil.ToFloat64(entries) - entriesBefore; got != 1 {
+			t.Fatalf("%s %s: entries grew by %v, want 1", tt.accountType, tt.entryType, got)
+		}
+		// Amounts are exported in major units of their own currency
+		if got := testutil.ToFloat64(amount) - amountBefore; got != tt.wantAmount {
+			t.Fatalf("%s %s %s: amount grew by %v, want %v", tt.accountType, tt.entryType, tt.amount.Currency, got, tt.wantAmount)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+-- Reverts 001_ledger_service_schema
+
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS settlement_batches;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS accounts;
+DROP FUNCTION IF EXISTS update_updated_at_column();
@@ -21,6 +21,14 @@ CREATE TABLE IF NOT EXISTS accounts (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Columns missing from tables created by the service before migrations
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS entity_id VARCHAR(255);
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS currency VARCHAR(3) DEFAULT 'USD';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS available_balance DECIMAL(20,2) DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS hold_balance DECIMAL(20,2) DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS status VARCHAR(50) DEFAULT 'active';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS metadata JSONB;
+
 -- Critical for looking up accounts by entity (merchant, customer, etc.)
 CREATE INDEX IF NOT EXISTS idx_accounts_entity_type ON accounts(entity_id, type);
 
@@ -29,7 +37,7 @@ CREATE TABLE IF NOT EXISTS ledger_entries (
     id BIGSERIAL PRIMARY KEY,
     account_id VARCHAR(255) NOT NULL REFERENCES accounts(id),
     payment_id VARCHAR(255),
-    entry_type VARCHAR(50) NOT NULL,
+    type VARCHAR(50) NOT NULL, -- debit or credit
     amount DECIMAL(20,2) NOT NULL,
     balance DECIMAL(20,2) NOT NULL,
     currency VARCHAR(3) DEFAULT 'USD',
@@ -39,6 +47,22 @@ CREATE TABLE IF NOT EXISTS ledger_entries (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Databases initialized from the old schema file named the column
+-- entry_type, while the service writes type
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'ledger_entries' AND column_name = 'entry_type'
+    ) THEN
+        ALTER TABLE ledger_entries RENAME COLUMN entry_type TO type;
+    END IF;
+END $$;
+
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS currency VARCHAR(3) DEFAULT 'USD';
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS metadata JSONB;
+
 -- Critical: ledger entries by account with time ordering
 CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_created ON ledger_entries(account_id, created_at DESC);
 -- Lookup by payment
@@ -101,9 +125,11 @@ END;
 $$ language 'plpgsql';
 
 -- Triggers for updated_at
+DROP TRIGGER IF EXISTS update_accounts_updated_at ON accounts;
 CREATE TRIGGER update_accounts_updated_at BEFORE UPDATE ON accounts
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_settlement_batches_updated_at ON settlement_batches;
 CREATE TRIGGER update_settlement_batches_updated_at BEFORE UPDATE ON settlement_batches
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
@@ -129,11 +155,3 @@ COMMENT ON TABLE accounts IS 'Financial accounts for merchants, platform, and cu
 COMMENT ON TABLE ledger_entries IS 'Double-entry bookkeeping ledger for all financial movements';
 COMMENT ON TABLE settlement_batches IS 'Batch settlements for merchants';
 COMMENT ON TABLE transactions IS 'Audit log of all transactions';
-
--- Schema version tracking
-CREATE TABLE IF NOT EXISTS schema_migrations (
-    version VARCHAR(50) PRIMARY KEY,
-    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-INSERT INTO schema_migrations (version) VALUES ('001_ledger_service_schema') ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Reverts 002_ledger_amount_minor
+
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS amount_minor;
+ALTER TABLE ledger_entries ALTER COLUMN balance TYPE DECIMAL(20,2);
+ALTER TABLE ledger_entries ALTER COLUMN amount TYPE DECIMAL(20,2);
+ALTER TABLE accounts ALTER COLUMN balance TYPE DECIMAL(20,2);
@@ -0,0 +1,17 @@
+-- Ledger entries carry their amount in integer minor units of the entry
+-- currency. Decimal columns are widened to three decimals for currencies
+-- such as KWD.
+
+ALTER TABLE accounts ALTER COLUMN balance TYPE DECIMAL(20,3);
+ALTER TABLE ledger_entries ALTER COLUMN amount TYPE DECIMAL(20,3);
+ALTER TABLE ledger_entries ALTER COLUMN balance TYPE DECIMAL(20,3);
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS amount_minor BIGINT;
+
+UPDATE ledger_entries
+SET amount_minor = ROUND(amount * CASE
+        WHEN currency IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+                          'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1
+        WHEN currency IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        ELSE 100
+    END)
+WHERE amount_minor IS NULL;
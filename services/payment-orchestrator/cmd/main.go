@@ -4,62 +4,173 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/lib/pq"
 	"github.com/nats-io/nats.go"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 
-	"github.com/akylbek/payment-system/payment-orchestrator/internal/telemetry"
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/attempts"
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/config"
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/connector"
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/fraudclient"
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/lock"
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/routing"
+	"github.com/akylbek/payment-system/payment-orchestrator/migrations"
+	"github.com/akylbek/payment-system/pkg/platform/consumer"
+	"github.com/akylbek/payment-system/pkg/platform/health"
+	"github.com/akylbek/payment-system/pkg/platform/httpserver"
+	"github.com/akylbek/payment-system/pkg/platform/kafkaclient"
+	"github.com/akylbek/payment-system/pkg/platform/lifecycle"
+	"github.com/akylbek/payment-system/pkg/platform/migrate"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+	"github.com/akylbek/payment-system/pkg/platform/natsclient"
+	"github.com/akylbek/payment-system/pkg/platform/postgres"
+	"github.com/akylbek/payment-system/pkg/platform/redisclient"
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
 )
 
 type PaymentState string
 
 const (
-	StateNew         PaymentState = "NEW"
-	StateAuthPending PaymentState = "AUTH_PENDING"
-	StateAuthorized  PaymentState = "AUTHORIZED"
-	StateCaptured    PaymentState = "CAPTURED"
-	StateSucceeded   PaymentState = "SUCCEEDED"
-	StateFailed      PaymentState = "FAILED"
-	StateCanceled    PaymentState = "CANCELED"
+	StateNew           PaymentState = "NEW"
+	StateAuthPending   PaymentState = "AUTH_PENDING"
+	StateReviewPending PaymentState = "REVIEW_PENDING"
+	StateAuthorized    PaymentState = "AUTHORIZED"
+	StateCaptured      PaymentState = "CAPTURED"
+	StateSucceeded     PaymentState = "SUCCEEDED"
+	StateFailed        PaymentState = "FAILED"
+	StateCanceled      PaymentState = "CANCELED"
+	StateRefunded      PaymentState = "REFUNDED"
 )
 
 type PaymentEvent struct {
-	PaymentID  string    `json:"payment_id"`
-	Amount     float64   `json:"amount"`
-	Currency   string    `json:"currency"`
-	CustomerID string    `json:"customer_id"`
-	MerchantID string    `json:"merchant_id"`
-	Status     string    `json:"status"`
-	CreatedAt  time.Time `json:"created_at"`
+	PaymentID string `json:"payment_id"`
+	// Amount is the same amount in major units, the only one sent by
+	// gateways predating amount_minor
+	Amount money.Decimal `json:"amount"`
+	money.Money
+	CustomerID      string    `json:"customer_id"`
+	MerchantID      string    `json:"merchant_id"`
+	CustomerEmail   string    `json:"customer_email,omitempty"`
+	CustomerCountry string    `json:"customer_country,omitempty"`
+	PaymentMethod   string    `json:"payment_method,omitempty"`
+	CardBIN         string    `json:"card_bin,omitempty"`
+	IPAddress       string    `json:"ip_address,omitempty"`
+	DeviceID        string    `json:"device_id,omitempty"`
+	Status          string    `json:"status"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 type FraudCheckRequest struct {
-	PaymentID  string  `json:"payment_id"`
-	Amount     float64 `json:"amount"`
-	CustomerID string  `json:"customer_id"`
+	PaymentID string        `json:"payment_id"`
+	Amount    money.Decimal `json:"amount"`
+	money.Money
+	CustomerID      string `json:"customer_id"`
+	MerchantID      string `json:"merchant_id,omitempty"`
+	CustomerCountry string `json:"customer_country,omitempty"`
+	CustomerEmail   string `json:"customer_email,omitempty"`
+	EmailDomain     string `json:"email_domain,omitempty"`
+	PaymentMethod   string `json:"payment_method,omitempty"`
+	CardBIN         string `json:"card_bin,omitempty"`
+	IPAddress       string `json:"ip_address,omitempty"`
+	DeviceID        string `json:"device_id,omitempty"`
 }
 
 type FraudCheckResponse struct {
-	Decision string `json:"decision"` // approve, deny, manual_review
-	Reason   string `json:"reason"`
+	PaymentID string            `json:"payment_id,omitempty"`
+	Decision  string            `json:"decision"` // approve, deny, manual_review
+	Reason    string            `json:"reason"`
+	RiskScore int               `json:"risk_score"`
+	Rules     []FraudRuleResult `json:"rules"`
 }
 
+// FraudRuleResult is a single evaluated fraud rule with its reason code
+type FraudRuleResult struct {
+	Code              string `json:"code"`
+	Name              string `json:"name"`
+	Outcome           string `json:"outcome"` // pass, triggered, skipped
+	Action            string `json:"action,omitempty"`
+	ScoreContribution int    `json:"score_contribution"`
+	Detail            string `json:"detail,omitempty"`
+}
+
+// ReviewDecision is published by the fraud service when an analyst closes a
+// manual review case or its SLA expires
+type ReviewDecision struct {
+	CaseID    int64     `json:"case_id"`
+	PaymentID string    `json:"payment_id"`
+	Decision  string    `json:"decision"` // approve, deny
+	Reason    string    `json:"reason"`
+	Analyst   string    `json:"analyst"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// ReviewRequest asks the fraud service to open a review case for a payment
+// it could not check
+type ReviewRequest struct {
+	PaymentID  string        `json:"payment_id"`
+	CustomerID string        `json:"customer_id"`
+	Amount     money.Decimal `json:"amount"`
+	money.Money
+	Reason string `json:"reason"`
+}
+
+const (
+	reviewStream           = "FRAUD_REVIEWS"
+	reviewDecidedSubject   = "fraud.review.decided"
+	reviewRequestStream    = "FRAUD_REVIEW_REQUESTS"
+	reviewRequestedSubject = "fraud.review.requested"
+)
+
+// Durable fraud checks, see the fraud-service for the consuming side
+const (
+	fraudCheckStream         = "FRAUD_CHECKS"
+	fraudCheckRequestSubject = "fraud.check.requests"
+	fraudResultStream        = "FRAUD_CHECK_RESULTS"
+	fraudResultSubject       = "fraud.check.results"
+	orchestratorQueueGroup   = "payment-orchestrator"
+)
+
 var (
 	db          *sql.DB
 	redisClient *redis.Client
 	nc          *nats.Conn
+	js          nats.JetStreamContext
 	kafkaWriter *kafka.Writer
+
+	// fraudCheckMode is "sync" (request-reply, falling back per merchant
+	// when the fraud service is unavailable) or "async" (always the durable
+	// stream)
+	fraudCheckMode string
+	fraudClient    *fraudclient.Client
+	fraudFallbacks *fraudclient.Fallbacks
+
+	// router picks the processors that authorize and capture approved
+	// payments
+	router *routing.Engine
+	// attemptRepo stores every processor call of a payment
+	attemptRepo *attempts.Repository
+	// paymentLocks keeps two consumers from processing the same payment
+	paymentLocks *lock.Locker
+)
+
+const (
+	paymentLockTTL = 30 * time.Second
+	// paymentLockWait is how long a redelivered event waits for the
+	// current holder before the consumer retries it
+	paymentLockWait = 30 * time.Second
+	// paymentLockPoll is how long NATS handlers and refunds wait for the
+	// current holder before redelivering or answering 409
+	paymentLockPoll = time.Second
 )
 
 func main() {
@@ -73,236 +184,863 @@ func main() {
 
 	telemetry.Logger.Info("Starting Payment Orchestrator")
 
+	// Load configuration
+	cfg := config.Load()
+	brokers := kafkaclient.Brokers(cfg.KafkaBrokers)
+
+	// Inspect or replay dead-lettered messages instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		if err := consumer.RunCommand(context.Background(), os.Args[2:], brokers, os.Stdout); err != nil {
+			telemetry.Logger.Fatal("dlq command failed", zap.Error(err))
+		}
+		return
+	}
+
 	// Connect to PostgreSQL
-	dbURL := os.Getenv("DATABASE_URL")
-	db, err = sql.Open("postgres", dbURL)
+	db, err = postgres.Open(cfg.DatabaseURL)
 	if err != nil {
 		telemetry.Logger.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer db.Close()
 
-	// Initialize database
-	if err := initDB(); err != nil {
-		telemetry.Logger.Fatal("Failed to initialize database", zap.Error(err))
+	migrator, err := migrate.New(db, migrations.FS, telemetry.Logger)
+	if err != nil {
+		telemetry.Logger.Fatal("Failed to load migrations", zap.Error(err))
 	}
 
-	// Connect to Redis
-	redisURL := os.Getenv("REDIS_URL")
-	redisClient = redis.NewClient(&redis.Options{
-		Addr: redisURL,
-	})
+	// Inspect, apply or roll back migrations instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.RunCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			telemetry.Logger.Fatal("migrate command failed", zap.Error(err))
+		}
+		return
+	}
+
+	if err := migrator.Up(context.Background()); err != nil {
+		telemetry.Logger.Fatal("Failed to migrate database", zap.Error(err))
+	}
+
+	attemptRepo = attempts.NewRepository(db)
+
+	redisClient = redisclient.New(cfg.RedisURL)
+	paymentLocks = lock.NewLocker(redisClient, paymentLockTTL, telemetry.Logger)
 
 	// Connect to NATS
-	natsURL := os.Getenv("NATS_URL")
-	nc, err = nats.Connect(natsURL)
+	nc, js, err = natsclient.Connect(cfg.NATSURL)
 	if err != nil {
 		telemetry.Logger.Fatal("Failed to connect to NATS", zap.Error(err))
 	}
-	defer nc.Close()
+	if err := ensureReviewStream(js); err != nil {
+		telemetry.Logger.Fatal("Failed to create review stream", zap.Error(err))
+	}
+	if err := ensureFraudCheckStreams(js); err != nil {
+		telemetry.Logger.Fatal("Failed to create fraud check streams", zap.Error(err))
+	}
+
+	// Setup fraud client with retries, circuit breaker and fallbacks
+	if err := initFraudClient(cfg); err != nil {
+		telemetry.Logger.Fatal("Invalid fraud client configuration", zap.Error(err))
+	}
 
-	// Connect to Kafka
-	kafkaBrokers := os.Getenv("KAFKA_BROKERS")
-	kafkaWriter = &kafka.Writer{
-		Addr:     kafka.TCP(kafkaBrokers),
-		Topic:    "payment.state.changed",
-		Balancer: &kafka.LeastBytes{},
+	// Setup processor routing
+	if err := initRouting(cfg); err != nil {
+		telemetry.Logger.Fatal("Invalid routing configuration", zap.Error(err))
 	}
-	defer kafkaWriter.Close()
 
-	// Start Kafka consumer
-	go consumePaymentEvents()
+	kafkaWriter = kafkaclient.NewWriter(brokers, "payment.state.changed")
 
-	// Setup Gin router
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(telemetry.TracingMiddleware())
+	workers, err := strconv.Atoi(cfg.ConsumerWorkers)
+	if err != nil || workers < 1 {
+		telemetry.Logger.Fatal("Invalid CONSUMER_WORKERS", zap.String("value", cfg.ConsumerWorkers))
+	}
 
-	// Prometheus metrics endpoint
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	// Events are keyed by payment ID, so each payment's events stay in order
+	paymentEvents := consumer.New(consumer.Config{
+		Brokers: brokers,
+		Topic:   "payment.created",
+		GroupID: "payment-orchestrator",
+		Workers: workers,
+	}, telemetry.Logger)
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "payment-orchestrator"})
-	})
+	checker := health.New("payment-orchestrator", 2*time.Second)
+	checker.Add("postgres", postgres.Check(db))
+	checker.Add("redis", redisclient.Check(redisClient))
+	checker.Add("nats", natsclient.Check(nc))
+	checker.Add("kafka", kafkaclient.Check(brokers))
 
+	r := httpserver.NewRouter(checker)
 	r.GET("/payments/:id/state", getPaymentState)
+	r.POST("/payments/:id/refund", refundPayment)
+	attempts.NewHandler(attemptRepo).Register(r)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8082"
+	shutdownTimeout, err := time.ParseDuration(cfg.ShutdownTimeout)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid SHUTDOWN_TIMEOUT", zap.Error(err))
+	}
+	drainDelay, err := time.ParseDuration(cfg.DrainDelay)
+	if err != nil {
+		telemetry.Logger.Fatal("Invalid DRAIN_DELAY", zap.Error(err))
 	}
 
-	// Setup HTTP server
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: r,
+	app := lifecycle.New(telemetry.Logger, shutdownTimeout)
+	app.Append(
+		postgres.CloseHook(db),
+		redisclient.CloseHook(redisClient),
+		natsclient.CloseHook(nc),
+		kafkaclient.CloseHook(kafkaWriter),
+		// Manual review outcomes
+		natsclient.SubscribeHook(reviewDecidedSubject, func() (*nats.Subscription, error) {
+			return natsclient.QueueSubscribe(js, natsclient.QueueConsumer{
+				Stream:  reviewStream,
+				Durable: "payment-orchestrator-reviews",
+				Subject: reviewDecidedSubject,
+				Queue:   orchestratorQueueGroup,
+				AckWait: 30 * time.Second,
+			}, handleReviewDecision)
+		}),
+		// Durable fraud check results
+		natsclient.SubscribeHook(fraudResultSubject, func() (*nats.Subscription, error) {
+			return natsclient.QueueSubscribe(js, natsclient.QueueConsumer{
+				Stream:  fraudResultStream,
+				Durable: "payment-orchestrator-fraud-results",
+				Subject: fraudResultSubject,
+				Queue:   orchestratorQueueGroup,
+				AckWait: 30 * time.Second,
+			}, handleFraudCheckResult)
+		}),
+		paymentEvents.Hook(handlePaymentCreated),
+		httpserver.Hook(cfg.Port, r),
+		checker.Hook(drainDelay),
+	)
+	if err := app.Run(context.Background()); err != nil {
+		telemetry.Logger.Fatal("Payment Orchestrator stopped with errors", zap.Error(err))
 	}
 
-	// Start server in goroutine
-	go func() {
-		telemetry.Logger.Info("Payment Orchestrator starting", zap.String("port", port))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			telemetry.Logger.Fatal("Failed to start server", zap.Error(err))
-		}
-	}()
+	telemetry.Logger.Info("Server exited")
+}
 
-	// Wait for interrupt signal for graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+func initFraudClient(cfg *config.Config) error {
+	fraudCheckMode = cfg.FraudCheckMode
+	if fraudCheckMode != "sync" && fraudCheckMode != "async" {
+		return fmt.Errorf("FRAUD_CHECK_MODE must be sync or async, got %q", fraudCheckMode)
+	}
 
-	telemetry.Logger.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	timeout, err := time.ParseDuration(cfg.FraudCheckTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid FRAUD_CHECK_TIMEOUT: %w", err)
+	}
+	attempts, err := strconv.Atoi(cfg.FraudRetryAttempts)
+	if err != nil || attempts < 1 {
+		return fmt.Errorf("FRAUD_RETRY_ATTEMPTS must be a positive number")
+	}
+	backoff, err := time.ParseDuration(cfg.FraudRetryBackoff)
+	if err != nil {
+		return fmt.Errorf("invalid FRAUD_RETRY_BACKOFF: %w", err)
+	}
+	maxBackoff, err := time.ParseDuration(cfg.FraudRetryMaxBackoff)
+	if err != nil {
+		return fmt.Errorf("invalid FRAUD_RETRY_MAX_BACKOFF: %w", err)
+	}
+	failures, err := strconv.Atoi(cfg.FraudBreakerFailures)
+	if err != nil || failures < 1 {
+		return fmt.Errorf("FRAUD_BREAKER_FAILURES must be a positive number")
+	}
+	openTimeout, err := time.ParseDuration(cfg.FraudBreakerOpenTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid FRAUD_BREAKER_OPEN_TIMEOUT: %w", err)
+	}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		telemetry.Logger.Error("Server forced to shutdown", zap.Error(err))
+	fraudFallbacks, err = fraudclient.ParseFallbacks(cfg.FraudFallbackPolicy, cfg.FraudFallbackLimit, cfg.FraudFallbackMerchants)
+	if err != nil {
+		return err
 	}
 
-	telemetry.Logger.Info("Server exited")
+	fraudClient = fraudclient.New(nc, "fraud.check", timeout,
+		fraudclient.RetryPolicy{Attempts: attempts, Backoff: backoff, MaxBackoff: maxBackoff},
+		fraudclient.NewBreaker(failures, openTimeout))
+	return nil
 }
 
-func initDB() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS payment_states (
-			payment_id VARCHAR(255) PRIMARY KEY,
-			state VARCHAR(50) NOT NULL,
-			previous_state VARCHAR(50),
-			fraud_decision VARCHAR(50),
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_payment_states_state ON payment_states(state)`,
+func initRouting(cfg *config.Config) error {
+	timeout, err := time.ParseDuration(cfg.ConnectorTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid CONNECTOR_TIMEOUT: %w", err)
+	}
+	attempts, err := strconv.Atoi(cfg.ConnectorRetryAttempts)
+	if err != nil || attempts < 1 {
+		return fmt.Errorf("CONNECTOR_RETRY_ATTEMPTS must be a positive number")
 	}
 
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return err
+	router, err = routing.Load(cfg.RoutingConfig, func(c connector.Connector) connector.Connector {
+		return connector.WithRetry(c, attempts, timeout, 200*time.Millisecond)
+	})
+	return err
+}
+
+func handlePaymentCreated(ctx context.Context, msg kafka.Message) error {
+	var event PaymentEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return consumer.Permanent(fmt.Errorf("unmarshal payment event: %w", err))
+	}
+	if event.PaymentID == "" {
+		return consumer.Permanent(errors.New("payment event without payment_id"))
+	}
+	if event.Minor == 0 {
+		amount, err := event.Amount.Money(event.Currency)
+		if err != nil {
+			return consumer.Permanent(fmt.Errorf("payment event amount: %w", err))
 		}
+		event.Money = amount
 	}
 
-	return nil
+	telemetry.Logger.Info("Processing payment",
+		zap.String("payment_id", event.PaymentID),
+		zap.Stringer("amount", event.Money),
+		zap.String("currency", event.Currency),
+	)
+
+	return processPayment(ctx, &event)
 }
 
-func consumePaymentEvents() {
-	kafkaBrokers := os.Getenv("KAFKA_BROKERS")
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:  []string{kafkaBrokers},
-		Topic:    "payment.created",
-		GroupID:  "payment-orchestrator",
-		MinBytes: 10e3,
-		MaxBytes: 10e6,
-	})
-	defer reader.Close()
+func processPayment(ctx context.Context, event *PaymentEvent) error {
+	// Contention is returned as an error, so the consumer retries the event
+	// once the holder finished or its lease expired
+	ctx, lease, err := lockPayment(ctx, event.PaymentID, paymentLockWait)
+	if err != nil {
+		return err
+	}
+	defer unlockPayment(ctx, event.PaymentID, lease)
 
-	ctx := context.Background()
+	// Save initial state with the details the connector needs later
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO payment_states (payment_id, state, previous_state,
+			amount, amount_minor, currency, merchant_id, customer_id, payment_method, card_bin, lock_fence)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11)
+		ON CONFLICT (payment_id) DO NOTHING
+	`, event.PaymentID, StateNew, "", event.Money.String(), event.Minor, event.Currency, event.MerchantID, event.CustomerID,
+		event.PaymentMethod, event.CardBIN, lease.Fence())
 
-	telemetry.Logger.Info("Started consuming payment.created events")
+	if err != nil {
+		return err
+	}
 
-	for {
-		msg, err := reader.ReadMessage(ctx)
+	// A redelivered or replayed event finds the payment where the last holder
+	// left it
+	var state PaymentState
+	if err := db.QueryRowContext(ctx, `SELECT state FROM payment_states WHERE payment_id = $1`,
+		event.PaymentID).Scan(&state); err != nil {
+		return err
+	}
+	switch state {
+	case StateNew:
+		if err := transitionState(ctx, event.PaymentID, StateNew, StateAuthPending); err != nil {
+			return err
+		}
+	case StateAuthPending:
+		// The previous holder crashed or lost its lock before the payment
+		// was decided. The fraud service answers a repeated check with its
+		// stored decision and processors authorize once per payment, so the
+		// payment is resumed from the fraud check.
+		claimed, err := claimPayment(ctx, event.PaymentID, StateAuthPending, lease.Fence())
 		if err != nil {
-			telemetry.Logger.Error("Error reading message from Kafka", zap.Error(err))
-			continue
+			return err
 		}
-
-		var event PaymentEvent
-		if err := json.Unmarshal(msg.Value, &event); err != nil {
-			telemetry.Logger.Error("Error unmarshaling event", zap.Error(err))
-			continue
+		if !claimed {
+			return nil
 		}
+		telemetry.Logger.Info("Resuming payment left in AUTH_PENDING",
+			zap.String("payment_id", event.PaymentID),
+			zap.Int64("fence", lease.Fence()),
+		)
+	default:
+		telemetry.Logger.Info("Payment already processed, skipping",
+			zap.String("payment_id", event.PaymentID),
+			zap.String("state", string(state)),
+		)
+		return nil
+	}
+
+	// Check fraud via NATS
+	fraudReq := FraudCheckRequest{
+		PaymentID:       event.PaymentID,
+		Amount:          event.Money.Decimal(),
+		Money:           event.Money,
+		CustomerID:      event.CustomerID,
+		MerchantID:      event.MerchantID,
+		CustomerCountry: event.CustomerCountry,
+		CustomerEmail:   event.CustomerEmail,
+		EmailDomain:     emailDomain(event.CustomerEmail),
+		PaymentMethod:   event.PaymentMethod,
+		CardBIN:         event.CardBIN,
+		IPAddress:       event.IPAddress,
+		DeviceID:        event.DeviceID,
+	}
+	fraudReqJSON, _ := json.Marshal(fraudReq)
+
+	// A holder that lost the lock stops before asking for a decision; the
+	// retried event resumes the payment
+	if err := lease.Check(); err != nil {
+		return fmt.Errorf("payment %s: %w", event.PaymentID, err)
+	}
+
+	if fraudCheckMode == "async" {
+		return requestDurableFraudCheck(ctx, event.PaymentID, fraudReqJSON)
+	}
 
-		telemetry.Logger.Info("Processing payment",
+	reply, err := fraudClient.Check(ctx, fraudReqJSON)
+	if errors.Is(err, natsclient.ErrRejected) {
+		// The fraud service cannot check this payment at all, so no
+		// fallback applies
+		telemetry.Logger.Error("Fraud service rejected the check request",
+			zap.String("payment_id", event.PaymentID),
+			zap.Error(err),
+		)
+		return applyFraudDecision(ctx, lease, event.PaymentID, &FraudCheckResponse{
+			PaymentID: event.PaymentID,
+			Decision:  "deny",
+			Reason:    "Fraud check request rejected",
+			Rules: []FraudRuleResult{{
+				Code:    "FRAUD_CHECK_REJECTED",
+				Name:    "Fraud Check Rejected",
+				Outcome: "triggered",
+				Action:  "deny",
+				Detail:  err.Error(),
+			}},
+		})
+	}
+	if err != nil {
+		telemetry.Logger.Warn("Fraud service unavailable, applying fallback",
 			zap.String("payment_id", event.PaymentID),
-			zap.Float64("amount", event.Amount),
+			zap.String("merchant_id", event.MerchantID),
+			zap.String("circuit", string(fraudClient.Breaker().State())),
+			zap.Error(err),
 		)
+		return applyFraudFallback(ctx, lease, event, fraudReqJSON)
+	}
+
+	var fraudResp FraudCheckResponse
+	if err := json.Unmarshal(reply, &fraudResp); err != nil {
+		return err
+	}
+
+	return applyFraudDecision(ctx, lease, event.PaymentID, &fraudResp)
+}
+
+// claimPayment stores the fencing token on a payment in state without moving
+// it, so the transitions of a previous holder are refused from now on. It
+// reports false when the state changed or a newer holder claimed it.
+func claimPayment(ctx context.Context, paymentID string, state PaymentState, fence int64) (bool, error) {
+	result, err := db.ExecContext(ctx, `
+		UPDATE payment_states SET lock_fence = $3
+		WHERE payment_id = $1 AND state = $2 AND COALESCE(lock_fence, 0) <= $3
+	`, paymentID, state, fence)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
 
-		if err := processPayment(ctx, &event); err != nil {
-			telemetry.Logger.Error("Error processing payment",
+// lockPayment takes the payment lock, waiting up to wait for the current
+// holder. The returned context carries the fencing token for the state
+// transitions made under the lease.
+func lockPayment(ctx context.Context, paymentID string, wait time.Duration) (context.Context, *lock.Lease, error) {
+	lease, err := paymentLocks.Acquire(ctx, "payment_lock:"+paymentID, wait)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("lock payment %s: %w", paymentID, err)
+	}
+	return lock.WithFence(ctx, lease.Fence()), lease, nil
+}
+
+func unlockPayment(ctx context.Context, paymentID string, lease *lock.Lease) {
+	if err := lease.Release(context.WithoutCancel(ctx)); err != nil {
+		telemetry.Logger.Warn("Error releasing payment lock",
+			zap.String("payment_id", paymentID),
+			zap.Error(err),
+		)
+	}
+}
+
+// applyFraudFallback decides a payment the fraud service could not check,
+// following the merchant's fallback policy
+func applyFraudFallback(ctx context.Context, lease *lock.Lease, event *PaymentEvent, fraudReqJSON []byte) error {
+	rule := fraudFallbacks.For(event.MerchantID)
+
+	if rule.Policy == fraudclient.PolicyDurable {
+		// The payment stays in AUTH_PENDING until the result arrives, see
+		// handleFraudCheckResult
+		fraudclient.FallbackDecisions.WithLabelValues(string(rule.Policy), "queued").Inc()
+		return requestDurableFraudCheck(ctx, event.PaymentID, fraudReqJSON)
+	}
+
+	result := FraudRuleResult{
+		Code:    "FRAUD_SERVICE_UNAVAILABLE",
+		Name:    "Fraud Service Fallback",
+		Outcome: "triggered",
+		Action:  "deny",
+		Detail:  "Fraud service unavailable",
+	}
+
+	switch rule.Policy {
+	case fraudclient.PolicyApproveBelow:
+		if limit, ok := rule.Approves(event.Money); ok {
+			result.Action = "approve"
+			result.Detail = fmt.Sprintf("Fraud service unavailable, approved below fallback limit %s %s", limit, limit.Currency)
+		} else if limit.Currency == "" {
+			result.Detail = fmt.Sprintf("Fraud service unavailable, no fallback limit for %s", event.Money.Currency)
+		}
+	case fraudclient.PolicyManualReview:
+		if err := requestManualReview(ctx, event); err != nil {
+			telemetry.Logger.Error("Error requesting manual review, declining payment",
 				zap.String("payment_id", event.PaymentID),
 				zap.Error(err),
 			)
+		} else {
+			result.Action = "manual_review"
+			result.Detail = "Fraud service unavailable, sent to manual review"
 		}
 	}
+
+	fraudclient.FallbackDecisions.WithLabelValues(string(rule.Policy), result.Action).Inc()
+	return applyFraudDecision(ctx, lease, event.PaymentID, &FraudCheckResponse{
+		PaymentID: event.PaymentID,
+		Decision:  result.Action,
+		Reason:    result.Detail,
+		Rules:     []FraudRuleResult{result},
+	})
 }
 
-func processPayment(ctx context.Context, event *PaymentEvent) error {
-	// Acquire lock
-	lockKey := fmt.Sprintf("payment_lock:%s", event.PaymentID)
-	locked := redisClient.SetNX(ctx, lockKey, "1", 30*time.Second)
-	if !locked.Val() {
-		return fmt.Errorf("payment %s is already being processed", event.PaymentID)
-	}
-	defer redisClient.Del(ctx, lockKey)
-
-	// Save initial state
-	_, err := db.Exec(`
-		INSERT INTO payment_states (payment_id, state, previous_state)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (payment_id) DO NOTHING
-	`, event.PaymentID, StateNew, "")
+// requestManualReview asks the fraud service to open a review case once it
+// is back. The analyst decision arrives through handleReviewDecision.
+func requestManualReview(ctx context.Context, event *PaymentEvent) error {
+	data, _ := json.Marshal(ReviewRequest{
+		PaymentID:  event.PaymentID,
+		CustomerID: event.CustomerID,
+		Amount:     event.Money.Decimal(),
+		Money:      event.Money,
+		Reason:     "Fraud service unavailable",
+	})
+	_, err := js.PublishMsg(natsclient.NewMsg(ctx, reviewRequestedSubject, data), nats.MsgId(event.PaymentID))
+	return err
+}
+
+// requestDurableFraudCheck publishes the check on the work-queue stream. If
+// even that is impossible the payment stays in AUTH_PENDING and the error is
+// returned, so the redelivered event resumes it from the fraud check.
+func requestDurableFraudCheck(ctx context.Context, paymentID string, fraudReqJSON []byte) error {
+	msg := natsclient.NewMsg(ctx, fraudCheckRequestSubject, fraudReqJSON)
+	if _, err := js.PublishMsg(msg, nats.MsgId(paymentID)); err != nil {
+		telemetry.Logger.Error("Error publishing durable fraud check",
+			zap.String("payment_id", paymentID),
+			zap.Error(err),
+		)
+		return fmt.Errorf("publish durable fraud check of payment %s: %w", paymentID, err)
+	}
 
+	telemetry.Logger.Info("Durable fraud check requested", zap.String("payment_id", paymentID))
+	return nil
+}
+
+// applyFraudDecision saves the fraud outcome and moves the payment on from
+// AUTH_PENDING. It fails when the decision could not be stored or the lease
+// was lost before the payment was decided; the redelivered event or result
+// then resumes the payment.
+func applyFraudDecision(ctx context.Context, lease *lock.Lease, paymentID string, fraudResp *FraudCheckResponse) error {
+	// Save fraud decision
+	fraudRules, _ := json.Marshal(fraudResp.Rules)
+	result, err := db.ExecContext(ctx, `
+		UPDATE payment_states
+		SET fraud_decision = $1, fraud_reason = $2, fraud_risk_score = $3, fraud_rules = $4
+		WHERE payment_id = $5
+			AND ($6::BIGINT IS NULL OR COALESCE(lock_fence, 0) <= $6)
+	`, fraudResp.Decision, fraudResp.Reason, fraudResp.RiskScore, fraudRules, paymentID, lockFence(ctx))
 	if err != nil {
-		return err
+		return fmt.Errorf("save fraud decision of payment %s: %w", paymentID, err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("save fraud decision of payment %s: %w", paymentID, err)
+	} else if rows == 0 {
+		return fmt.Errorf("save fraud decision of payment %s: %w", paymentID, lock.ErrLost)
 	}
 
-	// Transition to AUTH_PENDING
-	if err := transitionState(ctx, event.PaymentID, StateNew, StateAuthPending); err != nil {
-		return err
+	switch fraudResp.Decision {
+	case "approve":
+		return completePayment(ctx, lease, paymentID, StateAuthPending)
+	case "manual_review":
+		// Wait for the analyst outcome, see handleReviewDecision
+		return transitionState(ctx, paymentID, StateAuthPending, StateReviewPending)
+	default:
+		return transitionState(ctx, paymentID, StateAuthPending, StateFailed)
 	}
+}
 
-	// Check fraud via NATS
-	fraudReq := FraudCheckRequest{
-		PaymentID:  event.PaymentID,
-		Amount:     event.Amount,
-		CustomerID: event.CustomerID,
+func ensureFraudCheckStreams(js nats.JetStreamContext) error {
+	streams := []*nats.StreamConfig{
+		{
+			Name:      fraudCheckStream,
+			Subjects:  []string{fraudCheckRequestSubject},
+			Retention: nats.WorkQueuePolicy,
+			Storage:   nats.FileStorage,
+			MaxAge:    24 * time.Hour,
+		},
+		{
+			Name:     fraudResultStream,
+			Subjects: []string{fraudResultSubject},
+			Storage:  nats.FileStorage,
+			MaxAge:   24 * time.Hour,
+		},
+	}
+
+	return natsclient.EnsureStreams(js, streams...)
+}
+
+func handleFraudCheckResult(msg *nats.Msg) {
+	var fraudResp FraudCheckResponse
+	if err := json.Unmarshal(msg.Data, &fraudResp); err != nil || fraudResp.PaymentID == "" {
+		telemetry.Logger.Error("Error unmarshaling fraud check result", zap.Error(err))
+		msg.Term()
+		return
 	}
-	fraudReqJSON, _ := json.Marshal(fraudReq)
 
-	msg, err := nc.Request("fraud.check", fraudReqJSON, 5*time.Second)
+	ctx, span := natsclient.StartHandlerSpan(context.Background(), msg)
+	defer span.End()
+
+	ctx, lease, err := lockPayment(ctx, fraudResp.PaymentID, paymentLockPoll)
 	if err != nil {
-		telemetry.Logger.Warn("Fraud check timeout",
-			zap.String("payment_id", event.PaymentID),
+		msg.NakWithDelay(time.Second)
+		return
+	}
+	defer unlockPayment(ctx, fraudResp.PaymentID, lease)
+
+	var state PaymentState
+	err = db.QueryRowContext(ctx,
+		`SELECT state FROM payment_states WHERE payment_id = $1`, fraudResp.PaymentID).Scan(&state)
+	if err != nil && err != sql.ErrNoRows {
+		telemetry.Logger.Error("Error loading payment state",
+			zap.String("payment_id", fraudResp.PaymentID),
+			zap.Error(err),
+		)
+		msg.Nak()
+		return
+	}
+
+	// Already decided through request-reply or a duplicate result
+	if state != StateAuthPending {
+		msg.Ack()
+		return
+	}
+
+	telemetry.Logger.Info("Durable fraud check completed",
+		zap.String("payment_id", fraudResp.PaymentID),
+		zap.String("decision", fraudResp.Decision),
+	)
+
+	if err := applyFraudDecision(ctx, lease, fraudResp.PaymentID, &fraudResp); err != nil {
+		msg.Nak()
+		return
+	}
+	msg.Ack()
+}
+
+// emailDomain returns the lowercased domain part of an email address
+func emailDomain(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return ""
+	}
+	return strings.ToLower(email[i+1:])
+}
+
+// loadConnectorRequest reads the payment details saved by processPayment
+func loadConnectorRequest(ctx context.Context, paymentID string) (connector.Request, error) {
+	req := connector.Request{PaymentID: paymentID}
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(amount_minor, 0), COALESCE(currency, ''), COALESCE(merchant_id, ''), COALESCE(customer_id, ''),
+			COALESCE(payment_method, ''), COALESCE(card_bin, '')
+		FROM payment_states WHERE payment_id = $1
+	`, paymentID).Scan(&req.Amount.Minor, &req.Amount.Currency, &req.MerchantID, &req.CustomerID,
+		&req.PaymentMethod, &req.CardBIN)
+	return req, err
+}
+
+// callProcessor runs one processor call, stores it as a payment attempt and
+// keeps the latest processor result on the payment
+func callProcessor(ctx context.Context, paymentID string, processor *routing.Processor, operation string,
+	call func(connector.Connector) (*connector.Response, error)) (*connector.Response, error) {
+	started := time.Now()
+	resp, err := call(processor.Connector)
+	attempt := attempts.FromResponse(paymentID, operation, processor.Name, resp, err, time.Since(started))
+
+	if recordErr := attemptRepo.Record(ctx, &attempt); recordErr != nil {
+		telemetry.Logger.Error("Error recording payment attempt",
+			zap.String("payment_id", paymentID),
+			zap.String("operation", operation),
+			zap.Error(recordErr),
+		)
+	}
+
+	message := ""
+	if resp != nil {
+		message = resp.Message
+	}
+	if err != nil {
+		message = err.Error()
+	}
+	db.ExecContext(ctx, `
+		UPDATE payment_states
+		SET connector = $1, processor_reference = COALESCE(NULLIF($2, ''), processor_reference),
+			decline_code = NULLIF($3, ''), processor_message = NULLIF($4, '')
+		WHERE payment_id = $5
+	`, processor.Name, attempt.Reference, attempt.DeclineReason, message, paymentID)
+
+	return resp, err
+}
+
+// authorize tries the routed processors in order. Soft declines and
+// temporary errors move on to the next processor; hard declines and
+// timeouts, whose outcome is unknown, stop the cascade.
+func authorize(ctx context.Context, req connector.Request) (*routing.Processor, *connector.Response, error) {
+	processors, err := router.Route(req, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(processors) > router.MaxAttempts() {
+		processors = processors[:router.MaxAttempts()]
+	}
+
+	var resp *connector.Response
+	for i, p := range processors {
+		resp, err = callProcessor(ctx, req.PaymentID, p, "authorize", func(c connector.Connector) (*connector.Response, error) {
+			return c.Authorize(ctx, req)
+		})
+
+		result, soft := "approved", false
+		switch {
+		case err != nil:
+			result, soft = "error", errors.Is(err, connector.ErrSoft)
+		case resp.Status == connector.StatusDeclined && connector.SoftDecline(resp.DeclineCode):
+			result, soft = "soft_declined", true
+		case resp.Status == connector.StatusDeclined:
+			result = "declined"
+		}
+		routing.Attempts.WithLabelValues(p.Name, result).Inc()
+		router.Record(p.Name, result == "approved" || result == "declined", time.Now())
+
+		if !soft || i == len(processors)-1 {
+			return p, resp, err
+		}
+
+		next := processors[i+1]
+		routing.Failovers.WithLabelValues(p.Name, next.Name).Inc()
+		telemetry.Logger.Warn("Authorization soft failure, failing over",
+			zap.String("payment_id", req.PaymentID),
+			zap.String("processor", p.Name),
+			zap.String("next_processor", next.Name),
+			zap.String("result", result),
+		)
+	}
+	return nil, resp, err
+}
+
+// completePayment authorizes and captures an approved payment at the routed
+// processor, driving it from its current state to SUCCEEDED or FAILED. It
+// fails when the lease was lost or an authorized payment could not be
+// recorded as AUTHORIZED, leaving the payment for the next holder.
+func completePayment(ctx context.Context, lease *lock.Lease, paymentID string, from PaymentState) error {
+	req, err := loadConnectorRequest(ctx, paymentID)
+	if err != nil {
+		telemetry.Logger.Error("Error loading payment for authorization",
+			zap.String("payment_id", paymentID),
+			zap.Error(err),
+		)
+		transitionState(ctx, paymentID, from, StateFailed)
+		return nil
+	}
+
+	if err := checkLease(lease, paymentID); err != nil {
+		return err
+	}
+	processor, auth, err := authorize(ctx, req)
+	if err != nil || auth.Status != connector.StatusAuthorized {
+		logProcessorFailure("Authorization failed", paymentID, auth, err)
+		transitionState(ctx, paymentID, from, StateFailed)
+		return nil
+	}
+	if err := transitionState(ctx, paymentID, from, StateAuthorized); err != nil {
+		// The processor holds an authorization the payment does not show.
+		// The redelivered message repeats the authorization, which
+		// processors deduplicate by payment_id, and records it then.
+		telemetry.Logger.Error("Error recording authorization",
+			zap.String("payment_id", paymentID),
+			zap.String("reference", auth.Reference),
 			zap.Error(err),
 		)
-		transitionState(ctx, event.PaymentID, StateAuthPending, StateFailed)
 		return err
 	}
 
-	var fraudResp FraudCheckResponse
-	if err := json.Unmarshal(msg.Data, &fraudResp); err != nil {
+	if err := checkLease(lease, paymentID); err != nil {
 		return err
 	}
+	capture, err := callProcessor(ctx, paymentID, processor, "capture", func(c connector.Connector) (*connector.Response, error) {
+		return c.Capture(ctx, auth.Reference, req.Amount)
+	})
+	if err != nil || capture.Status != connector.StatusCaptured {
+		logProcessorFailure("Capture failed, voiding authorization", paymentID, capture, err)
+		_, err := callProcessor(ctx, paymentID, processor, "void", func(c connector.Connector) (*connector.Response, error) {
+			return c.Void(ctx, auth.Reference)
+		})
+		if err != nil {
+			telemetry.Logger.Error("Error voiding authorization",
+				zap.String("payment_id", paymentID),
+				zap.String("reference", auth.Reference),
+				zap.Error(err),
+			)
+		}
+		transitionState(ctx, paymentID, StateAuthorized, StateFailed)
+		return nil
+	}
+	transitionState(ctx, paymentID, StateAuthorized, StateCaptured)
+	transitionState(ctx, paymentID, StateCaptured, StateSucceeded)
+	return nil
+}
 
-	// Save fraud decision
-	db.Exec(`UPDATE payment_states SET fraud_decision = $1 WHERE payment_id = $2`,
-		fraudResp.Decision, event.PaymentID)
+// checkLease stops a holder that lost the payment lock before it calls a
+// processor, which the fencing token cannot undo
+func checkLease(lease *lock.Lease, paymentID string) error {
+	if err := lease.Check(); err != nil {
+		telemetry.Logger.Warn("Payment lock lost, stopping",
+			zap.String("payment_id", paymentID),
+			zap.Int64("fence", lease.Fence()),
+		)
+		return fmt.Errorf("payment %s: %w", paymentID, err)
+	}
+	return nil
+}
+
+func logProcessorFailure(msg, paymentID string, resp *connector.Response, err error) {
+	fields := []zap.Field{zap.String("payment_id", paymentID)}
+	if resp != nil {
+		fields = append(fields, zap.String("decline_code", resp.DeclineCode))
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	telemetry.Logger.Warn(msg, fields...)
+}
+
+func ensureReviewStream(js nats.JetStreamContext) error {
+	streams := []*nats.StreamConfig{
+		{
+			Name:     reviewStream,
+			Subjects: []string{reviewDecidedSubject},
+			Storage:  nats.FileStorage,
+		},
+		{
+			Name:      reviewRequestStream,
+			Subjects:  []string{reviewRequestedSubject},
+			Retention: nats.WorkQueuePolicy,
+			Storage:   nats.FileStorage,
+		},
+	}
+
+	return natsclient.EnsureStreams(js, streams...)
+}
+
+func handleReviewDecision(msg *nats.Msg) {
+	var decision ReviewDecision
+	if err := json.Unmarshal(msg.Data, &decision); err != nil {
+		telemetry.Logger.Error("Error unmarshaling review decision", zap.Error(err))
+		msg.Term()
+		return
+	}
+
+	ctx, span := natsclient.StartHandlerSpan(context.Background(), msg)
+	defer span.End()
+
+	ctx, lease, err := lockPayment(ctx, decision.PaymentID, paymentLockPoll)
+	if err != nil {
+		msg.NakWithDelay(time.Second)
+		return
+	}
+	defer unlockPayment(ctx, decision.PaymentID, lease)
+
+	var state PaymentState
+	err = db.QueryRowContext(ctx,
+		`SELECT state FROM payment_states WHERE payment_id = $1`, decision.PaymentID).Scan(&state)
+	if err != nil && err != sql.ErrNoRows {
+		telemetry.Logger.Error("Error loading payment state",
+			zap.String("payment_id", decision.PaymentID),
+			zap.Error(err),
+		)
+		msg.Nak()
+		return
+	}
+
+	// Duplicate or late decision, nothing to do
+	if state != StateReviewPending {
+		telemetry.Logger.Warn("Ignoring review decision for payment not awaiting review",
+			zap.String("payment_id", decision.PaymentID),
+			zap.String("state", string(state)),
+		)
+		msg.Ack()
+		return
+	}
+
+	db.ExecContext(ctx, `UPDATE payment_states SET fraud_decision = $1, fraud_reason = $2 WHERE payment_id = $3`,
+		decision.Decision, decision.Reason, decision.PaymentID)
+
+	telemetry.Logger.Info("Manual review decided",
+		zap.String("payment_id", decision.PaymentID),
+		zap.String("decision", decision.Decision),
+		zap.String("analyst", decision.Analyst),
+		zap.String("reason", decision.Reason),
+	)
 
-	if fraudResp.Decision == "approve" {
-		transitionState(ctx, event.PaymentID, StateAuthPending, StateAuthorized)
-		transitionState(ctx, event.PaymentID, StateAuthorized, StateCaptured)
-		transitionState(ctx, event.PaymentID, StateCaptured, StateSucceeded)
+	if decision.Decision == "approve" {
+		if err := completePayment(ctx, lease, decision.PaymentID, StateReviewPending); err != nil {
+			msg.Nak()
+			return
+		}
 	} else {
-		transitionState(ctx, event.PaymentID, StateAuthPending, StateFailed)
+		transitionState(ctx, decision.PaymentID, StateReviewPending, StateFailed)
 	}
 
-	return nil
+	msg.Ack()
+}
+
+// lockFence returns the fencing token of the payment lock held by ctx, or
+// NULL outside a lock
+func lockFence(ctx context.Context) sql.NullInt64 {
+	var fence sql.NullInt64
+	fence.Int64, fence.Valid = lock.FenceFrom(ctx)
+	return fence
 }
 
 func transitionState(ctx context.Context, paymentID string, from, to PaymentState) error {
-	result, err := db.Exec(`
+	// Transitions made under a payment lock refuse to overwrite a newer
+	// holder's work; the others rely on the state check alone
+	fence := lockFence(ctx)
+
+	var processor string
+	var createdAt time.Time
+	var amount money.Money
+	err := db.QueryRowContext(ctx, `
 		UPDATE payment_states 
-		SET state = $1, previous_state = $2, updated_at = NOW()
+		SET state = $1, previous_state = $2, updated_at = NOW(), lock_fence = COALESCE($5, lock_fence)
 		WHERE payment_id = $3 AND state = $4
-	`, to, from, paymentID, from)
+			AND ($5::BIGINT IS NULL OR COALESCE(lock_fence, 0) <= $5)
+		RETURNING COALESCE(connector, ''), created_at, COALESCE(amount_minor, 0), COALESCE(currency, '')
+	`, to, from, paymentID, from, fence).Scan(&processor, &createdAt, &amount.Minor, &amount.Currency)
 
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("invalid state transition from %s to %s for payment %s (state changed or lock superseded)", from, to, paymentID)
+	}
 	if err != nil {
 		return err
 	}
 
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("invalid state transition from %s to %s for payment %s", from, to, paymentID)
+	stateTransitions.WithLabelValues(string(from), string(to)).Inc()
+	if isTerminal(to) {
+		paymentDuration.WithLabelValues(string(to)).Observe(time.Since(createdAt).Seconds())
 	}
 
 	// Publish state change event
@@ -310,14 +1048,22 @@ func transitionState(ctx context.Context, paymentID string, from, to PaymentStat
 		"payment_id":     paymentID,
 		"state":          to,
 		"previous_state": from,
+		"amount":         amount.Decimal(),
+		"amount_minor":   amount.Minor,
+		"currency":       amount.Currency,
 		"timestamp":      time.Now(),
 	}
+	if processor != "" {
+		stateEvent["processor"] = processor
+	}
 	eventJSON, _ := json.Marshal(stateEvent)
 
-	kafkaWriter.WriteMessages(ctx, kafka.Message{
-		Key:   []byte(paymentID),
-		Value: eventJSON,
-	})
+	msg := kafka.Message{Key: []byte(paymentID), Value: eventJSON}
+	publishCtx, span := telemetry.StartKafkaProducerSpan(ctx, kafkaWriter.Topic, &msg)
+	if err := kafkaWriter.WriteMessages(publishCtx, msg); err != nil {
+		span.RecordError(err)
+	}
+	span.End()
 
 	telemetry.Logger.Info("Payment state transition",
 		zap.String("payment_id", paymentID),
@@ -328,16 +1074,93 @@ func transitionState(ctx context.Context, paymentID string, from, to PaymentStat
 	return nil
 }
 
+// refundPayment refunds a succeeded payment in full at the processor
+func refundPayment(c *gin.Context) {
+	paymentID := c.Param("id")
+	ctx := c.Request.Context()
+
+	ctx, lease, err := lockPayment(ctx, paymentID, paymentLockPoll)
+	if errors.Is(err, lock.ErrNotAcquired) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment is being processed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock payment"})
+		return
+	}
+	defer unlockPayment(ctx, paymentID, lease)
+
+	var state PaymentState
+	var processorName, reference string
+	var amount money.Money
+	err = db.QueryRowContext(ctx, `
+		SELECT state, COALESCE(connector, ''), COALESCE(processor_reference, ''),
+			COALESCE(amount_minor, 0), COALESCE(currency, '')
+		FROM payment_states WHERE payment_id = $1
+	`, paymentID).Scan(&state, &processorName, &reference, &amount.Minor, &amount.Currency)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment state not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment state"})
+		return
+	}
+	if state != StateSucceeded || reference == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Payment in state %s cannot be refunded", state)})
+		return
+	}
+
+	// Refunds go to the processor that captured the payment
+	processor := router.Processor(processorName)
+	if processor == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Processor %s is not configured", processorName)})
+		return
+	}
+
+	if err := checkLease(lease, paymentID); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment is being processed"})
+		return
+	}
+	resp, err := callProcessor(ctx, paymentID, processor, "refund", func(c connector.Connector) (*connector.Response, error) {
+		return c.Refund(ctx, reference, amount)
+	})
+	if err != nil {
+		telemetry.Logger.Error("Error refunding payment", zap.String("payment_id", paymentID), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Processor refund failed"})
+		return
+	}
+	if resp.Status != connector.StatusRefunded {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Refund declined", "decline_code": resp.DeclineCode})
+		return
+	}
+
+	if err := transitionState(ctx, paymentID, StateSucceeded, StateRefunded); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment state"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment_id": paymentID, "state": StateRefunded, "reference": resp.Reference})
+}
+
 func getPaymentState(c *gin.Context) {
 	paymentID := c.Param("id")
 
-	var state, previousState, fraudDecision string
+	var state, previousState, fraudDecision, fraudReason string
+	var connectorName, reference, declineCode, processorMessage string
+	var fraudRiskScore sql.NullInt64
+	var fraudRules []byte
 	var createdAt, updatedAt time.Time
 
 	err := db.QueryRow(`
-		SELECT state, previous_state, fraud_decision, created_at, updated_at
+		SELECT state, COALESCE(previous_state, ''), COALESCE(fraud_decision, ''), COALESCE(fraud_reason, ''),
+			fraud_risk_score, COALESCE(fraud_rules, '[]'), COALESCE(connector, ''), COALESCE(processor_reference, ''),
+			COALESCE(decline_code, ''), COALESCE(processor_message, ''),
+			created_at, updated_at
 		FROM payment_states WHERE payment_id = $1
-	`, paymentID).Scan(&state, &previousState, &fraudDecision, &createdAt, &updatedAt)
+	`, paymentID).Scan(&state, &previousState, &fraudDecision, &fraudReason,
+		&fraudRiskScore, &fraudRules, &connectorName, &reference,
+		&declineCode, &processorMessage, &createdAt, &updatedAt)
 
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Payment state not found"})
@@ -349,12 +1172,39 @@ func getPaymentState(c *gin.Context) {
 		return
 	}
 
+	rules := []FraudRuleResult{}
+	json.Unmarshal(fraudRules, &rules)
+
+	paymentAttempts, err := attemptRepo.ForPayment(c.Request.Context(), paymentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment attempts"})
+		return
+	}
+
+	var riskScore *int64
+	if fraudRiskScore.Valid {
+		riskScore = &fraudRiskScore.Int64
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"payment_id":     paymentID,
 		"state":          state,
 		"previous_state": previousState,
 		"fraud_decision": fraudDecision,
-		"created_at":     createdAt,
-		"updated_at":     updatedAt,
+		"fraud": gin.H{
+			"decision":   fraudDecision,
+			"reason":     fraudReason,
+			"risk_score": riskScore,
+			"rules":      rules,
+		},
+		"processor": gin.H{
+			"connector":    connectorName,
+			"reference":    reference,
+			"decline_code": declineCode,
+			"message":      processorMessage,
+		},
+		"attempts":   paymentAttempts,
+		"created_at": createdAt,
+		"updated_at": updatedAt,
 	})
 }
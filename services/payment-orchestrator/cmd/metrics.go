@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	stateTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orchestrator_state_transitions_total",
+		Help: "Payment state machine transitions, by source and target state",
+	}, []string{"from", "to"})
+
+	// paymentDuration measures from the payment_states row being created to
+	// SUCCEEDED, FAILED or CANCELED, including fraud checks, manual review
+	// and processor calls
+	paymentDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "orchestrator_payment_duration_seconds",
+		Help:    "Time from payment creation to a terminal state, by state",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 1800, 3600, 86400},
+	}, []string{"state"})
+)
+
+// isTerminal reports whether a payment stops in state until a refund
+func isTerminal(state PaymentState) bool {
+	switch state {
+	case StateSucceeded, StateFailed, StateCanceled:
+		return true
+	}
+	return false
+}
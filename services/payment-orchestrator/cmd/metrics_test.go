@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestIsTerminal(t *testing.T) {
+	for state, want := range map[PaymentState]bool{
+		StateNew:           false,
+		StateAuthPending:   false,
+		StateReviewPending: false,
+		StateAuthorized:    false,
+		StateCaptured:      false,
+		StateSucceeded:     true,
+		StateFailed:        true,
+		StateCanceled:      true,
+		// A refund happens after the payment duration was observed
+		StateRefunded: false,
+	} {
+		if got := isTerminal(state); got != want {
+			t.Fatalf("isTerminal(%s) = %v, want %v", state, got, want)
+		}
+	}
+}
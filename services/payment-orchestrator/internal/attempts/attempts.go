@@ -0,0 +1,113 @@
+package attempts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/connector"
+)
+
+// Attempt is one call to a processor for a payment. A payment has one row
+// in payment_states and any number of attempts, e.g. after a failover.
+type Attempt struct {
+	ID            int64           `json:"id"`
+	PaymentID     string          `json:"payment_id"`
+	AttemptNumber int             `json:"attempt_number"`
+	Operation     string          `json:"operation"` // authorize, capture, void, refund
+	Connector     string          `json:"connector"`
+	Status        string          `json:"status"`
+	Reference     string          `json:"reference,omitempty"`
+	ResponseCode  string          `json:"response_code,omitempty"`
+	DeclineReason string          `json:"decline_reason,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	LatencyMs     int64           `json:"latency_ms"`
+	RawResponse   json.RawMessage `json:"raw_response,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// StatusError is the attempt status when the processor call itself failed
+const StatusError = "error"
+
+// FromResponse builds an attempt from a connector call
+func FromResponse(paymentID, operation, connectorName string, resp *connector.Response, err error, latency time.Duration) Attempt {
+	a := Attempt{
+		PaymentID: paymentID,
+		Operation: operation,
+		Connector: connectorName,
+		Status:    StatusError,
+		LatencyMs: latency.Milliseconds(),
+	}
+	if resp != nil {
+		a.Status = string(resp.Status)
+		a.Reference = resp.Reference
+		a.ResponseCode = resp.ResponseCode
+		a.DeclineReason = resp.DeclineCode
+		a.RawResponse = resp.Raw
+	}
+	if err != nil {
+		a.Status = StatusError
+		a.Error = err.Error()
+	}
+	return a
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Record stores an attempt with the next attempt number of its payment.
+// Callers hold the payment lock, so numbers do not race.
+func (r *Repository) Record(ctx context.Context, a *Attempt) error {
+	var raw interface{}
+	if len(a.RawResponse) > 0 {
+		raw = []byte(a.RawResponse)
+	}
+
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO payment_attempts (payment_id, attempt_number, operation, connector, status, reference,
+			response_code, decline_reason, error, latency_ms, raw_response)
+		SELECT $1, COALESCE(MAX(attempt_number), 0) + 1, $2, $3, $4, NULLIF($5, ''),
+			NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10::jsonb
+		FROM payment_attempts WHERE payment_id = $1
+		RETURNING id, attempt_number, created_at
+	`, a.PaymentID, a.Operation, a.Connector, a.Status, a.Reference,
+		a.ResponseCode, a.DeclineReason, a.Error, a.LatencyMs, raw,
+	).Scan(&a.ID, &a.AttemptNumber, &a.CreatedAt)
+}
+
+// ForPayment returns the attempts of a payment in order
+func (r *Repository) ForPayment(ctx context.Context, paymentID string) ([]Attempt, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, payment_id, attempt_number, operation, connector, status, COALESCE(reference, ''),
+			COALESCE(response_code, ''), COALESCE(decline_reason, ''), COALESCE(error, ''), latency_ms,
+			raw_response, created_at
+		FROM payment_attempts
+		WHERE payment_id = $1
+		ORDER BY attempt_number
+	`, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Attempt{}
+	for rows.Next() {
+		var a Attempt
+		var raw []byte
+		if err := rows.Scan(&a.ID, &a.PaymentID, &a.AttemptNumber, &a.Operation, &a.Connector, &a.Status,
+			&a.Reference, &a.ResponseCode, &a.DeclineReason, &a.Error, &a.LatencyMs, &raw, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(raw) > 0 {
+			a.RawResponse = raw
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,86 @@
+package attempts
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/connector"
+)
+
+func TestFromResponse(t *testing.T) {
+	raw := []byte(`{"id": "sim_p1"}`)
+
+	tests := []struct {
+		name string
+		resp *connector.Response
+		err  error
+		want Attempt
+	}{
+		{
+			"authorized",
+			&connector.Response{Reference: "sim_p1", Status: connector.StatusAuthorized, ResponseCode: "00", Raw: raw},
+			nil,
+			Attempt{Status: "authorized", Reference: "sim_p1", ResponseCode: "00", RawResponse: raw},
+		},
+		{
+			"declined",
+			&connector.Response{Reference: "sim_p1", Status: connector.StatusDeclined, ResponseCode: "51", DeclineCode: "insufficient_funds"},
+			nil,
+			Attempt{Status: "declined", Reference: "sim_p1", ResponseCode: "51", DeclineReason: "insufficient_funds"},
+		},
+		{
+			"call failed",
+			nil,
+			fmt.Errorf("authorize: %w", connector.ErrTimeout),
+			Attempt{Status: StatusError, Error: "authorize: processor timeout"},
+		},
+		// An error wins over a partial response
+		{
+			"response with an error",
+			&connector.Response{Reference: "sim_p1", Status: connector.StatusCaptured},
+			connector.ErrSoft,
+			Attempt{Status: StatusError, Reference: "sim_p1", Error: "temporary processor error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromResponse("p1", "authorize", "acquirer_a", tt.resp, tt.err, 1500*time.Microsecond)
+			want := tt.want
+			want.PaymentID, want.Operation, want.Connector, want.LatencyMs = "p1", "authorize", "acquirer_a", 1
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("FromResponse = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestAttemptJSON(t *testing.T) {
+	a := FromResponse("p1", "authorize", "acquirer_a",
+		&connector.Response{Reference: "sim_p1", Status: connector.StatusAuthorized, Raw: []byte(`{"id":"sim_p1"}`)},
+		nil, 20*time.Millisecond)
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	// The processor payload is embedded as JSON, empty fields are left out
+	if raw, ok := got["raw_response"].(map[string]interface{}); !ok || raw["id"] != "sim_p1" {
+		t.Fatalf("raw_response = %v", got["raw_response"])
+	}
+	for _, field := range []string{"response_code", "decline_reason", "error"} {
+		if _, ok := got[field]; ok {
+			t.Fatalf("empty %s serialized: %s", field, data)
+		}
+	}
+	if got["latency_ms"] != float64(20) {
+		t.Fatalf("latency_ms = %v, want 20", got["latency_ms"])
+	}
+}
@@ -0,0 +1,33 @@
+package attempts
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// Register mounts the payment attempt routes on the router
+func (h *Handler) Register(r gin.IRouter) {
+	r.GET("/payments/:id/attempts", h.List)
+}
+
+// List returns every processor attempt of a payment
+func (h *Handler) List(c *gin.Context) {
+	paymentID := c.Param("id")
+
+	list, err := h.repo.ForPayment(c.Request.Context(), paymentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment attempts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment_id": paymentID, "attempts": list})
+}
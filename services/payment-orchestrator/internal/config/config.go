@@ -0,0 +1,80 @@
+package config
+
+import "os"
+
+type Config struct {
+	DatabaseURL    string
+	RedisURL       string
+	KafkaBrokers   string
+	NATSURL        string
+	JaegerEndpoint string
+	Port           string
+
+	FraudCheckMode          string
+	FraudCheckTimeout       string
+	FraudRetryAttempts      string
+	FraudRetryBackoff       string
+	FraudRetryMaxBackoff    string
+	FraudBreakerFailures    string
+	FraudBreakerOpenTimeout string
+	FraudFallbackPolicy     string
+	FraudFallbackLimit      string
+	FraudFallbackMerchants  string
+
+	RoutingConfig          string
+	ConnectorTimeout       string
+	ConnectorRetryAttempts string
+
+	// ConsumerWorkers is the number of payment.created messages handled
+	// concurrently; payments are spread over workers by payment ID
+	ConsumerWorkers string
+
+	// ShutdownTimeout bounds draining in-flight work on SIGTERM
+	ShutdownTimeout string
+	// DrainDelay keeps serving after readiness turns false on SIGTERM, so
+	// load balancers stop routing before the listener closes
+	DrainDelay string
+}
+
+func Load() *Config {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8082"
+	}
+
+	return &Config{
+		DatabaseURL:    os.Getenv("DATABASE_URL"),
+		RedisURL:       os.Getenv("REDIS_URL"),
+		KafkaBrokers:   os.Getenv("KAFKA_BROKERS"),
+		NATSURL:        os.Getenv("NATS_URL"),
+		JaegerEndpoint: os.Getenv("JAEGER_ENDPOINT"),
+		Port:           port,
+
+		FraudCheckMode:          getEnv("FRAUD_CHECK_MODE", "sync"),
+		FraudCheckTimeout:       getEnv("FRAUD_CHECK_TIMEOUT", "2s"),
+		FraudRetryAttempts:      getEnv("FRAUD_RETRY_ATTEMPTS", "3"),
+		FraudRetryBackoff:       getEnv("FRAUD_RETRY_BACKOFF", "100ms"),
+		FraudRetryMaxBackoff:    getEnv("FRAUD_RETRY_MAX_BACKOFF", "1s"),
+		FraudBreakerFailures:    getEnv("FRAUD_BREAKER_FAILURES", "5"),
+		FraudBreakerOpenTimeout: getEnv("FRAUD_BREAKER_OPEN_TIMEOUT", "30s"),
+		FraudFallbackPolicy:     getEnv("FRAUD_FALLBACK_POLICY", "durable"),
+		FraudFallbackLimit:      getEnv("FRAUD_FALLBACK_APPROVE_LIMIT", "USD:100,EUR:100,GBP:100,KZT:50000,KGS:10000,RUB:10000,JPY:15000,KWD:30"),
+		FraudFallbackMerchants:  os.Getenv("FRAUD_FALLBACK_MERCHANTS"),
+
+		RoutingConfig:          os.Getenv("ROUTING_CONFIG"),
+		ConnectorTimeout:       getEnv("CONNECTOR_TIMEOUT", "5s"),
+		ConnectorRetryAttempts: getEnv("CONNECTOR_RETRY_ATTEMPTS", "3"),
+
+		ConsumerWorkers: getEnv("CONSUMER_WORKERS", "8"),
+
+		ShutdownTimeout: getEnv("SHUTDOWN_TIMEOUT", "10s"),
+		DrainDelay:      getEnv("DRAIN_DELAY", "0s"),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,102 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// Status is the processor side state of a transaction
+type Status string
+
+const (
+	StatusAuthorized Status = "authorized"
+	StatusCaptured   Status = "captured"
+	StatusVoided     Status = "voided"
+	StatusRefunded   Status = "refunded"
+	StatusDeclined   Status = "declined"
+)
+
+var (
+	// ErrSoft is a temporary processor failure; the call may be retried
+	ErrSoft = errors.New("temporary processor error")
+	// ErrTimeout means the outcome is unknown. Authorize is idempotent per
+	// payment, so retrying it is safe.
+	ErrTimeout = errors.New("processor timeout")
+	// ErrUnknownTransaction is returned for references the processor does
+	// not know
+	ErrUnknownTransaction = errors.New("unknown processor transaction")
+	// ErrInvalidOperation is returned when the transaction is in the wrong
+	// state, e.g. capturing a voided authorization
+	ErrInvalidOperation = errors.New("invalid operation for transaction state")
+)
+
+// Retryable reports whether a connector error may go away on retry
+func Retryable(err error) bool {
+	return errors.Is(err, ErrSoft) || errors.Is(err, ErrTimeout)
+}
+
+// softDeclines are issuer or processor side refusals that another
+// processor may well approve, unlike hard declines such as
+// insufficient_funds or expired_card
+var softDeclines = map[string]bool{
+	"do_not_honor":       true,
+	"issuer_unavailable": true,
+	"processing_error":   true,
+	"try_again_later":    true,
+}
+
+// SoftDecline reports whether a decline code is worth retrying on another
+// processor
+func SoftDecline(code string) bool {
+	return softDeclines[code]
+}
+
+// Request describes a payment to authorize. PaymentID doubles as the
+// idempotency key sent to the processor.
+type Request struct {
+	PaymentID     string
+	MerchantID    string
+	CustomerID    string
+	Amount        money.Money
+	PaymentMethod string
+	CardBIN       string
+}
+
+// Response is the processor answer. Declines are responses, not errors.
+type Response struct {
+	Reference string `json:"reference"`
+	Status    Status `json:"status"`
+	// ResponseCode is the processor's code in ISO 8583 style, "00" approved
+	ResponseCode string `json:"response_code,omitempty"`
+	DeclineCode  string `json:"decline_code,omitempty"`
+	Message      string `json:"message,omitempty"`
+	// Raw is the unparsed processor payload, kept for the attempt history
+	Raw []byte `json:"-"`
+}
+
+// Connector talks to an acquirer or PSP
+type Connector interface {
+	Name() string
+	Authorize(ctx context.Context, req Request) (*Response, error)
+	Capture(ctx context.Context, reference string, amount money.Money) (*Response, error)
+	Void(ctx context.Context, reference string) (*Response, error)
+	Refund(ctx context.Context, reference string, amount money.Money) (*Response, error)
+	GetStatus(ctx context.Context, reference string) (*Response, error)
+}
+
+// New builds a connector named name of the given kind. Only the in-process
+// "simulator" exists so far; simulatorConfig is an optional rules file.
+func New(name, kind, simulatorConfig string) (Connector, error) {
+	switch kind {
+	case "simulator":
+		if simulatorConfig == "" {
+			return NewSimulator(name, DefaultSimulatorConfig()), nil
+		}
+		return LoadSimulator(name, simulatorConfig)
+	default:
+		return nil, fmt.Errorf("unknown connector %q", kind)
+	}
+}
@@ -0,0 +1,83 @@
+package connector
+
+import (
+	"context"
+	"time"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// Retrying wraps a connector with a per-call timeout and retries on soft
+// errors and timeouts. Declines are never retried.
+type Retrying struct {
+	Connector
+	attempts int
+	timeout  time.Duration
+	backoff  time.Duration
+}
+
+func WithRetry(c Connector, attempts int, timeout, backoff time.Duration) *Retrying {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &Retrying{Connector: c, attempts: attempts, timeout: timeout, backoff: backoff}
+}
+
+func (r *Retrying) Authorize(ctx context.Context, req Request) (*Response, error) {
+	return r.do(ctx, func(ctx context.Context) (*Response, error) {
+		return r.Connector.Authorize(ctx, req)
+	})
+}
+
+func (r *Retrying) Capture(ctx context.Context, reference string, amount money.Money) (*Response, error) {
+	return r.do(ctx, func(ctx context.Context) (*Response, error) {
+		return r.Connector.Capture(ctx, reference, amount)
+	})
+}
+
+func (r *Retrying) Void(ctx context.Context, reference string) (*Response, error) {
+	return r.do(ctx, func(ctx context.Context) (*Response, error) {
+		return r.Connector.Void(ctx, reference)
+	})
+}
+
+func (r *Retrying) Refund(ctx context.Context, reference string, amount money.Money) (*Response, error) {
+	return r.do(ctx, func(ctx context.Context) (*Response, error) {
+		return r.Connector.Refund(ctx, reference, amount)
+	})
+}
+
+func (r *Retrying) GetStatus(ctx context.Context, reference string) (*Response, error) {
+	return r.do(ctx, func(ctx context.Context) (*Response, error) {
+		return r.Connector.GetStatus(ctx, reference)
+	})
+}
+
+func (r *Retrying) do(ctx context.Context, call func(context.Context) (*Response, error)) (*Response, error) {
+	var err error
+	for attempt := 1; attempt <= r.attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return nil, err
+			case <-time.After(r.backoff * time.Duration(attempt-1)):
+			}
+		}
+
+		var resp *Response
+		resp, err = r.once(ctx, call)
+		if err == nil || !Retryable(err) {
+			return resp, err
+		}
+	}
+	return nil, err
+}
+
+func (r *Retrying) once(ctx context.Context, call func(context.Context) (*Response, error)) (*Response, error) {
+	if r.timeout <= 0 {
+		return call(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return call(ctx)
+}
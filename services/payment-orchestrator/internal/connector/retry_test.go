@@ -0,0 +1,93 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// scripted fails authorizations with errs in turn and then approves them
+type scripted struct {
+	Connector
+	errs  []error
+	calls int
+}
+
+func (s *scripted) Authorize(ctx context.Context, req Request) (*Response, error) {
+	s.calls++
+	if s.calls > len(s.errs) {
+		return &Response{Status: StatusAuthorized}, nil
+	}
+	if err := s.errs[s.calls-1]; err != nil {
+		return nil, err
+	}
+	// A nil error is a decline
+	return &Response{Status: StatusDeclined, DeclineCode: "do_not_honor"}, nil
+}
+
+func TestRetrying(t *testing.T) {
+	tests := []struct {
+		name       string
+		errs       []error
+		wantErr    error
+		wantStatus Status
+		wantCalls  int
+	}{
+		{"approved", nil, nil, StatusAuthorized, 1},
+		{"soft errors are retried", []error{ErrSoft, fmt.Errorf("gateway: %w", ErrTimeout)}, nil, StatusAuthorized, 3},
+		{"gives up after the attempts", []error{ErrSoft, ErrSoft, ErrTimeout}, ErrTimeout, "", 3},
+		{"declines are not retried", []error{nil}, nil, StatusDeclined, 1},
+		{"other errors are not retried", []error{ErrInvalidOperation}, ErrInvalidOperation, "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &scripted{errs: tt.errs}
+			resp, err := WithRetry(c, 3, 0, time.Millisecond).Authorize(context.Background(), Request{PaymentID: "p1"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authorize error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && resp.Status != tt.wantStatus {
+				t.Fatalf("Authorize status = %s, want %s", resp.Status, tt.wantStatus)
+			}
+			if c.calls != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", c.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryingRecoversTimedOutAuthorization(t *testing.T) {
+	// The simulator holds timeouts until the caller gives up, but keeps the
+	// authorization, so the retry finds it
+	s := newSimulator()
+	r := WithRetry(s, 2, 10*time.Millisecond, time.Millisecond)
+
+	start := time.Now()
+	resp, err := r.Authorize(context.Background(), Request{PaymentID: "p1", Amount: usd(1000), CardBIN: "400012"})
+	if err != nil || resp.Status != StatusAuthorized {
+		t.Fatalf("Authorize = %+v, %v; want the authorization of the first attempt", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Authorize took %v, want the first attempt cut at 10ms", elapsed)
+	}
+
+	// Without retries the timeout reaches the caller
+	_, err = WithRetry(s, 1, 10*time.Millisecond, 0).Authorize(context.Background(),
+		Request{PaymentID: "p2", Amount: usd(1000), CardBIN: "400012"})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Authorize error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestRetryingStopsWhenCancelled(t *testing.T) {
+	c := &scripted{errs: []error{ErrSoft, ErrSoft}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := WithRetry(c, 3, 0, time.Hour).Authorize(ctx, Request{PaymentID: "p1"})
+	if !errors.Is(err, ErrSoft) || c.calls != 1 {
+		t.Fatalf("Authorize = %v after %d calls, want the first error without retrying", err, c.calls)
+	}
+}
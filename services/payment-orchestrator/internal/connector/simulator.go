@@ -0,0 +1,332 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+type Outcome string
+
+const (
+	OutcomeApprove   Outcome = "approve"
+	OutcomeDecline   Outcome = "decline"
+	OutcomeSoftError Outcome = "soft_error"
+	OutcomeTimeout   Outcome = "timeout"
+)
+
+// SimulatorRule injects an outcome for matching calls. Empty fields match
+// everything; CardBIN matches as a prefix. AmountMin and AmountMax are per
+// currency in major units, e.g. {"USD": 5000}, see MatchAmount. The first
+// matching rule wins.
+type SimulatorRule struct {
+	Operation   string       `json:"operation,omitempty"` // authorize, capture, void, refund
+	CardBIN     string       `json:"card_bin,omitempty"`
+	MerchantID  string       `json:"merchant_id,omitempty"`
+	AmountMin   money.Limits `json:"amount_min,omitempty"`
+	AmountMax   money.Limits `json:"amount_max,omitempty"`
+	Outcome     Outcome      `json:"outcome"`
+	DeclineCode string       `json:"decline_code,omitempty"`
+	// Latency is added on top of the base latency, e.g. "2s"
+	Latency string `json:"latency,omitempty"`
+	// Probability of applying the rule to a matching call, 1 when unset
+	Probability float64 `json:"probability,omitempty"`
+}
+
+// SimulatorConfig is the file format of the simulator
+type SimulatorConfig struct {
+	Latency string          `json:"latency"`
+	Rules   []SimulatorRule `json:"rules"`
+}
+
+// DefaultSimulatorConfig follows the usual test card conventions
+func DefaultSimulatorConfig() SimulatorConfig {
+	return SimulatorConfig{
+		Latency: "50ms",
+		Rules: []SimulatorRule{
+			{Operation: "authorize", CardBIN: "400002", Outcome: OutcomeDecline, DeclineCode: "card_declined"},
+			{Operation: "authorize", CardBIN: "400995", Outcome: OutcomeDecline, DeclineCode: "insufficient_funds"},
+			{Operation: "authorize", CardBIN: "400069", Outcome: OutcomeDecline, DeclineCode: "expired_card"},
+			{Operation: "authorize", CardBIN: "400119", Outcome: OutcomeSoftError},
+			{Operation: "authorize", CardBIN: "400012", Outcome: OutcomeTimeout},
+			{Operation: "capture", CardBIN: "400341", Outcome: OutcomeDecline, DeclineCode: "capture_failed"},
+		},
+	}
+}
+
+type transaction struct {
+	reference   string
+	request     Request
+	status      Status
+	declineCode string
+}
+
+// Simulator is an in-process connector for local development. It keeps
+// transactions in memory and is idempotent per payment id, so a retried
+// authorization after a timeout returns the original result.
+type Simulator struct {
+	name    string
+	latency time.Duration
+	rules   []SimulatorRule
+	delays  []time.Duration
+
+	mu           sync.Mutex
+	transactions map[string]*transaction
+	byPayment    map[string]*transaction
+}
+
+func NewSimulator(name string, config SimulatorConfig) *Simulator {
+	s := &Simulator{
+		name:         name,
+		rules:        config.Rules,
+		delays:       make([]time.Duration, len(config.Rules)),
+		transactions: make(map[string]*transaction),
+		byPayment:    make(map[string]*transaction),
+	}
+	s.latency, _ = time.ParseDuration(config.Latency)
+	for i, rule := range config.Rules {
+		s.delays[i], _ = time.ParseDuration(rule.Latency)
+	}
+	return s
+}
+
+// LoadSimulator reads a SimulatorConfig from a JSON file
+func LoadSimulator(name, path string) (*Simulator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var config SimulatorConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if _, err := time.ParseDuration(config.Latency); config.Latency != "" && err != nil {
+		return nil, fmt.Errorf("invalid simulator latency %q", config.Latency)
+	}
+	for _, rule := range config.Rules {
+		switch rule.Outcome {
+		case OutcomeApprove, OutcomeDecline, OutcomeSoftError, OutcomeTimeout:
+		default:
+			return nil, fmt.Errorf("unknown simulator outcome %q", rule.Outcome)
+		}
+		if _, err := time.ParseDuration(rule.Latency); rule.Latency != "" && err != nil {
+			return nil, fmt.Errorf("invalid simulator rule latency %q", rule.Latency)
+		}
+	}
+	return NewSimulator(name, config), nil
+}
+
+func (s *Simulator) Name() string {
+	return s.name
+}
+
+func (s *Simulator) Authorize(ctx context.Context, req Request) (*Response, error) {
+	s.mu.Lock()
+	existing := s.byPayment[req.PaymentID]
+	s.mu.Unlock()
+	if existing != nil {
+		return s.respond(ctx, s.latency, existing)
+	}
+
+	rule, delay := s.match("authorize", req)
+	tx := &transaction{reference: "sim_" + req.PaymentID, request: req, status: StatusAuthorized}
+
+	switch rule.Outcome {
+	case OutcomeDecline:
+		tx.status = StatusDeclined
+		tx.declineCode = rule.DeclineCode
+	case OutcomeSoftError:
+		return nil, s.fail(ctx, delay, ErrSoft)
+	case OutcomeTimeout:
+		// The processor authorized the payment but the answer was lost
+		s.store(tx)
+		return nil, s.fail(ctx, delay, ErrTimeout)
+	}
+
+	s.store(tx)
+	return s.respond(ctx, delay, tx)
+}
+
+func (s *Simulator) Capture(ctx context.Context, reference string, amount money.Money) (*Response, error) {
+	return s.transition(ctx, "capture", reference, amount, StatusCaptured, StatusAuthorized)
+}
+
+func (s *Simulator) Void(ctx context.Context, reference string) (*Response, error) {
+	return s.transition(ctx, "void", reference, money.Money{}, StatusVoided, StatusAuthorized)
+}
+
+func (s *Simulator) Refund(ctx context.Context, reference string, amount money.Money) (*Response, error) {
+	return s.transition(ctx, "refund", reference, amount, StatusRefunded, StatusCaptured)
+}
+
+func (s *Simulator) GetStatus(ctx context.Context, reference string) (*Response, error) {
+	s.mu.Lock()
+	tx := s.transactions[reference]
+	s.mu.Unlock()
+	if tx == nil {
+		return nil, ErrUnknownTransaction
+	}
+	return s.respond(ctx, s.latency, tx)
+}
+
+// transition moves a transaction from the given state to the target. Repeating an operation that already happened returns the current
+// state, so callers can retry after timeouts.
+func (s *Simulator) transition(ctx context.Context, operation, reference string, amount money.Money, to Status, from Status) (*Response, error) {
+	s.mu.Lock()
+	tx := s.transactions[reference]
+	s.mu.Unlock()
+	if tx == nil {
+		return nil, ErrUnknownTransaction
+	}
+	if amount.IsPositive() && (amount.Currency != tx.request.Amount.Currency || amount.Minor > tx.request.Amount.Minor) {
+		return nil, fmt.Errorf("%w: amount %s %s exceeds authorized %s %s", ErrInvalidOperation,
+			amount, amount.Currency, tx.request.Amount, tx.request.Amount.Currency)
+	}
+
+	req := tx.request
+	if amount.IsPositive() {
+		req.Amount = amount
+	}
+	rule, delay := s.match(operation, req)
+	switch rule.Outcome {
+	case OutcomeSoftError:
+		return nil, s.fail(ctx, delay, ErrSoft)
+	case OutcomeTimeout:
+		return nil, s.fail(ctx, delay, ErrTimeout)
+	case OutcomeDecline:
+		return s.respond(ctx, delay, &transaction{reference: reference, status: StatusDeclined, declineCode: rule.DeclineCode})
+	}
+
+	s.mu.Lock()
+	switch tx.status {
+	case to:
+	case from:
+		tx.status = to
+	default:
+		status := tx.status
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: cannot %s a %s transaction", ErrInvalidOperation, operation, status)
+	}
+	s.mu.Unlock()
+
+	return s.respond(ctx, delay, tx)
+}
+
+// match returns the first rule for the call, an approving rule if none
+// applies, and the latency to simulate
+func (s *Simulator) match(operation string, req Request) (SimulatorRule, time.Duration) {
+	for i, rule := range s.rules {
+		if rule.Operation != "" && rule.Operation != operation {
+			continue
+		}
+		if rule.CardBIN != "" && !strings.HasPrefix(req.CardBIN, rule.CardBIN) {
+			continue
+		}
+		if rule.MerchantID != "" && rule.MerchantID != req.MerchantID {
+			continue
+		}
+		if !MatchAmount(req.Amount, rule.AmountMin, rule.AmountMax) {
+			continue
+		}
+		if rule.Probability > 0 && rand.Float64() >= rule.Probability {
+			continue
+		}
+		return rule, s.latency + s.delays[i]
+	}
+	return SimulatorRule{Outcome: OutcomeApprove}, s.latency
+}
+
+func (s *Simulator) store(tx *transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transactions[tx.reference] = tx
+	s.byPayment[tx.request.PaymentID] = tx
+}
+
+func (s *Simulator) respond(ctx context.Context, delay time.Duration, tx *transaction) (*Response, error) {
+	if err := sleep(ctx, delay); err != nil {
+		return nil, ErrTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &Response{Reference: tx.reference, Status: tx.status, ResponseCode: "00", DeclineCode: tx.declineCode}
+	if tx.status == StatusDeclined {
+		if resp.DeclineCode == "" {
+			resp.DeclineCode = "do_not_honor"
+		}
+		resp.ResponseCode = responseCode(resp.DeclineCode)
+		resp.Message = "Declined by simulator"
+	}
+	resp.Raw, _ = json.Marshal(map[string]string{
+		"simulator":     s.name,
+		"id":            resp.Reference,
+		"status":        string(resp.Status),
+		"response_code": resp.ResponseCode,
+		"decline_code":  resp.DeclineCode,
+	})
+	return resp, nil
+}
+
+// responseCode maps simulator decline codes to ISO 8583 response codes
+func responseCode(declineCode string) string {
+	switch declineCode {
+	case "insufficient_funds":
+		return "51"
+	case "expired_card":
+		return "54"
+	case "issuer_unavailable":
+		return "91"
+	case "processing_error", "capture_failed":
+		return "96"
+	default:
+		return "05"
+	}
+}
+
+// fail waits for the rule latency and returns err. Timeouts block until the
+// caller gives up, or for 30s at most.
+func (s *Simulator) fail(ctx context.Context, delay time.Duration, err error) error {
+	if err == ErrTimeout {
+		sleep(ctx, 30*time.Second)
+		return ErrTimeout
+	}
+	if sleep(ctx, delay) != nil {
+		return ErrTimeout
+	}
+	return err
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// MatchAmount reports whether amount is within the per-currency bounds of
+// a rule. A rule without bounds matches every amount; a rule with bounds
+// only matches currencies it has a bound for.
+func MatchAmount(amount money.Money, min, max money.Limits) bool {
+	if len(min) == 0 && len(max) == 0 {
+		return true
+	}
+	lo, hasMin := min.Get(amount.Currency)
+	hi, hasMax := max.Get(amount.Currency)
+	if !hasMin && !hasMax {
+		return false
+	}
+	return (!hasMin || amount.Minor >= lo.Minor) && (!hasMax || amount.Minor <= hi.Minor)
+}
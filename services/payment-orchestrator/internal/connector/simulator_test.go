@@ -0,0 +1,229 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func usd(minor int64) money.Money {
+	return money.Money{Minor: minor, Currency: "USD"}
+}
+
+// newSimulator returns the default simulator without its base latency
+func newSimulator() *Simulator {
+	config := DefaultSimulatorConfig()
+	config.Latency = ""
+	return NewSimulator("sim", config)
+}
+
+func TestSimulatorAuthorize(t *testing.T) {
+	tests := []struct {
+		bin             string
+		wantErr         error
+		wantStatus      Status
+		wantDeclineCode string
+		wantCode        string
+	}{
+		{"411111", nil, StatusAuthorized, "", "00"},
+		{"400002", nil, StatusDeclined, "card_declined", "05"},
+		{"40099512", nil, StatusDeclined, "insufficient_funds", "51"},
+		{"400069", nil, StatusDeclined, "expired_card", "54"},
+		{"400119", ErrSoft, "", "", ""},
+		{"400012", ErrTimeout, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bin, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			resp, err := newSimulator().Authorize(ctx, Request{PaymentID: "p1", Amount: usd(1000), CardBIN: tt.bin})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authorize error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if resp.Status != tt.wantStatus || resp.DeclineCode != tt.wantDeclineCode || resp.ResponseCode != tt.wantCode {
+				t.Fatalf("Authorize = %+v", resp)
+			}
+			if resp.Reference != "sim_p1" || len(resp.Raw) == 0 {
+				t.Fatalf("Authorize reference %q raw %s", resp.Reference, resp.Raw)
+			}
+		})
+	}
+}
+
+func TestSimulatorAuthorizeIsIdempotent(t *testing.T) {
+	s := newSimulator()
+	req := Request{PaymentID: "p1", Amount: usd(1000), CardBIN: "400012"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.Authorize(ctx, req); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("first Authorize error = %v, want ErrTimeout", err)
+	}
+
+	// The timed out authorization went through on the processor side
+	resp, err := s.Authorize(context.Background(), req)
+	if err != nil || resp.Status != StatusAuthorized || resp.Reference != "sim_p1" {
+		t.Fatalf("retried Authorize = %+v, %v", resp, err)
+	}
+}
+
+func TestSimulatorTransitions(t *testing.T) {
+	s := newSimulator()
+	ctx := context.Background()
+	if _, err := s.Authorize(ctx, Request{PaymentID: "p1", Amount: usd(1000), CardBIN: "411111"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		call       func() (*Response, error)
+		wantErr    error
+		wantStatus Status
+	}{
+		{"refund before capture", func() (*Response, error) { return s.Refund(ctx, "sim_p1", usd(1000)) }, ErrInvalidOperation, ""},
+		{"capture above the authorization", func() (*Response, error) { return s.Capture(ctx, "sim_p1", usd(1001)) }, ErrInvalidOperation, ""},
+		{"capture in another currency", func() (*Response, error) {
+			return s.Capture(ctx, "sim_p1", money.Money{Minor: 1000, Currency: "EUR"})
+		}, ErrInvalidOperation, ""},
+		{"partial capture", func() (*Response, error) { return s.Capture(ctx, "sim_p1", usd(600)) }, nil, StatusCaptured},
+		{"repeated capture", func() (*Response, error) { return s.Capture(ctx, "sim_p1", usd(600)) }, nil, StatusCaptured},
+		{"void after capture", func() (*Response, error) { return s.Void(ctx, "sim_p1") }, ErrInvalidOperation, ""},
+		{"refund", func() (*Response, error) { return s.Refund(ctx, "sim_p1", usd(600)) }, nil, StatusRefunded},
+		{"status", func() (*Response, error) { return s.GetStatus(ctx, "sim_p1") }, nil, StatusRefunded},
+		{"unknown reference", func() (*Response, error) { return s.Capture(ctx, "sim_p2", usd(1)) }, ErrUnknownTransaction, ""},
+		{"status of unknown reference", func() (*Response, error) { return s.GetStatus(ctx, "sim_p2") }, ErrUnknownTransaction, ""},
+	}
+	for _, tt := range tests {
+		resp, err := tt.call()
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && resp.Status != tt.wantStatus {
+			t.Fatalf("%s: status = %s, want %s", tt.name, resp.Status, tt.wantStatus)
+		}
+	}
+
+	// Capture declines leave the authorization in place
+	if _, err := s.Authorize(ctx, Request{PaymentID: "p2", Amount: usd(1000), CardBIN: "400341"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.Capture(ctx, "sim_p2", usd(1000))
+	if err != nil || resp.Status != StatusDeclined || resp.DeclineCode != "capture_failed" || resp.ResponseCode != "96" {
+		t.Fatalf("Capture = %+v, %v", resp, err)
+	}
+	if resp, _ := s.GetStatus(ctx, "sim_p2"); resp.Status != StatusAuthorized {
+		t.Fatalf("status after declined capture = %s, want authorized", resp.Status)
+	}
+}
+
+func TestSimulatorRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "simulator.json")
+	err := os.WriteFile(path, []byte(`{"rules": [
+		{"operation": "authorize", "merchant_id": "m_risky", "outcome": "decline", "decline_code": "do_not_honor"},
+		{"operation": "authorize", "amount_min": {"USD": 5000, "JPY": 500000}, "outcome": "decline", "decline_code": "issuer_unavailable"},
+		{"operation": "authorize", "amount_max": {"USD": "0.99"}, "outcome": "soft_error", "probability": 1}
+	]}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSimulator("sim", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		req             Request
+		wantErr         error
+		wantDeclineCode string
+	}{
+		{"merchant rule", Request{MerchantID: "m_risky", Amount: usd(100)}, nil, "do_not_honor"},
+		{"at the minimum", Request{Amount: usd(500000)}, nil, "issuer_unavailable"},
+		{"below the minimum", Request{Amount: usd(499999)}, nil, ""},
+		{"minimum in another currency", Request{Amount: money.Money{Minor: 500000, Currency: "JPY"}}, nil, "issuer_unavailable"},
+		{"currency without bounds", Request{Amount: money.Money{Minor: 99999999, Currency: "EUR"}}, nil, ""},
+		{"at the maximum", Request{Amount: usd(99)}, ErrSoft, ""},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.PaymentID = string(rune('a' + i))
+			resp, err := s.Authorize(context.Background(), tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authorize error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && resp.DeclineCode != tt.wantDeclineCode {
+				t.Fatalf("Authorize = %+v, want decline code %q", resp, tt.wantDeclineCode)
+			}
+		})
+	}
+}
+
+func TestLoadSimulatorRejectsInvalidConfig(t *testing.T) {
+	for _, config := range []string{
+		`not json`,
+		`{"latency": "fast"}`,
+		`{"rules": [{"outcome": "fraud"}]}`,
+		`{"rules": [{"outcome": "approve", "latency": "1 second"}]}`,
+		`{"rules": [{"outcome": "decline", "amount_min": {"USD": -1}}]}`,
+		`{"rules": [{"outcome": "decline", "amount_max": {"XXX": 1}}]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "simulator.json")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSimulator("sim", path); err == nil {
+			t.Fatalf("LoadSimulator(%s) succeeded", config)
+		}
+	}
+}
+
+func TestMatchAmount(t *testing.T) {
+	min := money.Limits{"USD": usd(1000)}
+	max := money.Limits{"USD": usd(5000), "EUR": money.Money{Minor: 4000, Currency: "EUR"}}
+
+	tests := []struct {
+		name   string
+		amount money.Money
+		min    money.Limits
+		max    money.Limits
+		want   bool
+	}{
+		{"no bounds", usd(1), nil, nil, true},
+		{"within", usd(3000), min, max, true},
+		{"at the minimum", usd(1000), min, max, true},
+		{"at the maximum", usd(5000), min, max, true},
+		{"below", usd(999), min, max, false},
+		{"above", usd(5001), min, max, false},
+		{"only a maximum", money.Money{Minor: 1, Currency: "EUR"}, min, max, true},
+		{"above the only maximum", money.Money{Minor: 4001, Currency: "EUR"}, min, max, false},
+		{"currency without bounds", money.Money{Minor: 1, Currency: "JPY"}, min, max, false},
+	}
+	for _, tt := range tests {
+		if got := MatchAmount(tt.amount, tt.min, tt.max); got != tt.want {
+			t.Fatalf("%s: MatchAmount = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSoftDecline(t *testing.T) {
+	for code, want := range map[string]bool{
+		"do_not_honor":       true,
+		"issuer_unavailable": true,
+		"insufficient_funds": false,
+		"expired_card":       false,
+		"":                   false,
+	} {
+		if got := SoftDecline(code); got != want {
+			t.Fatalf("SoftDecline(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
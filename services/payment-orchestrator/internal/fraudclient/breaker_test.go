@@ -0,0 +1,74 @@
+package fraudclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	const timeout = time.Minute
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Every step runs at start+at and leaves the breaker in wantState
+	type step struct {
+		op        string // allow, success or failure
+		at        time.Duration
+		wantErr   error
+		wantState State
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"opens after consecutive failures", []step{
+			{"allow", 0, nil, StateClosed},
+			{"failure", 0, nil, StateClosed},
+			{"allow", 0, nil, StateClosed},
+			{"failure", 0, nil, StateOpen},
+			{"allow", timeout - time.Second, ErrCircuitOpen, StateOpen},
+		}},
+		{"success resets the failure count", []step{
+			{"failure", 0, nil, StateClosed},
+			{"success", 0, nil, StateClosed},
+			{"failure", 0, nil, StateClosed},
+		}},
+		{"single probe after the timeout", []step{
+			{"failure", 0, nil, StateClosed},
+			{"failure", 0, nil, StateOpen},
+			{"allow", timeout, nil, StateHalfOpen},
+			{"allow", timeout, ErrCircuitOpen, StateHalfOpen},
+			{"success", timeout, nil, StateClosed},
+			{"allow", timeout, nil, StateClosed},
+		}},
+		{"failed probe reopens the circuit", []step{
+			{"failure", 0, nil, StateClosed},
+			{"failure", 0, nil, StateOpen},
+			{"allow", timeout, nil, StateHalfOpen},
+			{"failure", timeout, nil, StateOpen},
+			// The open timeout restarts at the failed probe
+			{"allow", 2*timeout - time.Second, ErrCircuitOpen, StateOpen},
+			{"allow", 2 * timeout, nil, StateHalfOpen},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(2, timeout)
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+				var err error
+				switch s.op {
+				case "allow":
+					err = b.Allow(now)
+				case "success":
+					b.Success()
+				case "failure":
+					b.Failure(now)
+				}
+				if err != s.wantErr || b.State() != s.wantState {
+					t.Fatalf("step %d (%s at %v): err %v state %s, want %v %s",
+						i, s.op, s.at, err, b.State(), s.wantErr, s.wantState)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package fraudclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/akylbek/payment-system/pkg/platform/natsclient"
+)
+
+// RetryPolicy retries failed requests with exponential backoff and jitter
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// delay returns the wait before the given retry (1-based), with up to 50%
+// jitter so replicas do not retry in lockstep
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff << (retry - 1)
+	if d <= 0 || d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Client sends fraud checks over NATS request-reply through a circuit
+// breaker. Each attempt has its own timeout.
+type Client struct {
+	nc      *nats.Conn
+	subject string
+	timeout time.Duration
+	retry   RetryPolicy
+	breaker *Breaker
+}
+
+func New(nc *nats.Conn, subject string, timeout time.Duration, retry RetryPolicy, breaker *Breaker) *Client {
+	if retry.Attempts < 1 {
+		retry.Attempts = 1
+	}
+	return &Client{nc: nc, subject: subject, timeout: timeout, retry: retry, breaker: breaker}
+}
+
+func (c *Client) Breaker() *Breaker {
+	return c.breaker
+}
+
+// Check returns the raw fraud service reply. It returns ErrCircuitOpen
+// without calling the service while the circuit is open, and an error
+// wrapping natsclient.ErrRejected without retrying when the service
+// rejected the request itself.
+func (c *Client) Check(ctx context.Context, data []byte) ([]byte, error) {
+	var lastErr error
+	for attempt := 1; attempt <= c.retry.Attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(c.retry.delay(attempt - 1)):
+			}
+		}
+
+		if err := c.breaker.Allow(time.Now()); err != nil {
+			calls.WithLabelValues("rejected").Inc()
+			if lastErr != nil {
+				return nil, errors.Join(err, lastErr)
+			}
+			return nil, err
+		}
+
+		reply, err := c.request(ctx, data)
+		if err == nil {
+			c.breaker.Success()
+			calls.WithLabelValues("success").Inc()
+			return reply, nil
+		}
+		if errors.Is(err, natsclient.ErrRejected) {
+			// The service is up; the same request would be rejected again
+			c.breaker.Success()
+			calls.WithLabelValues("invalid").Inc()
+			return nil, err
+		}
+
+		c.breaker.Failure(time.Now())
+		calls.WithLabelValues("error").Inc()
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (c *Client) request(ctx context.Context, data []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	ctx, span := natsclient.StartClientSpan(ctx, c.subject)
+	defer span.End()
+
+	reply, err := c.nc.RequestMsgWithContext(ctx, natsclient.NewMsg(ctx, c.subject, data))
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := natsclient.ReplyError(reply); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return reply.Data, nil
+}
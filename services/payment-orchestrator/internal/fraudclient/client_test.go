@@ -0,0 +1,146 @@
+package fraudclient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+
+	"github.com/akylbek/payment-system/pkg/platform/natsclient"
+	"github.com/akylbek/payment-system/pkg/platform/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	// Spans go to the no-op global provider
+	telemetry.Tracer = otel.Tracer("fraudclient-test")
+	os.Exit(m.Run())
+}
+
+const testSubject = "fraud.check"
+
+func connect(t *testing.T) *nats.Conn {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+// respond answers requests with reply; the first `drop` requests get no
+// answer and time out. It returns the number of requests received.
+func respond(t *testing.T, nc *nats.Conn, drop int32, reply func(*nats.Msg)) *atomic.Int32 {
+	t.Helper()
+	var received atomic.Int32
+	_, err := nc.Subscribe(testSubject, func(msg *nats.Msg) {
+		if received.Add(1) > drop {
+			reply(msg)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &received
+}
+
+func echo(msg *nats.Msg) { msg.Respond(msg.Data) }
+
+func reject(msg *nats.Msg) { natsclient.RespondError(msg, errors.New("invalid request")) }
+
+func TestClientCheck(t *testing.T) {
+	retry := RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+	tests := []struct {
+		name         string
+		drop         int32
+		reply        func(*nats.Msg)
+		threshold    int
+		wantErr      error
+		wantRequests int32
+		wantState    State
+	}{
+		{"answered", 0, echo, 5, nil, 1, StateClosed},
+		{"retried after timeouts", 2, echo, 5, nil, 3, StateClosed},
+		{"every attempt times out", 3, echo, 5, context.DeadlineExceeded, 3, StateClosed},
+		// The service is up, so a rejection is neither retried nor a failure
+		{"rejected", 0, reject, 1, natsclient.ErrRejected, 1, StateClosed},
+		{"circuit opens between attempts", 3, echo, 2, ErrCircuitOpen, 2, StateOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nc := connect(t)
+			received := respond(t, nc, tt.drop, tt.reply)
+			client := New(nc, testSubject, 50*time.Millisecond, retry, NewBreaker(tt.threshold, time.Minute))
+
+			reply, err := client.Check(context.Background(), []byte(`{"payment_id": "p1"}`))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Check error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || string(reply) != `{"payment_id": "p1"}` {
+				t.Fatalf("Check = %s, %v", reply, err)
+			}
+			if got := received.Load(); got != tt.wantRequests {
+				t.Fatalf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if got := client.Breaker().State(); got != tt.wantState {
+				t.Fatalf("breaker state = %s, want %s", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestClientCheckWhileCircuitOpen(t *testing.T) {
+	nc := connect(t)
+	received := respond(t, nc, 0, echo)
+	breaker := NewBreaker(1, time.Minute)
+	breaker.Failure(time.Now())
+
+	client := New(nc, testSubject, time.Second, RetryPolicy{Attempts: 3}, breaker)
+	if _, err := client.Check(context.Background(), []byte(`{}`)); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Check error = %v, want ErrCircuitOpen", err)
+	}
+	if got := received.Load(); got != 0 {
+		t.Fatalf("requests = %d, want none while the circuit is open", got)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		// The shift overflows
+		{64, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			if d := p.delay(tt.retry); d < tt.max/2 || d > tt.max {
+				t.Fatalf("delay(%d) = %v, want between %v and %v", tt.retry, d, tt.max/2, tt.max)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package fraudclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// Policy decides a payment when the fraud service cannot be reached
+type Policy string
+
+const (
+	// PolicyFail declines the payment
+	PolicyFail Policy = "fail"
+	// PolicyApproveBelow approves payments up to a limit and declines the rest
+	PolicyApproveBelow Policy = "approve_below"
+	// PolicyManualReview sends the payment to the manual review queue
+	PolicyManualReview Policy = "manual_review"
+	// PolicyDurable queues the check on JetStream until the service is back
+	PolicyDurable Policy = "durable"
+)
+
+// Rule is the fallback of one merchant. Limits only apply to approve_below;
+// payments in a currency without a limit are declined.
+type Rule struct {
+	Policy Policy
+	Limits money.Limits
+}
+
+// Approves reports whether approve_below lets a payment of amount through
+// and returns the limit it was compared with
+func (r Rule) Approves(amount money.Money) (money.Money, bool) {
+	limit, ok := r.Limits.Get(amount.Currency)
+	return limit, ok && amount.Minor <= limit.Minor
+}
+
+// Fallbacks holds the default rule and per-merchant overrides
+type Fallbacks struct {
+	Default   Rule
+	Merchants map[string]Rule
+}
+
+// For returns the rule of a merchant, or the default
+func (f *Fallbacks) For(merchantID string) Rule {
+	if rule, ok := f.Merchants[merchantID]; ok {
+		return rule
+	}
+	return f.Default
+}
+
+// ParseFallbacks builds the fallback configuration. defaultLimits is a comma
+// separated list of currency:limit in major units, e.g. "USD:100,JPY:15000".
+// merchants is a comma separated list of merchant:policy[:currency:limit],
+// e.g. "m_1:approve_below:USD:50,m_1:approve_below:EUR:45,m_2:manual_review";
+// entries of the same merchant add limits for more currencies. Currencies a
+// merchant sets no limit for inherit defaultLimits.
+func ParseFallbacks(defaultPolicy, defaultLimits, merchants string) (*Fallbacks, error) {
+	limits, err := money.ParseLimits(defaultLimits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fallback approve limits %q: %w", defaultLimits, err)
+	}
+
+	policy, err := parsePolicy(defaultPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fallbacks{Default: Rule{Policy: policy, Limits: limits}, Merchants: map[string]Rule{}}
+	for _, item := range strings.Split(merchants, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if (len(parts) != 2 && len(parts) != 4) || parts[0] == "" {
+			return nil, fmt.Errorf("invalid merchant fallback %q, expected merchant:policy[:currency:limit]", item)
+		}
+		rule, ok := f.Merchants[parts[0]]
+		if !ok {
+			rule = Rule{Limits: money.Limits{}}
+			for currency, limit := range limits {
+				rule.Limits[currency] = limit
+			}
+		}
+		p, err := parsePolicy(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if ok && p != rule.Policy {
+			return nil, fmt.Errorf("merchant fallback %q conflicts with policy %s", item, rule.Policy)
+		}
+		rule.Policy = p
+		if len(parts) == 4 {
+			limit, err := money.ParseLimit(parts[2], parts[3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid limit in merchant fallback %q: %w", item, err)
+			}
+			rule.Limits[limit.Currency] = limit
+		}
+		f.Merchants[parts[0]] = rule
+	}
+	return f, nil
+}
+
+func parsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.TrimSpace(s)); p {
+	case PolicyFail, PolicyApproveBelow, PolicyManualReview, PolicyDurable:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown fraud fallback policy %q", s)
+	}
+}
@@ -0,0 +1,81 @@
+package fraudclient
+
+import (
+	"testing"
+
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func usd(minor int64) money.Money {
+	return money.Money{Minor: minor, Currency: "USD"}
+}
+
+func TestParseFallbacks(t *testing.T) {
+	f, err := ParseFallbacks("approve_below", "USD:100,JPY:15000",
+		" m_1:approve_below:USD:50 , m_1:approve_below:EUR:45,m_2:manual_review,,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		merchant   string
+		amount     money.Money
+		wantPolicy Policy
+		wantLimit  money.Money
+		wantOK     bool
+	}{
+		{"unknown", usd(10000), PolicyApproveBelow, usd(10000), true},
+		{"unknown", usd(10001), PolicyApproveBelow, usd(10000), false},
+		{"unknown", money.Money{Minor: 15000, Currency: "JPY"}, PolicyApproveBelow, money.Money{Minor: 15000, Currency: "JPY"}, true},
+		// Currencies without a limit are declined
+		{"unknown", money.Money{Minor: 1, Currency: "EUR"}, PolicyApproveBelow, money.Money{}, false},
+		{"m_1", usd(5000), PolicyApproveBelow, usd(5000), true},
+		{"m_1", usd(5001), PolicyApproveBelow, usd(5000), false},
+		{"m_1", money.Money{Minor: 4500, Currency: "EUR"}, PolicyApproveBelow, money.Money{Minor: 4500, Currency: "EUR"}, true},
+		// Inherited from the default limits
+		{"m_1", money.Money{Minor: 15001, Currency: "JPY"}, PolicyApproveBelow, money.Money{Minor: 15000, Currency: "JPY"}, false},
+		{"m_2", usd(100), PolicyManualReview, usd(10000), true},
+	}
+	for _, tt := range tests {
+		rule := f.For(tt.merchant)
+		if rule.Policy != tt.wantPolicy {
+			t.Fatalf("For(%s).Policy = %s, want %s", tt.merchant, rule.Policy, tt.wantPolicy)
+		}
+		limit, ok := rule.Approves(tt.amount)
+		if limit != tt.wantLimit || ok != tt.wantOK {
+			t.Fatalf("For(%s).Approves(%d %s) = %+v, %v; want %+v, %v",
+				tt.merchant, tt.amount.Minor, tt.amount.Currency, limit, ok, tt.wantLimit, tt.wantOK)
+		}
+	}
+
+	// Merchant limits do not leak into the defaults
+	if _, ok := f.Default.Limits["EUR"]; ok || f.Default.Limits["USD"] != usd(10000) {
+		t.Fatalf("default limits = %v", f.Default.Limits)
+	}
+}
+
+func TestParseFallbacksRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		limits    string
+		merchants string
+	}{
+		{"unknown default policy", "approve", "", ""},
+		{"invalid default limit", "fail", "USD", ""},
+		{"negative default limit", "fail", "USD:-1", ""},
+		{"unknown merchant policy", "fail", "", "m_1:allow"},
+		{"missing policy", "fail", "", "m_1"},
+		{"missing merchant", "fail", "", ":fail"},
+		{"limit without currency", "fail", "", "m_1:approve_below:50"},
+		{"invalid merchant limit", "fail", "", "m_1:approve_below:USD:1.234"},
+		{"conflicting policies", "fail", "", "m_1:approve_below:USD:50,m_1:manual_review"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseFallbacks(tt.policy, tt.limits, tt.merchants); err == nil {
+				t.Fatal("ParseFallbacks succeeded")
+			}
+		})
+	}
+}
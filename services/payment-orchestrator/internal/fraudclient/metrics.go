@@ -0,0 +1,30 @@
+package fraudclient
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	circuitState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "orchestrator_fraud_circuit_state",
+		Help: "State of the fraud check circuit breaker (0 closed, 1 half-open, 2 open)",
+	})
+
+	circuitTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orchestrator_fraud_circuit_transitions_total",
+		Help: "Fraud check circuit breaker state changes",
+	}, []string{"from", "to"})
+
+	calls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orchestrator_fraud_calls_total",
+		Help: "Fraud check attempts by result (success, error, invalid, rejected)",
+	}, []string{"result"})
+
+	// FallbackDecisions counts payments decided by a fallback policy instead of
+	// the fraud service
+	FallbackDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orchestrator_fraud_fallbacks_total",
+		Help: "Payments handled by a fraud fallback policy, by policy and outcome",
+	}, []string{"policy", "outcome"})
+)
@@ -0,0 +1,220 @@
+// Package lock implements Redis leases with owner tokens and fencing
+// tokens.
+//
+// Only the owner can renew or release a lease, so a worker whose lease
+// expired cannot delete the lease of the next holder. Every acquisition also
+// gets a fencing token from a counter that only grows; writes guarded by the
+// lease store the token and refuse older ones, which stops a holder that
+// lost its lease (e.g. after a long GC pause) from overwriting newer work.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotAcquired is returned when the lease is still held by another
+	// owner
+	ErrNotAcquired = errors.New("lock is held by another owner")
+	// ErrLost is returned by Check once the lease expired or was taken over
+	ErrLost = errors.New("lock lost")
+)
+
+// fenceKey is a single counter shared by all leases, so tokens keep growing
+// even when a lease key expires
+const fenceKey = "lock:fence"
+
+var (
+	acquireScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+	renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+	releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+)
+
+type Locker struct {
+	client *redis.Client
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// NewLocker returns a locker whose leases expire after ttl unless renewed.
+// Held leases are renewed every ttl/3.
+func NewLocker(client *redis.Client, ttl time.Duration, logger *zap.Logger) *Locker {
+	return &Locker{client: client, ttl: ttl, logger: logger}
+}
+
+// Lease is a held lock. It is renewed in the background until Release.
+type Lease struct {
+	locker *Locker
+	key    string
+	token  string
+	fence  int64
+
+	stop chan struct{}
+	done chan struct{}
+	lost chan struct{}
+}
+
+// TryAcquire takes the lease on key or returns ErrNotAcquired
+func (l *Locker) TryAcquire(ctx context.Context, key string) (*Lease, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// The TTL runs from the moment Redis may have set the key
+	acquired := time.Now()
+	fence, err := acquireScript.Run(ctx, l.client, []string{key, fenceKey}, token, l.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if fence == 0 {
+		return nil, ErrNotAcquired
+	}
+
+	lease := &Lease{
+		locker: l,
+		key:    key,
+		token:  token,
+		fence:  fence,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	go lease.renew(acquired)
+	return lease, nil
+}
+
+// Acquire waits up to wait for the lease on key, polling while another
+// owner holds it
+func (l *Locker) Acquire(ctx context.Context, key string, wait time.Duration) (*Lease, error) {
+	deadline := time.Now().Add(wait)
+	delay := 50 * time.Millisecond
+
+	for {
+		lease, err := l.TryAcquire(ctx, key)
+		if !errors.Is(err, ErrNotAcquired) || time.Now().After(deadline) {
+			return lease, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay < time.Second {
+			delay *= 2
+		}
+	}
+}
+
+// Fence is the fencing token of this acquisition
+func (k *Lease) Fence() int64 {
+	return k.fence
+}
+
+// Lost is closed when a renewal finds the lease owned by someone else or
+// expired, or when renewals kept failing for a whole TTL
+func (k *Lease) Lost() <-chan struct{} {
+	return k.lost
+}
+
+// Check returns ErrLost once the lease is lost. Holders call it before work
+// that a fencing token cannot guard, such as calls to other services.
+func (k *Lease) Check() error {
+	select {
+	case <-k.lost:
+		return ErrLost
+	default:
+		return nil
+	}
+}
+
+// Release stops renewing and deletes the lease if it is still ours
+func (k *Lease) Release(ctx context.Context) error {
+	close(k.stop)
+	<-k.done
+
+	return releaseScript.Run(ctx, k.locker.client, []string{k.key}, k.token).Err()
+}
+
+func (k *Lease) renew(renewed time.Time) {
+	defer close(k.done)
+
+	ticker := time.NewTicker(k.locker.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-k.stop:
+			return
+		case <-ticker.C:
+		}
+
+		attempt := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), k.locker.ttl/3)
+		ok, err := renewScript.Run(ctx, k.locker.client, []string{k.key}, k.token, k.locker.ttl.Milliseconds()).Int64()
+		cancel()
+		if err != nil {
+			k.locker.logger.Warn("Error renewing lock", zap.String("key", k.key), zap.Error(err))
+			// Redis may be back before the lease expires; the next tick
+			// tries again. Past the TTL the key may be gone and taken.
+			if time.Since(renewed) < k.locker.ttl {
+				continue
+			}
+		} else if ok != 0 {
+			renewed = attempt
+			continue
+		}
+
+		k.locker.logger.Warn("Lock lost", zap.String("key", k.key), zap.Int64("fence", k.fence))
+		close(k.lost)
+		<-k.stop
+		return
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type fenceContextKey struct{}
+
+// WithFence returns a context carrying the fencing token of a lease, for
+// the writes made on its behalf
+func WithFence(ctx context.Context, fence int64) context.Context {
+	return context.WithValue(ctx, fenceContextKey{}, fence)
+}
+
+// FenceFrom returns the fencing token carried by ctx, if any
+func FenceFrom(ctx context.Context) (int64, bool) {
+	fence, ok := ctx.Value(fenceContextKey{}).(int64)
+	return fence, ok
+}
@@ -0,0 +1,223 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const testTTL = 300 * time.Millisecond
+
+func newTestLocker(t *testing.T) (*Locker, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewLocker(client, testTTL, zap.NewNop()), mr
+}
+
+func TestTryAcquireHeldLease(t *testing.T) {
+	locker, _ := newTestLocker(t)
+	ctx := context.Background()
+
+	lease, err := locker.TryAcquire(ctx, "payment_lock:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release(ctx)
+
+	if _, err := locker.TryAcquire(ctx, "payment_lock:1"); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("second TryAcquire: got %v, want ErrNotAcquired", err)
+	}
+}
+
+func TestReleaseOnlyDeletesOwnLease(t *testing.T) {
+	locker, mr := newTestLocker(t)
+	ctx := context.Background()
+
+	stale, err := locker.TryAcquire(ctx, "payment_lock:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first holder stalls past its TTL and the next one takes over
+	mr.FastForward(testTTL)
+	current, err := locker.TryAcquire(ctx, "payment_lock:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stale.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.Get("payment_lock:1"); got != current.token {
+		t.Fatalf("lease owner after stale release = %q, want %q", got, current.token)
+	}
+	if _, err := locker.TryAcquire(ctx, "payment_lock:1"); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("TryAcquire after stale release: got %v, want ErrNotAcquired", err)
+	}
+
+	if err := current.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("payment_lock:1") {
+		t.Fatal("lease still exists after the owner released it")
+	}
+}
+
+func TestLeaseIsRenewed(t *testing.T) {
+	locker, mr := newTestLocker(t)
+	ctx := context.Background()
+
+	lease, err := locker.TryAcquire(ctx, "payment_lock:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release(ctx)
+
+	mr.FastForward(2 * testTTL / 3)
+	waitFor(t, func() bool { return mr.TTL("payment_lock:1") == testTTL })
+
+	if err := lease.Check(); err != nil {
+		t.Fatalf("Check on a renewed lease: %v", err)
+	}
+}
+
+func TestLeaseLost(t *testing.T) {
+	locker, mr := newTestLocker(t)
+	ctx := context.Background()
+
+	lease, err := locker.TryAcquire(ctx, "payment_lock:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release(ctx)
+
+	mr.Set("payment_lock:1", "someone else")
+
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease not reported lost")
+	}
+	if err := lease.Check(); !errors.Is(err, ErrLost) {
+		t.Fatalf("Check on a lost lease: got %v, want ErrLost", err)
+	}
+	if got, _ := mr.Get("payment_lock:1"); got != "someone else" {
+		t.Fatalf("lost lease renewed over the new owner, value %q", got)
+	}
+}
+
+func TestLeaseLostWhenRedisIsDown(t *testing.T) {
+	locker, mr := newTestLocker(t)
+	ctx := context.Background()
+
+	lease, err := locker.TryAcquire(ctx, "payment_lock:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release(ctx)
+
+	// Renewals fail from now on; the lease may expire after one TTL
+	stopped := time.Now()
+	mr.Close()
+
+	select {
+	case <-lease.Lost():
+	case <-time.After(3 * testTTL):
+		t.Fatal("lease not reported lost while Redis was down")
+	}
+	if elapsed := time.Since(stopped); elapsed < testTTL*2/3 {
+		t.Fatalf("lease reported lost after %v, before it could expire", elapsed)
+	}
+	if err := lease.Check(); !errors.Is(err, ErrLost) {
+		t.Fatalf("Check on a lost lease: got %v, want ErrLost", err)
+	}
+}
+
+func TestLeaseSurvivesShortRedisOutage(t *testing.T) {
+	locker, mr := newTestLocker(t)
+	ctx := context.Background()
+
+	lease, err := locker.TryAcquire(ctx, "payment_lock:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release(ctx)
+
+	// One renewal fails, the next one succeeds within the TTL
+	mr.SetError("LOADING")
+	time.Sleep(testTTL / 2)
+	mr.SetError("")
+	time.Sleep(testTTL)
+
+	if err := lease.Check(); err != nil {
+		t.Fatalf("Check after a short outage: %v", err)
+	}
+}
+
+func TestFenceOnlyGrows(t *testing.T) {
+	locker, mr := newTestLocker(t)
+	ctx := context.Background()
+
+	var last int64
+	check := func(lease *Lease) {
+		t.Helper()
+		if lease.Fence() <= last {
+			t.Fatalf("fence %d after %d, want it to grow", lease.Fence(), last)
+		}
+		last = lease.Fence()
+	}
+
+	for _, key := range []string{"payment_lock:1", "payment_lock:2", "payment_lock:1"} {
+		lease, err := locker.TryAcquire(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(lease)
+		if err := lease.Release(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A lease taken over after expiry still gets a newer token
+	stale, err := locker.TryAcquire(ctx, "payment_lock:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(stale)
+	mr.FastForward(testTTL)
+	current, err := locker.TryAcquire(ctx, "payment_lock:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(current)
+
+	stale.Release(ctx)
+	current.Release(ctx)
+}
+
+func TestFenceContext(t *testing.T) {
+	if _, ok := FenceFrom(context.Background()); ok {
+		t.Fatal("fence found in a context without one")
+	}
+	if fence, ok := FenceFrom(WithFence(context.Background(), 42)); !ok || fence != 42 {
+		t.Fatalf("FenceFrom = %d, %v; want 42, true", fence, ok)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,26 @@
+package routing
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	successRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "orchestrator_processor_success_rate",
+		Help: "Recent success rate of each processor as used for routing",
+	}, []string{"processor"})
+
+	// Attempts counts processor calls by processor and result (approved,
+	// declined, soft_declined, error)
+	Attempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orchestrator_processor_attempts_total",
+		Help: "Authorization attempts by processor and result",
+	}, []string{"processor", "result"})
+
+	// Failovers counts authorizations moved to another processor
+	Failovers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orchestrator_processor_failovers_total",
+		Help: "Authorizations retried on another processor, by source and target",
+	}, []string{"from", "to"})
+)
@@ -0,0 +1,313 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/connector"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+// ErrNoProcessor is returned when no processor accepts the payment
+var ErrNoProcessor = errors.New("no processor available for payment")
+
+// ProcessorConfig describes one processor. Empty Currencies or Merchants
+// accept everything. MinAmount, MaxAmount and CostFixed are per currency
+// in major units, e.g. {"USD": 0.10, "JPY": 15}; a currency without a
+// MinAmount or MaxAmount is not bounded and one without CostFixed has no
+// fixed fee.
+type ProcessorConfig struct {
+	Name            string `json:"name"`
+	Connector       string `json:"connector"`
+	SimulatorConfig string `json:"simulator_config,omitempty"`
+	// Simulator holds inline simulator rules, used instead of SimulatorConfig
+	Simulator  *connector.SimulatorConfig `json:"simulator,omitempty"`
+	Currencies []string                   `json:"currencies,omitempty"`
+	Merchants  []string                   `json:"merchants,omitempty"`
+	MinAmount  money.Limits               `json:"min_amount,omitempty"`
+	MaxAmount  money.Limits               `json:"max_amount,omitempty"`
+	// Cost of a transaction is CostPercent of the amount plus CostFixed
+	CostPercent float64      `json:"cost_percent"`
+	CostFixed   money.Limits `json:"cost_fixed,omitempty"`
+}
+
+// Rule pins the processor order for matching payments. Empty fields match
+// everything; AmountMin and AmountMax are per currency, see
+// connector.MatchAmount.
+type Rule struct {
+	MerchantID string       `json:"merchant_id,omitempty"`
+	Currency   string       `json:"currency,omitempty"`
+	AmountMin  money.Limits `json:"amount_min,omitempty"`
+	AmountMax  money.Limits `json:"amount_max,omitempty"`
+	Processors []string     `json:"processors"`
+}
+
+// Config is the routing file format
+type Config struct {
+	// MaxAttempts is the number of processors tried for one authorization
+	MaxAttempts int `json:"max_attempts"`
+	// Processors below MinSuccessRate over at least MinSamples recent calls
+	// are only used when no healthy processor is left
+	MinSuccessRate float64           `json:"min_success_rate"`
+	MinSamples     int               `json:"min_samples"`
+	Window         string            `json:"window"`
+	Processors     []ProcessorConfig `json:"processors"`
+	Rules          []Rule            `json:"rules,omitempty"`
+}
+
+// DefaultConfig routes between two local stand-in acquirers: a cheaper one
+// for major currencies and a more expensive one that takes everything.
+// Card BIN 400500 is soft declined by the first, to exercise failover.
+func DefaultConfig() Config {
+	acquirerA := connector.DefaultSimulatorConfig()
+	acquirerA.Rules = append([]connector.SimulatorRule{
+		{Operation: "authorize", CardBIN: "400500", Outcome: connector.OutcomeDecline, DeclineCode: "issuer_unavailable"},
+	}, acquirerA.Rules...)
+
+	return Config{
+		MaxAttempts:    2,
+		MinSuccessRate: 0.5,
+		MinSamples:     20,
+		Window:         "5m",
+		Processors: []ProcessorConfig{
+			{Name: "sim_acquirer_a", Connector: "simulator", Simulator: &acquirerA, Currencies: []string{"USD", "EUR", "GBP"},
+				CostPercent: 1.5, CostFixed: mustLimits("USD:0.10,EUR:0.10,GBP:0.10")},
+			{Name: "sim_acquirer_b", Connector: "simulator",
+				CostPercent: 2.2, CostFixed: mustLimits("USD:0.05,EUR:0.05,GBP:0.05,KZT:25,KGS:5,RUB:5,JPY:8,KWD:0.015")},
+		},
+	}
+}
+
+// Processor is a configured connector with its routing constraints
+type Processor struct {
+	ProcessorConfig
+	Connector connector.Connector
+}
+
+// mustLimits parses the limits of DefaultConfig
+func mustLimits(spec string) money.Limits {
+	limits, err := money.ParseLimits(spec)
+	if err != nil {
+		panic(err)
+	}
+	return limits
+}
+
+// Cost returns the fee for a payment in the payment's currency
+func (p *Processor) Cost(amount money.Money) money.Money {
+	cost := amount.Percent(int64(math.Round(p.CostPercent * 100)))
+	if fixed, ok := p.CostFixed.Get(amount.Currency); ok {
+		cost.Minor += fixed.Minor
+	}
+	return cost
+}
+
+func (p *Processor) accepts(req connector.Request) bool {
+	if len(p.Currencies) > 0 && !contains(p.Currencies, req.Amount.Currency) {
+		return false
+	}
+	if len(p.Merchants) > 0 && !contains(p.Merchants, req.MerchantID) {
+		return false
+	}
+	if lo, ok := p.MinAmount.Get(req.Amount.Currency); ok && req.Amount.Minor < lo.Minor {
+		return false
+	}
+	hi, ok := p.MaxAmount.Get(req.Amount.Currency)
+	return !ok || req.Amount.Minor <= hi.Minor
+}
+
+// Engine selects processors for payments
+type Engine struct {
+	config     Config
+	processors []*Processor
+	byName     map[string]*Processor
+	stats      *Stats
+}
+
+// Load reads the routing config from path, or uses DefaultConfig when path
+// is empty. wrap decorates every connector, e.g. with retries.
+func Load(path string, wrap func(connector.Connector) connector.Connector) (*Engine, error) {
+	config := DefaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		config = Config{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	return New(config, wrap)
+}
+
+func New(config Config, wrap func(connector.Connector) connector.Connector) (*Engine, error) {
+	if len(config.Processors) == 0 {
+		return nil, errors.New("routing config has no processors")
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	if !(config.MinSuccessRate >= 0 && config.MinSuccessRate <= 1) {
+		return nil, fmt.Errorf("routing min_success_rate %v is outside 0..1", config.MinSuccessRate)
+	}
+	if config.MinSamples < 0 {
+		return nil, fmt.Errorf("negative routing min_samples %d", config.MinSamples)
+	}
+	window := 5 * time.Minute
+	if config.Window != "" {
+		var err error
+		if window, err = time.ParseDuration(config.Window); err != nil {
+			return nil, fmt.Errorf("invalid routing window %q", config.Window)
+		}
+	}
+
+	e := &Engine{config: config, byName: make(map[string]*Processor), stats: NewStats(window, 100)}
+	for _, pc := range config.Processors {
+		if pc.Name == "" {
+			return nil, errors.New("routing processor without a name")
+		}
+		if _, ok := e.byName[pc.Name]; ok {
+			return nil, fmt.Errorf("duplicate routing processor %q", pc.Name)
+		}
+		for i, currency := range pc.Currencies {
+			pc.Currencies[i] = strings.ToUpper(currency)
+			if _, err := money.Exponent(pc.Currencies[i]); err != nil {
+				return nil, fmt.Errorf("processor %s: %w", pc.Name, err)
+			}
+		}
+		// A fee above the amount is a typo, e.g. 150 for 1.5%
+		if !(pc.CostPercent >= 0 && pc.CostPercent <= 100) {
+			return nil, fmt.Errorf("processor %s: cost_percent %v is outside 0..100", pc.Name, pc.CostPercent)
+		}
+
+		var c connector.Connector
+		if pc.Simulator != nil && pc.Connector == "simulator" {
+			c = connector.NewSimulator(pc.Name, *pc.Simulator)
+		} else {
+			var err error
+			if c, err = connector.New(pc.Name, pc.Connector, pc.SimulatorConfig); err != nil {
+				return nil, fmt.Errorf("processor %s: %w", pc.Name, err)
+			}
+		}
+		p := &Processor{ProcessorConfig: pc, Connector: wrap(c)}
+		e.processors = append(e.processors, p)
+		e.byName[pc.Name] = p
+		successRate.WithLabelValues(pc.Name).Set(1)
+	}
+
+	for _, rule := range config.Rules {
+		if rule.Currency != "" {
+			if _, err := money.Exponent(strings.ToUpper(rule.Currency)); err != nil {
+				return nil, fmt.Errorf("routing rule: %w", err)
+			}
+		}
+		for _, name := range rule.Processors {
+			if _, ok := e.byName[name]; !ok {
+				return nil, fmt.Errorf("routing rule references unknown processor %q", name)
+			}
+		}
+	}
+	return e, nil
+}
+
+func (e *Engine) MaxAttempts() int {
+	return e.config.MaxAttempts
+}
+
+// Processor returns a processor by name, or nil
+func (e *Engine) Processor(name string) *Processor {
+	return e.byName[name]
+}
+
+// Route returns the processors to try for a payment, best first. A matching
+// rule fixes the order; otherwise processors are ranked by expected cost
+// per successful payment (cost divided by recent success rate). Unhealthy
+// processors always come last.
+func (e *Engine) Route(req connector.Request, now time.Time) ([]*Processor, error) {
+	candidates := e.processors
+	ordered := false
+	if rule := e.matchRule(req); rule != nil {
+		candidates = make([]*Processor, 0, len(rule.Processors))
+		for _, name := range rule.Processors {
+			candidates = append(candidates, e.byName[name])
+		}
+		ordered = true
+	}
+
+	type ranked struct {
+		processor *Processor
+		healthy   bool
+		score     float64
+	}
+	var list []ranked
+	for _, p := range candidates {
+		if !p.accepts(req) {
+			continue
+		}
+		rate, samples := e.stats.SuccessRate(p.Name, now)
+		healthy := samples < e.config.MinSamples || rate >= e.config.MinSuccessRate
+		// Every candidate charges in the payment's currency, so minor
+		// units compare directly
+		score := float64(p.Cost(req.Amount).Minor) / max(rate, 0.01)
+		list = append(list, ranked{processor: p, healthy: healthy, score: score})
+	}
+	if len(list) == 0 {
+		return nil, ErrNoProcessor
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].healthy != list[j].healthy {
+			return list[i].healthy
+		}
+		if ordered {
+			return false
+		}
+		return list[i].score < list[j].score
+	})
+
+	processors := make([]*Processor, len(list))
+	for i, r := range list {
+		processors[i] = r.processor
+	}
+	return processors, nil
+}
+
+// Record feeds an authorization outcome back into the success rates
+func (e *Engine) Record(processor string, ok bool, now time.Time) {
+	e.stats.Record(processor, ok, now)
+	rate, _ := e.stats.SuccessRate(processor, now)
+	successRate.WithLabelValues(processor).Set(rate)
+}
+
+func (e *Engine) matchRule(req connector.Request) *Rule {
+	for i := range e.config.Rules {
+		rule := &e.config.Rules[i]
+		if rule.MerchantID != "" && rule.MerchantID != req.MerchantID {
+			continue
+		}
+		if rule.Currency != "" && !strings.EqualFold(rule.Currency, req.Amount.Currency) {
+			continue
+		}
+		if !connector.MatchAmount(req.Amount, rule.AmountMin, rule.AmountMax) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
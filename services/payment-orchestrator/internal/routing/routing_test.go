@@ -0,0 +1,259 @@
+package routing
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akylbek/payment-system/payment-orchestrator/internal/connector"
+	"github.com/akylbek/payment-system/pkg/platform/money"
+)
+
+func noWrap(c connector.Connector) connector.Connector { return c }
+
+func simulated(name string, costPercent float64, costFixed string) ProcessorConfig {
+	sim := connector.DefaultSimulatorConfig()
+	return ProcessorConfig{
+		Name:        name,
+		Connector:   "simulator",
+		Simulator:   &sim,
+		CostPercent: costPercent,
+		CostFixed:   mustLimits(costFixed),
+	}
+}
+
+// testConfig has a processor that is cheaper for large USD payments and one
+// that is cheaper for small USD payments and for JPY
+func testConfig() Config {
+	return Config{
+		MaxAttempts:    2,
+		MinSuccessRate: 0.5,
+		MinSamples:     2,
+		Window:         "5m",
+		Processors: []ProcessorConfig{
+			simulated("a", 1.5, "USD:0.10,JPY:50"),
+			simulated("b", 2.2, "USD:0.05,JPY:8"),
+		},
+	}
+}
+
+type result struct {
+	processor string
+	ok        bool
+}
+
+func TestRoute(t *testing.T) {
+	usd := func(minor int64) money.Money { return money.Money{Minor: minor, Currency: "USD"} }
+
+	tests := []struct {
+		name     string
+		config   func(*Config)
+		outcomes []result
+		req      connector.Request
+		want     []string
+		wantErr  error
+	}{
+		{
+			// a: 15 + 10 = 25 cents, b: 22 + 5 = 27 cents
+			name: "cheapest first",
+			req:  connector.Request{Amount: usd(1000)},
+			want: []string{"a", "b"},
+		},
+		{
+			// a: 2 + 10 = 12 cents, b: 2 + 5 = 7 cents
+			name: "fixed fee dominates small payments",
+			req:  connector.Request{Amount: usd(100)},
+			want: []string{"b", "a"},
+		},
+		{
+			// Fees are compared in the payment's currency: a: 15 + 50 JPY,
+			// b: 22 + 8 JPY
+			name: "ranked by fees of the payment currency",
+			req:  connector.Request{Amount: money.Money{Minor: 1000, Currency: "JPY"}},
+			want: []string{"b", "a"},
+		},
+		{
+			// A currency without a fixed fee pays the percentage only
+			name: "currency without fixed fee",
+			req:  connector.Request{Amount: money.Money{Minor: 1000, Currency: "EUR"}},
+			want: []string{"a", "b"},
+		},
+		{
+			// a is healthy at 50% but costs 25 / 0.5 = 50 per success
+			name:     "cost divided by success rate",
+			outcomes: []result{{"a", true}, {"a", false}},
+			req:      connector.Request{Amount: usd(1000)},
+			want:     []string{"b", "a"},
+		},
+		{
+			name:     "unhealthy processor last",
+			outcomes: []result{{"a", false}, {"a", false}},
+			req:      connector.Request{Amount: usd(1000)},
+			want:     []string{"b", "a"},
+		},
+		{
+			name: "rule fixes the order",
+			config: func(c *Config) {
+				c.Rules = []Rule{{MerchantID: "m1", Processors: []string{"b", "a"}}}
+			},
+			req:  connector.Request{MerchantID: "m1", Amount: usd(1000)},
+			want: []string{"b", "a"},
+		},
+		{
+			name: "rule for another merchant is ignored",
+			config: func(c *Config) {
+				c.Rules = []Rule{{MerchantID: "m1", Processors: []string{"b", "a"}}}
+			},
+			req:  connector.Request{MerchantID: "m2", Amount: usd(1000)},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "first matching rule wins",
+			config: func(c *Config) {
+				c.Rules = []Rule{
+					{Currency: "usd", AmountMin: mustLimits("USD:5"), Processors: []string{"b"}},
+					{Currency: "USD", Processors: []string{"a"}},
+				}
+			},
+			req:  connector.Request{Amount: usd(1000)},
+			want: []string{"b"},
+		},
+		{
+			name: "amount outside a rule's bounds",
+			config: func(c *Config) {
+				c.Rules = []Rule{
+					{Currency: "USD", AmountMin: mustLimits("USD:100"), Processors: []string{"b"}},
+					{Currency: "USD", Processors: []string{"a"}},
+				}
+			},
+			req:  connector.Request{Amount: usd(1000)},
+			want: []string{"a"},
+		},
+		{
+			name: "too few samples to be unhealthy",
+			config: func(c *Config) {
+				c.MinSamples = 3
+				c.Rules = []Rule{{Processors: []string{"a", "b"}}}
+			},
+			outcomes: []result{{"a", false}, {"a", false}},
+			req:      connector.Request{Amount: usd(1000)},
+			want:     []string{"a", "b"},
+		},
+		{
+			name: "unhealthy processor last within a rule",
+			config: func(c *Config) {
+				c.Rules = []Rule{{Processors: []string{"a", "b"}}}
+			},
+			outcomes: []result{{"a", false}, {"a", false}},
+			req:      connector.Request{Amount: usd(1000)},
+			want:     []string{"b", "a"},
+		},
+		{
+			name: "currency and amount bounds",
+			config: func(c *Config) {
+				c.Processors[0].Currencies = []string{"usd"}
+				c.Processors[1].MaxAmount = mustLimits("USD:5")
+			},
+			req:  connector.Request{Amount: usd(1000)},
+			want: []string{"a"},
+		},
+		{
+			name: "no processor accepts the payment",
+			config: func(c *Config) {
+				c.Processors[0].Currencies = []string{"EUR"}
+				c.Processors[1].MinAmount = mustLimits("USD:50")
+			},
+			req:     connector.Request{Amount: usd(1000)},
+			wantErr: ErrNoProcessor,
+		},
+		{
+			name: "rule leaves no processor",
+			config: func(c *Config) {
+				c.Processors[1].Merchants = []string{"m2"}
+				c.Rules = []Rule{{MerchantID: "m1", Processors: []string{"b"}}}
+			},
+			req:     connector.Request{MerchantID: "m1", Amount: usd(1000)},
+			wantErr: ErrNoProcessor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			if tt.config != nil {
+				tt.config(&config)
+			}
+			engine, err := New(config, noWrap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Now()
+			for _, o := range tt.outcomes {
+				engine.Record(o.processor, o.ok, now)
+			}
+
+			processors, err := engine.Route(tt.req, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Route error = %v, want %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, p := range processors {
+				names = append(names, p.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Fatalf("Route = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func(*Config)
+		wantErr string
+	}{
+		{"no processors", func(c *Config) { c.Processors = nil }, "no processors"},
+		{"success rate above 1", func(c *Config) { c.MinSuccessRate = 1.5 }, "min_success_rate"},
+		{"negative success rate", func(c *Config) { c.MinSuccessRate = -0.1 }, "min_success_rate"},
+		{"negative samples", func(c *Config) { c.MinSamples = -1 }, "min_samples"},
+		{"invalid window", func(c *Config) { c.Window = "5 minutes" }, "window"},
+		{"processor without name", func(c *Config) { c.Processors[0].Name = "" }, "without a name"},
+		{"duplicate processor", func(c *Config) { c.Processors[1].Name = "a" }, "duplicate"},
+		{"unknown currency", func(c *Config) { c.Processors[0].Currencies = []string{"USD", "XXX"} }, "unknown currency"},
+		{"negative cost", func(c *Config) { c.Processors[0].CostPercent = -1 }, "cost_percent"},
+		{"cost above 100%", func(c *Config) { c.Processors[0].CostPercent = 150 }, "cost_percent"},
+		{"rule with unknown currency", func(c *Config) { c.Rules = []Rule{{Currency: "usdx", Processors: []string{"a"}}} }, "unknown currency"},
+		{"rule with unknown processor", func(c *Config) { c.Rules = []Rule{{Processors: []string{"c"}}} }, "unknown processor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			tt.config(&config)
+			_, err := New(config, noWrap)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	p := &Processor{ProcessorConfig: simulated("a", 1.5, "USD:0.10,KWD:0.015")}
+	tests := []struct {
+		amount money.Money
+		want   int64
+	}{
+		{money.Money{Minor: 10000, Currency: "USD"}, 150 + 10},
+		{money.Money{Minor: 1000, Currency: "KWD"}, 15 + 15},
+		{money.Money{Minor: 1000, Currency: "JPY"}, 15},
+	}
+	for _, tt := range tests {
+		got := p.Cost(tt.amount)
+		if got.Minor != tt.want || got.Currency != tt.amount.Currency {
+			t.Fatalf("Cost(%v %s) = %v %s, want %d minor units", tt.amount, tt.amount.Currency, got, got.Currency, tt.want)
+		}
+	}
+}
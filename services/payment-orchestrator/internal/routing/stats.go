@@ -0,0 +1,71 @@
+package routing
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats keeps the recent outcomes of each processor. Only outcomes inside
+// the window count, and at most size per processor.
+type Stats struct {
+	window time.Duration
+	size   int
+
+	mu       sync.Mutex
+	outcomes map[string][]outcome
+}
+
+type outcome struct {
+	at time.Time
+	ok bool
+}
+
+func NewStats(window time.Duration, size int) *Stats {
+	return &Stats{window: window, size: size, outcomes: make(map[string][]outcome)}
+}
+
+// Record adds an outcome. ok is false for processor failures (errors,
+// timeouts, soft declines); hard issuer declines count as ok since the
+// processor did its job.
+func (s *Stats) Record(processor string, ok bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := append(s.prune(processor, now), outcome{at: now, ok: ok})
+	if len(list) > s.size {
+		list = list[len(list)-s.size:]
+	}
+	s.outcomes[processor] = list
+}
+
+// SuccessRate returns the share of successful outcomes in the window and
+// the number of samples it is based on
+func (s *Stats) SuccessRate(processor string, now time.Time) (float64, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.prune(processor, now)
+	if len(list) == 0 {
+		return 1, 0
+	}
+	succeeded := 0
+	for _, o := range list {
+		if o.ok {
+			succeeded++
+		}
+	}
+	return float64(succeeded) / float64(len(list)), len(list)
+}
+
+// prune drops outcomes older than the window. Outcomes are appended in
+// time order, so the old ones are at the front.
+func (s *Stats) prune(processor string, now time.Time) []outcome {
+	list := s.outcomes[processor]
+	i := 0
+	for i < len(list) && now.Sub(list[i].at) > s.window {
+		i++
+	}
+	list = list[i:]
+	s.outcomes[processor] = list
+	return list
+}
@@ -0,0 +1,58 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Outcomes are recorded at start+at; the rate is read at start+readAt
+	type record struct {
+		ok bool
+		at time.Duration
+	}
+	tests := []struct {
+		name        string
+		records     []record
+		readAt      time.Duration
+		wantRate    float64
+		wantSamples int
+	}{
+		{"no samples", nil, 0, 1, 0},
+		{"all succeeded", []record{{true, 0}, {true, time.Second}}, time.Second, 1, 2},
+		{"mixed", []record{{true, 0}, {false, 0}, {true, 0}, {false, 0}}, 0, 0.5, 4},
+		{"at the window edge", []record{{false, 0}, {true, time.Minute}}, time.Minute, 0.5, 2},
+		{"old outcomes expire", []record{{false, 0}, {true, time.Minute}}, time.Minute + time.Second, 1, 1},
+		{"all expired", []record{{false, 0}}, 2 * time.Minute, 1, 0},
+		// Only the last five outcomes are kept
+		{"size cap", []record{{false, 0}, {false, 0}, {true, 0}, {true, 0}, {true, 0}, {true, 0}, {false, 0}}, 0, 0.8, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStats(time.Minute, 5)
+			for _, r := range tt.records {
+				s.Record("acquirer_a", r.ok, start.Add(r.at))
+			}
+			rate, samples := s.SuccessRate("acquirer_a", start.Add(tt.readAt))
+			if rate != tt.wantRate || samples != tt.wantSamples {
+				t.Fatalf("SuccessRate = %v over %d samples, want %v over %d", rate, samples, tt.wantRate, tt.wantSamples)
+			}
+		})
+	}
+}
+
+func TestStatsArePerProcessor(t *testing.T) {
+	now := time.Now()
+	s := NewStats(time.Minute, 10)
+	s.Record("acquirer_a", false, now)
+	s.Record("acquirer_b", true, now)
+
+	if rate, samples := s.SuccessRate("acquirer_a", now); rate != 0 || samples != 1 {
+		t.Fatalf("acquirer_a: SuccessRate = %v over %d samples", rate, samples)
+	}
+	if rate, samples := s.SuccessRate("acquirer_b", now); rate != 1 || samples != 1 {
+		t.Fatalf("acquirer_b: SuccessRate = %v over %d samples", rate, samples)
+	}
+}
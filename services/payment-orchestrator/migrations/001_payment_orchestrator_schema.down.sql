@@ -0,0 +1,7 @@
+-- Reverts 001_payment_orchestrator_schema
+
+DROP TABLE IF EXISTS inbox_events;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS payment_attempts;
+DROP TABLE IF EXISTS payment_states;
+DROP FUNCTION IF EXISTS update_updated_at_column();
@@ -12,15 +12,65 @@ CREATE TABLE IF NOT EXISTS payment_states (
     state VARCHAR(50) NOT NULL,
     previous_state VARCHAR(50),
     fraud_decision VARCHAR(50),
+    fraud_reason TEXT,
+    fraud_risk_score INTEGER,
+    fraud_rules JSONB,
+    amount DECIMAL(15,2),
+    currency VARCHAR(3),
+    merchant_id VARCHAR(255),
+    customer_id VARCHAR(255),
+    payment_method VARCHAR(50),
+    card_bin VARCHAR(8),
+    connector VARCHAR(50),
+    processor_reference VARCHAR(255),
+    decline_code VARCHAR(100),
+    processor_message TEXT,
     error_message TEXT,
     retry_count INTEGER DEFAULT 0,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Columns missing from tables created by the service before migrations
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS fraud_reason TEXT;
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS fraud_risk_score INTEGER;
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS fraud_rules JSONB;
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS amount DECIMAL(15,2);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS currency VARCHAR(3);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS merchant_id VARCHAR(255);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS customer_id VARCHAR(255);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS payment_method VARCHAR(50);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS card_bin VARCHAR(8);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS connector VARCHAR(50);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS processor_reference VARCHAR(255);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS decline_code VARCHAR(100);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS processor_message TEXT;
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS error_message TEXT;
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS retry_count INTEGER DEFAULT 0;
+
 -- Composite index for state queries with time ordering
 CREATE INDEX IF NOT EXISTS idx_payment_states_state_updated ON payment_states(state, updated_at DESC);
 
+-- Processor attempts (authorize, capture, void, refund calls per payment)
+CREATE TABLE IF NOT EXISTS payment_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id VARCHAR(255) NOT NULL,
+    attempt_number INTEGER NOT NULL,
+    operation VARCHAR(20) NOT NULL,
+    connector VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    reference VARCHAR(255),
+    response_code VARCHAR(10),
+    decline_reason VARCHAR(100),
+    error TEXT,
+    latency_ms BIGINT NOT NULL,
+    raw_response JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (payment_id, attempt_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_attempts_connector_created ON payment_attempts(connector, created_at DESC);
+
 -- Outbox events (for reliable event publishing)
 CREATE TABLE IF NOT EXISTS outbox_events (
     id BIGSERIAL PRIMARY KEY,
@@ -66,6 +116,7 @@ END;
 $$ language 'plpgsql';
 
 -- Triggers for updated_at
+DROP TRIGGER IF EXISTS update_payment_states_updated_at ON payment_states;
 CREATE TRIGGER update_payment_states_updated_at BEFORE UPDATE ON payment_states
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
@@ -74,13 +125,6 @@ CREATE TRIGGER update_payment_states_updated_at BEFORE UPDATE ON payment_states
 -- =====================================================
 
 COMMENT ON TABLE payment_states IS 'State machine tracking for payment lifecycle';
+COMMENT ON TABLE payment_attempts IS 'Every processor call of a payment, including failovers and retries';
 COMMENT ON TABLE outbox_events IS 'Outbox pattern for reliable event publishing';
 COMMENT ON TABLE inbox_events IS 'Inbox pattern for idempotent event processing';
-
--- Schema version tracking
-CREATE TABLE IF NOT EXISTS schema_migrations (
-    version VARCHAR(50) PRIMARY KEY,
-    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-INSERT INTO schema_migrations (version) VALUES ('001_payment_orchestrator_schema') ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+-- Reverts 002_payment_states_lock_fence
+
+ALTER TABLE payment_states DROP COLUMN IF EXISTS lock_fence;
@@ -0,0 +1,3 @@
+-- Fencing token of the lock holder that made the last state transition.
+-- Transitions carrying an older token are refused.
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS lock_fence BIGINT;
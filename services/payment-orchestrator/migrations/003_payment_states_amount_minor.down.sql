@@ -0,0 +1,4 @@
+-- Reverts 003_payment_states_amount_minor
+
+ALTER TABLE payment_states DROP COLUMN IF EXISTS amount_minor;
+ALTER TABLE payment_states ALTER COLUMN amount TYPE DECIMAL(15,2);
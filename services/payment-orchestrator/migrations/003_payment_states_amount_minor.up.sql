@@ -0,0 +1,13 @@
+-- Payment amounts in integer minor units of their currency, passed to the
+-- processors and published with state changes
+ALTER TABLE payment_states ALTER COLUMN amount TYPE DECIMAL(20,3);
+ALTER TABLE payment_states ADD COLUMN IF NOT EXISTS amount_minor BIGINT;
+
+UPDATE payment_states
+SET amount_minor = ROUND(amount * CASE
+        WHEN currency IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+                          'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1
+        WHEN currency IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        ELSE 100
+    END)
+WHERE amount_minor IS NULL AND amount IS NOT NULL;